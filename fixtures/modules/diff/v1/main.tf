@@ -0,0 +1,45 @@
+terraform {
+  required_providers {
+    aws = {
+      source  = "hashicorp/aws"
+      version = "~> 4.0"
+    }
+  }
+}
+
+variable "name" {
+  type = string
+}
+
+variable "size" {
+  type    = number
+  default = 1
+}
+
+variable "zones" {
+  type    = list(string)
+  default = []
+}
+
+variable "legacy" {
+  type    = string
+  default = ""
+}
+
+resource "aws_instance" "web" {
+  tags = {
+    Name = var.name
+  }
+}
+
+resource "aws_eip" "web" {
+  instance = aws_instance.web.id
+}
+
+output "instance_id" {
+  value = aws_instance.web.id
+}
+
+output "ip" {
+  value = aws_eip.web.public_ip
+}
@@ -0,0 +1,55 @@
+terraform {
+  required_providers {
+    aws = {
+      source  = "hashicorp/aws"
+      version = "~> 5.0"
+    }
+    random = {
+      source = "hashicorp/random"
+    }
+  }
+}
+
+variable "name" {
+  type = string
+}
+
+variable "size" {
+  type    = number
+  default = 2
+}
+
+variable "zones" {
+  type    = any
+  default = []
+}
+
+variable "owner" {
+  type = string
+}
+
+variable "debug" {
+  type    = bool
+  default = false
+}
+
+resource "random_pet" "this" {}
+
+resource "aws_instance" "app" {
+  tags = {
+    Name  = "${var.name}-${random_pet.this.id}"
+    Owner = var.owner
+  }
+}
+
+resource "aws_eip" "web" {
+  instance = aws_instance.app.id
+}
+
+output "instance_id" {
+  value = aws_instance.app.id
+}
+
+output "public_ip" {
+  value = aws_eip.web.public_ip
+}
@@ -0,0 +1,34 @@
+variable "cidr" {
+  type = string
+}
+
+module "network" {
+  source = "./network"
+  cidr   = var.cidr
+}
+
+resource "aws_instance" "app" {
+  ami           = "ami-123"
+  instance_type = "t3.micro"
+}
+
+resource "aws_s3_bucket" "logs" {
+  bucket = "logs"
+}
+
+resource "aws_s3_bucket" "imported" {
+  bucket = "imported"
+}
+
+import {
+  to = aws_s3_bucket.imported
+  id = "imported"
+}
+
+removed {
+  from = aws_s3_bucket.old
+
+  lifecycle {
+    destroy = false
+  }
+}
@@ -0,0 +1,6 @@
+# Moves that have already been written by hand.
+
+moved {
+  from = aws_subnet.private
+  to   = module.network.aws_subnet.private
+}
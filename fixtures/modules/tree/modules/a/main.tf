@@ -0,0 +1,7 @@
+module "b" {
+  source = "../b"
+}
+
+module "d" {
+  source = "../d"
+}
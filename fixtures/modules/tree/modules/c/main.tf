@@ -0,0 +1,7 @@
+module "d" {
+  source = "../d"
+}
+
+module "nope" {
+  source = "../nope"
+}
@@ -0,0 +1,7 @@
+module "a" {
+  source = "../../modules/a"
+}
+
+module "c" {
+  source = "../../modules/c"
+}
@@ -0,0 +1,15 @@
+variable "name" {
+  type = string
+}
+
+module "vpc" {
+  source = "../vpc"
+
+  cidr_block = "10.1.0.0/16"
+}
+
+module "labels" {
+  source = "git::https://github.com/acme/terraform-modules.git//labels?ref=v1.2.0"
+
+  name = var.name
+}
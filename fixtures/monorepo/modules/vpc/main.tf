@@ -0,0 +1,11 @@
+variable "cidr_block" {
+  type = string
+}
+
+resource "aws_vpc" "this" {
+  cidr_block = var.cidr_block
+}
+
+output "vpc_id" {
+  value = aws_vpc.this.id
+}
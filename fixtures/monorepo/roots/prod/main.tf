@@ -0,0 +1,16 @@
+module "vpc" {
+  source = "../../modules/vpc"
+
+  cidr_block = "10.0.0.0/16"
+}
+
+module "consul" {
+  source  = "hashicorp/consul/aws"
+  version = "0.1.0"
+}
+
+module "labels" {
+  source = "git::https://github.com/acme/terraform-modules.git//labels?ref=v1.2.0"
+
+  name = "prod"
+}
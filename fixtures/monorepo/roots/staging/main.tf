@@ -0,0 +1,16 @@
+module "app" {
+  source = "../../modules/app"
+
+  name = "staging"
+}
+
+module "consul" {
+  source  = "registry.terraform.io/hashicorp/consul/aws"
+  version = "~> 0.2.0"
+}
+
+module "labels" {
+  source = "github.com/acme/terraform-modules//labels?ref=v1.3.0"
+
+  name = "staging"
+}
@@ -0,0 +1,27 @@
+scope "org" "acme" {
+  region = "us-west-2"
+
+  scope "platform" "gold" {
+    cidr = "10.0.0.0/16"
+
+    scope "domain" "product" {
+      scope "environment" "dev" {
+        account_id = "111111111111"
+      }
+      scope "environment" "prod" {
+        account_id = "222222222222"
+        region     = "us-east-1"
+      }
+    }
+  }
+
+  scope "platform" "silver" {
+    cidr = "10.1.0.0/16"
+
+    scope "domain" "product" {
+      scope "environment" "dev" {
+        account_id = "333333333333"
+      }
+    }
+  }
+}
@@ -0,0 +1,7 @@
+variable "names" {
+  type = list(string)
+}
+
+output "id" {
+  value = join("-", var.names)
+}
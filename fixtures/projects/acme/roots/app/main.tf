@@ -0,0 +1,15 @@
+variable "environment" {
+  type = string
+}
+
+variable "account_id" {
+  type = string
+}
+
+variable "region" {
+  type = string
+}
+
+output "account" {
+  value = "${var.environment} (${var.account_id}) in ${var.region}"
+}
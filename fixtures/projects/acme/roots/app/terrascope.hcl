@@ -0,0 +1,3 @@
+root "app" {
+  scopes = ["org", "platform", "domain", "environment"]
+}
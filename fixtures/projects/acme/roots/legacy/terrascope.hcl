@@ -0,0 +1 @@
+root "legacy" {}
@@ -0,0 +1,27 @@
+variable "org" {
+  type = string
+}
+
+variable "platform" {
+  type = string
+}
+
+variable "cidr" {
+  type = string
+}
+
+module "labels" {
+  source = "../../modules/labels"
+
+  names = [var.org, var.platform]
+}
+
+module "subnets" {
+  source = "./subnets"
+
+  cidr = var.cidr
+}
+
+output "vpc_cidr" {
+  value = var.cidr
+}
@@ -0,0 +1,7 @@
+variable "cidr" {
+  type = string
+}
+
+output "cidrs" {
+  value = cidrsubnets(var.cidr, 8, 8)
+}
@@ -0,0 +1,3 @@
+root "network" {
+  scopes = ["org", "platform"]
+}
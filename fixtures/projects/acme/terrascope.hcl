@@ -0,0 +1,24 @@
+project "acme" {
+  roots_dir  = "roots"
+  scope_data = ["data.hcl"]
+
+  scope "org" {
+    description = "the company, which has the Organization account"
+  }
+  scope "platform" {
+    description = "a copy of the infrastructure, with its own Security and Networking accounts"
+  }
+  scope "domain" {
+    description = "a product, or the team that owns it"
+  }
+  scope "environment" {
+    description = "one of a domain's accounts, like dev or prod"
+  }
+  scope "region" {}
+
+  backend "s3" {
+    bucket = "${scope.org}-terraform-state"
+    key    = "${root}/${scope_path}/terraform.tfstate"
+    region = "us-west-2"
+  }
+}
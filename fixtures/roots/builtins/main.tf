@@ -0,0 +1,27 @@
+variable "files" {
+  type = list(string)
+}
+
+variable "contents" {
+  type = map(string)
+}
+
+locals {
+  prefix = "${terraform.workspace}-"
+}
+
+resource "local_file" "counted" {
+  count    = length(var.files)
+  filename = "${path.module}/${local.prefix}${var.files[count.index]}"
+  content  = ""
+
+  provisioner "local-exec" {
+    command = "cat ${self.filename}"
+  }
+}
+
+resource "local_file" "each" {
+  for_each = var.contents
+  filename = "${path.root}/${each.key}"
+  content  = each.value
+}
@@ -0,0 +1,22 @@
+locals {
+  a = local.c
+  b = "${local.a}-b"
+  c = upper(local.b)
+}
+
+resource "random_string" "first" {
+  length  = 3
+  special = random_string.second.special
+}
+
+resource "random_string" "second" {
+  length  = 3
+  special = false
+  keepers = {
+    first = random_string.first.result
+  }
+}
+
+output "a" {
+  value = local.a
+}
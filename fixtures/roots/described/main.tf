@@ -0,0 +1,89 @@
+terraform {
+  required_version = ">= 1.3"
+
+  required_providers {
+    aws = {
+      source  = "hashicorp/aws"
+      version = "~> 5.0"
+    }
+    random = {
+      source = "hashicorp/random"
+    }
+  }
+}
+
+variable "name" {
+  type        = string
+  description = "The name of the service. Used as a prefix for everything it creates."
+
+  validation {
+    condition     = length(var.name) <= 16
+    error_message = "The name must be 16 characters or fewer."
+  }
+
+  validation {
+    condition     = can(regex("^[a-z-]+$", var.name))
+    error_message = "The name must only contain lowercase letters and dashes."
+  }
+}
+
+variable "tags" {
+  type = object({
+    team = string
+    cost = optional(string)
+  })
+  description = "Tags for every resource."
+  default = {
+    team = "platform"
+  }
+}
+
+variable "db_password" {
+  type      = string
+  sensitive = true
+}
+
+module "network" {
+  source  = "terraform-aws-modules/vpc/aws"
+  version = "5.1.0"
+
+  name = var.name
+}
+
+data "aws_ami" "ubuntu" {
+  most_recent = true
+}
+
+data "aws_ami" "windows" {
+  most_recent = true
+}
+
+resource "random_string" "suffix" {
+  length = 4
+}
+
+resource "aws_instance" "web" {
+  count         = 2
+  ami           = data.aws_ami.ubuntu.id
+  subnet_id     = module.network.private_subnets[count.index]
+  instance_type = "t3.micro"
+  tags          = merge(var.tags, { Name = "${var.name}-${random_string.suffix.result}" })
+}
+
+resource "aws_db_instance" "this" {
+  password = var.db_password
+}
+
+locals {
+  web_ids = aws_instance.web[*].id
+}
+
+output "web_ids" {
+  description = "The IDs of the web | app instances."
+  value       = local.web_ids
+}
+
+output "db_address" {
+  value     = aws_db_instance.this.address
+  sensitive = true
+}
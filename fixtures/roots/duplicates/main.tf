@@ -0,0 +1,15 @@
+variable "name" {
+  type = string
+}
+
+locals {
+  prefix = "app"
+}
+
+resource "random_string" "this" {
+  length = 8
+}
+
+output "name" {
+  value = "${local.prefix}-${var.name}"
+}
@@ -0,0 +1,12 @@
+locals {
+  prefix = "svc"
+}
+
+resource "random_string" "this" {
+  length = 12
+}
+
+# a module call may share a name with a resource, since their addresses differ
+module "this" {
+  source = "./child"
+}
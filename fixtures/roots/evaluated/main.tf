@@ -0,0 +1,46 @@
+variable "env" {
+  type = string
+}
+
+variable "vpc_cidr" {
+  type    = string
+  default = "10.0.0.0/16"
+}
+
+variable "zones" {
+  type    = list(string)
+  default = ["a", "b"]
+}
+
+variable "tags" {
+  type = object({
+    team  = string
+    owner = optional(string, "platform")
+  })
+  default = {
+    team = "core"
+  }
+}
+
+variable "instance_count" {
+  type    = number
+  default = 1
+
+  validation {
+    condition     = var.instance_count > 0 && var.instance_count <= 5
+    error_message = "instance_count must be between 1 and 5."
+  }
+}
+
+locals {
+  name    = "${local.prefix}-${var.env}"
+  prefix  = "acme"
+  subnets = { for i, zone in var.zones : zone => cidrsubnet(var.vpc_cidr, 8, i) }
+  tags    = merge(var.tags, { Name = local.name })
+  vpc_id  = aws_vpc.this.id
+}
+
+resource "aws_vpc" "this" {
+  cidr_block = var.vpc_cidr
+  tags       = local.tags
+}
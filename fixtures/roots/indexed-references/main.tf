@@ -0,0 +1,33 @@
+variable "names" {
+  type = map(string)
+}
+
+resource "random_string" "by_key" {
+  for_each = var.names
+  length   = 3
+}
+
+resource "random_string" "by_index" {
+  count  = 2
+  length = 3
+}
+
+module "child" {
+  source = "./child"
+  count  = 1
+}
+
+locals {
+  map = {
+    "a.b" = "c"
+    true  = "d"
+  }
+
+  string_key  = random_string.by_key["a"].result
+  number_key  = random_string.by_index[0].result
+  module_key  = module.child[0].name
+  dotted_key  = local.map["a.b"]
+  bool_key    = local.map[true]
+  unknown_key = random_string.by_key[var.names["x"]].result
+  splat       = random_string.by_index[*].result
+}
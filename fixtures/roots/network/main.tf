@@ -0,0 +1,37 @@
+variable "vpc_cidr" {
+  type = string
+}
+
+variable "env" {
+  type    = string
+  default = "dev"
+}
+
+locals {
+  name = "network-${var.env}"
+  tags = {
+    Name = local.name
+  }
+}
+
+data "aws_availability_zones" "available" {}
+
+resource "aws_vpc" "this" {
+  cidr_block = var.vpc_cidr
+  tags       = local.tags
+}
+
+resource "aws_subnet" "this" {
+  count             = 2
+  vpc_id            = aws_vpc.this.id
+  cidr_block        = cidrsubnet(var.vpc_cidr, 8, count.index)
+  availability_zone = data.aws_availability_zones.available.names[count.index]
+}
+
+output "vpc_id" {
+  value = aws_vpc.this.id
+}
+
+output "subnet_ids" {
+  value = aws_subnet.this[*].id
+}
@@ -0,0 +1,28 @@
+variable "name" {
+  type = string
+}
+
+variable "length" {
+  type    = number
+  default = 8
+}
+
+locals {
+  prefix = "app"
+}
+
+resource "random_string" "this" {
+  length = var.length
+  keepers = {
+    name = var.name
+  }
+
+  lifecycle {
+    create_before_destroy = true
+    ignore_changes        = [special]
+  }
+}
+
+output "name" {
+  value = "${local.prefix}-${random_string.this.result}"
+}
@@ -0,0 +1,13 @@
+locals {
+  prefix = var.name
+}
+
+resource "random_string" "this" {
+  keepers = {
+    pet = random_pet.this.id
+  }
+
+  lifecycle {
+    ignore_changes = [length]
+  }
+}
@@ -0,0 +1,74 @@
+terraform {
+  required_providers {
+    aws = {
+      source = "hashicorp/aws"
+    }
+  }
+}
+
+provider "aws" {
+  region = var.region
+}
+
+provider "aws" {
+  alias  = "west"
+  region = "us-west-2"
+}
+
+provider "aws" {
+  alias  = "east"
+  region = "us-east-1"
+}
+
+variable "region" {
+  type = string
+}
+
+variable "unused" {
+  type = string
+
+  validation {
+    condition     = length(var.unused) > 0
+    error_message = "Must not be empty."
+  }
+}
+
+locals {
+  name   = "example"
+  unused = "nobody reads this"
+}
+
+data "aws_caller_identity" "current" {}
+
+data "aws_region" "unused" {
+  provider = aws.west
+}
+
+resource "aws_s3_bucket" "this" {
+  bucket = "${local.name}-${data.aws_caller_identity.current.account_id}"
+}
+
+# only import blocks use these
+variable "legacy_bucket" {
+  type = string
+}
+
+variable "log_buckets" {
+  type = map(string)
+}
+
+import {
+  to = aws_s3_bucket.this
+  id = var.legacy_bucket
+}
+
+import {
+  for_each = var.log_buckets
+  to       = aws_s3_bucket.logs[each.key]
+  id       = each.value
+}
+
+resource "aws_s3_bucket" "logs" {
+  for_each = toset(["app", "audit"])
+  bucket   = "${local.name}-${each.key}-logs"
+}
@@ -51,9 +51,12 @@ func NewTerrascopeCmd() *cobra.Command {
 	cmd.PersistentFlags().BoolVarP(&quiet, "quiet", "q", false, "silences all logs but the errors (and prints those to stderr). Still prints command output to stdout. Overrides verbose and vvv")
 
 	cmd.AddCommand(newVersionCommand())
+	cmd.AddCommand(newInitCommand())
 
 	cmd.AddCommand(newModuleCommand())
 	cmd.AddCommand(newProviderCommand())
+	cmd.AddCommand(newRootCommand())
+	cmd.AddCommand(newScopeCommand())
 
 	return cmd
 }
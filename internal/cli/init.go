@@ -0,0 +1,170 @@
+package cli
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/spilliams/terrascope/internal/hcl"
+)
+
+// exampleScopes are the scope types that `init` suggests, from
+// notes/hypothetical.md.
+var exampleScopes = []string{"org", "platform", "domain", "environment", "region"}
+
+// prompter asks for the values of flags that weren't given, if there is
+// someone at a terminal to answer.
+type prompter struct {
+	cmd         *cobra.Command
+	interactive bool
+	reader      *bufio.Reader
+}
+
+func newPrompter(cmd *cobra.Command, noInput bool) *prompter {
+	return &prompter{cmd: cmd, interactive: !noInput && isTerminal(os.Stdin), reader: bufio.NewReader(os.Stdin)}
+}
+
+// isTerminal returns whether the given file is a terminal, as near as we can
+// tell without a terminal library: a character device that isn't the null
+// device.
+func isTerminal(f *os.File) bool {
+	info, err := f.Stat()
+	if err != nil || info.Mode()&os.ModeCharDevice == 0 {
+		return false
+	}
+	if null, err := os.Stat(os.DevNull); err == nil && os.SameFile(info, null) {
+		return false
+	}
+	return true
+}
+
+// ask returns the given value if its flag was given, or if there's no one to
+// ask. Otherwise it asks the given question, and returns the answer, or the
+// given suggestion if the answer is empty.
+func (p *prompter) ask(flag, question, value, suggestion string) (string, error) {
+	if !p.interactive || p.cmd.Flags().Changed(flag) {
+		return value, nil
+	}
+	if len(suggestion) > 0 {
+		fmt.Fprintf(os.Stderr, "%s [%s]: ", question, suggestion)
+	} else {
+		fmt.Fprintf(os.Stderr, "%s: ", question)
+	}
+	answer, err := p.reader.ReadString('\n')
+	if err != nil && err != io.EOF {
+		return "", err
+	}
+	answer = strings.TrimSpace(answer)
+	if len(answer) == 0 {
+		return suggestion, nil
+	}
+	return answer, nil
+}
+
+// askList is like ask, for a flag with a list of values. The answer is split
+// on commas.
+func (p *prompter) askList(flag, question string, values, suggestion []string) ([]string, error) {
+	answer, err := p.ask(flag, question+", separated by commas", strings.Join(values, ","), strings.Join(suggestion, ","))
+	if err != nil {
+		return nil, err
+	}
+	list := make([]string, 0)
+	for _, s := range strings.Split(answer, ",") {
+		if s = strings.TrimSpace(s); len(s) > 0 {
+			list = append(list, s)
+		}
+	}
+	return list, nil
+}
+
+func newInitCommand() *cobra.Command {
+	var topDir, name, rootsDir, scopeData string
+	var scopes []string
+	var noInput bool
+
+	cmd := &cobra.Command{
+		Use:   "init",
+		Short: "creates a new terrascope project",
+		Long: "Creates a new terrascope project: a terrascope.hcl in the top\n" +
+			"directory, which declares the project's scope types, a scope data\n" +
+			"file for their values, and a roots directory.\n\n" +
+			"When run at a terminal, asks for anything not given by a flag. With\n" +
+			"`--no-input`, or when not at a terminal (e.g. in CI), the flags'\n" +
+			"defaults are used instead, and `--scopes` is required.",
+		Args:         cobra.NoArgs,
+		SilenceUsage: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			prompt := newPrompter(cmd, noInput)
+			var err error
+			if topDir, err = prompt.ask("dir", "Top directory", topDir, topDir); err != nil {
+				return err
+			}
+			absTopDir, err := filepath.Abs(topDir)
+			if err != nil {
+				return err
+			}
+			if len(name) == 0 {
+				name = filepath.Base(absTopDir)
+			}
+			if name, err = prompt.ask("name", "Project name", name, name); err != nil {
+				return err
+			}
+			if scopes, err = prompt.askList("scopes", "Scope types, from the outermost in", scopes, exampleScopes); err != nil {
+				return err
+			}
+			if len(scopes) == 0 {
+				return fmt.Errorf("a project needs at least one scope type (--scopes)")
+			}
+			if rootsDir, err = prompt.ask("roots-dir", "Roots directory", rootsDir, rootsDir); err != nil {
+				return err
+			}
+			if scopeData, err = prompt.ask("scope-data", "Scope data file", scopeData, scopeData); err != nil {
+				return err
+			}
+
+			projectFilename := filepath.Join(absTopDir, hcl.ProjectFilename)
+			if _, err := os.Stat(projectFilename); err == nil {
+				return fmt.Errorf("%s already exists", relativePath(projectFilename))
+			}
+			project, err := hcl.NewProjectFile(name, rootsDir, scopeData, scopes)
+			if err != nil {
+				return err
+			}
+			if err := os.MkdirAll(filepath.Join(absTopDir, rootsDir), 0755); err != nil {
+				return err
+			}
+			if err := os.WriteFile(projectFilename, project, 0644); err != nil {
+				return err
+			}
+			fmt.Println(relativePath(projectFilename))
+
+			scopeDataFilename := filepath.Join(absTopDir, scopeData)
+			if _, err := os.Stat(scopeDataFilename); err == nil {
+				log.Infof("Keeping the scope data in %s", relativePath(scopeDataFilename))
+			} else {
+				if err := os.MkdirAll(filepath.Dir(scopeDataFilename), 0755); err != nil {
+					return err
+				}
+				if err := os.WriteFile(scopeDataFilename, hcl.NewScopeDataFile(scopes), 0644); err != nil {
+					return err
+				}
+				fmt.Println(relativePath(scopeDataFilename))
+			}
+			log.Infof("Created project %s. Add scope values with `terrascope scope add`, and roots with `terrascope root new`", name)
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&topDir, "dir", ".", "the top directory of the project, where its terrascope.hcl goes")
+	cmd.Flags().StringVar(&name, "name", "", "the name of the project. Defaults to the name of the top directory")
+	cmd.Flags().StringVar(&rootsDir, "roots-dir", "roots", "the directory of the project's roots, relative to the top directory")
+	cmd.Flags().StringVar(&scopeData, "scope-data", "data.hcl", "the file of the project's scope values, relative to the top directory")
+	cmd.Flags().StringSliceVar(&scopes, "scopes", []string{}, "the project's scope types, from the outermost in (e.g. `org,platform,environment`)")
+	cmd.Flags().BoolVar(&noInput, "no-input", false, "don't ask for anything; use the flags' defaults instead")
+
+	return cmd
+}
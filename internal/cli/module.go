@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"strings"
 
 	"github.com/spf13/cobra"
 	"github.com/spilliams/terrascope/internal/hcl"
@@ -17,45 +18,145 @@ func newModuleCommand() *cobra.Command {
 	}
 
 	cmd.AddCommand(newModuleGraphResourcesCommand())
+	cmd.AddCommand(newModuleStateGraphCommand())
+	cmd.AddCommand(newModuleBeautifyCommand())
+	cmd.AddCommand(newModuleImpactCommand())
+	cmd.AddCommand(newModuleLintCommand())
+	cmd.AddCommand(newModuleCyclesCommand())
+	cmd.AddCommand(newModuleDescribeCommand())
+	cmd.AddCommand(newModuleDocsCommand())
+	cmd.AddCommand(newModuleDiffCommand())
+	cmd.AddCommand(newModuleRefactorMovesCommand())
+	cmd.AddCommand(newModuleWhyCommand())
+	cmd.AddCommand(newModuleVersionsCommand())
+	cmd.AddCommand(newModuleUpgradeCommand())
+	cmd.AddCommand(newModuleTreeCommand())
+	cmd.AddCommand(newModuleEvalCommand())
+	cmd.AddCommand(newModuleLocalsCommand())
+	cmd.AddCommand(newModuleCheckVarsCommand())
 
 	return cmd
 }
 
+// graphOptions holds the flags of the graph-resources command
+type graphOptions struct {
+	planFile  string
+	instances bool
+	expand    bool
+	focus     string
+	up        int
+	down      int
+	eval      evalOptions
+}
+
 func newModuleGraphResourcesCommand() *cobra.Command {
-	return &cobra.Command{
+	opts := &graphOptions{}
+
+	cmd := &cobra.Command{
 		Use:   "graph-resources [DIRECTORY]",
 		Short: "(EXPERIMENTAL) graphs the root module at the given directory (`.` by default)",
 		Args:  cobra.MaximumNArgs(1),
 		RunE: func(cmd *cobra.Command, args []string) error {
-			rootDir, err := os.Getwd()
+			if len(opts.focus) == 0 && (cmd.Flags().Changed("up") || cmd.Flags().Changed("down")) {
+				return fmt.Errorf("--up and --down require --focus")
+			}
+			rootDir, err := moduleDirectory(args)
 			if err != nil {
 				return err
 			}
-			if len(args) > 0 {
-				rootDir = args[0]
-				rootDir, err = filepath.Abs(rootDir)
-				if err != nil {
-					return err
-				}
-			}
-			return printModuleGraph(rootDir)
+			return printModuleGraph(rootDir, opts)
 		},
 	}
+
+	cmd.Flags().StringVar(&opts.planFile, "plan", "", "a JSON plan `FILE` (from terraform show -json) to color the graph with")
+	cmd.Flags().BoolVar(&opts.instances, "instances", false, "give references to a particular instance (e.g. aws_instance.web[\"a\"]) a node of their own")
+	cmd.Flags().BoolVar(&opts.expand, "expand", false, "give each instance of a resource or module call with count or for_each a node of its own, where the instances can be found from variable values. Nodes whose instances can't be found are dashed")
+	opts.eval.addFlags(cmd)
+	cmd.MarkFlagsMutuallyExclusive("instances", "expand")
+	cmd.Flags().StringVar(&opts.focus, "focus", "", "only graph the given `ADDRESS` (e.g. var.vpc_cidr) and the nodes connected to it")
+	cmd.Flags().IntVar(&opts.up, "up", -1, "with --focus, how many steps of dependencies to include. Negative means all of them")
+	cmd.Flags().IntVar(&opts.down, "down", -1, "with --focus, how many steps of dependents to include. Negative means all of them")
+
+	return cmd
+}
+
+// moduleDirectory returns the absolute path of the module directory named by
+// the given args, or the working directory if there are none.
+func moduleDirectory(args []string) (string, error) {
+	if len(args) == 0 {
+		return os.Getwd()
+	}
+	return filepath.Abs(args[0])
 }
 
-func printModuleGraph(dir string) error {
+// parseModule reads the configuration at the given directory.
+func parseModule(dir string) (hcl.Module, error) {
 	log.Infof("reading configuration at %s", dir)
 
 	parser := hcl.NewModule(log.Logger)
 	if err := parser.ParseModuleDirectory(dir); err != nil {
+		return nil, err
+	}
+	return parser, nil
+}
+
+func printModuleGraph(dir string, opts *graphOptions) error {
+	parser, err := parseModule(dir)
+	if err != nil {
+		return err
+	}
+
+	var graph *hcl.Graph
+	if opts.instances {
+		graph, err = parser.InstanceGraph()
+	} else if opts.expand {
+		var inputs hcl.InputValues
+		inputs, err = opts.eval.inputs(parser, dir)
+		if err == nil {
+			graph, err = parser.ExpandedGraph(inputs)
+		}
+	} else {
+		graph, err = parser.Graph()
+	}
+	if err != nil {
 		return err
 	}
 
-	graph, err := parser.DependencyGraph()
+	if users := graph.Dependents("terraform.workspace"); len(users) > 0 {
+		log.Infof("terraform.workspace is used by %s", strings.Join(users, ", "))
+	}
+
+	// the plan is applied to the whole graph, so that changes outside the
+	// focus aren't reported as missing
+	if len(opts.planFile) > 0 {
+		plan, err := hcl.ParsePlanFile(opts.planFile)
+		if err != nil {
+			return err
+		}
+		if plan.Errored {
+			log.Warnf("plan %s errored, so its changes may be incomplete", opts.planFile)
+		}
+		unmatched := graph.ApplyPlan(plan)
+		for _, address := range unmatched {
+			log.Warnf("planned change %s does not match any node in the graph", address)
+		}
+	}
+
+	if len(opts.focus) > 0 {
+		graph, err = graph.Subgraph(opts.focus, opts.up, opts.down)
+		if err != nil {
+			return err
+		}
+		for _, node := range graph.Match(opts.focus) {
+			graph.SetNodeAttribute(node, "peripheries", "2")
+		}
+	}
+
+	dot, err := graph.DOT()
 	if err != nil {
 		return err
 	}
-	fmt.Println(graph)
+	fmt.Println(dot)
 	log.Warnf("Note: this graph is experimental and may not represent\n100%% of the resources or relationships of your configuration. If you know how\nthis could be improved, please submit an Issue or a PR to the source repository!\nhttps://github.com/spilliams/terrascope")
 	return nil
 }
@@ -0,0 +1,37 @@
+package cli
+
+import (
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/spf13/cobra"
+	"github.com/spilliams/terrascope/internal/tfgraph"
+)
+
+func newModuleBeautifyCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "beautify < graph.dot",
+		Short: "reads the output of `terraform graph` from stdin, and prints a more readable graph",
+		Long: "Reads the output of `terraform graph` from stdin, and prints a more\n" +
+			"readable DOT-format graph of the same configuration. Terraform's\n" +
+			"internal nodes (providers, meta-nodes and the like) are removed,\n" +
+			"module contents are grouped into clusters, and resources are\n" +
+			"colored by provider.",
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			raw, err := io.ReadAll(os.Stdin)
+			if err != nil {
+				return err
+			}
+			graph, err := tfgraph.Beautify(string(raw))
+			if err != nil {
+				return err
+			}
+			fmt.Println(graph)
+			return nil
+		},
+	}
+
+	return cmd
+}
@@ -0,0 +1,59 @@
+package cli
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+)
+
+func newModuleCheckVarsCommand() *cobra.Command {
+	opts := &evalOptions{}
+
+	cmd := &cobra.Command{
+		Use:   "check-vars [DIRECTORY]",
+		Short: "checks variable values against the variables of the module at the given directory (`.` by default)",
+		Long: "Checks variable values against the variables of the module at the\n" +
+			"given directory (`.` by default), and exits non-zero if it finds any\n" +
+			"problems: values for variables that aren't declared, required\n" +
+			"variables with no value, values that don't match their variable's\n" +
+			"type, and values that break a variable's validation rules.\n\n" +
+			"Like terraform, values come from `terraform.tfvars` and\n" +
+			"`*.auto.tfvars` files, then `--var-file`, then `--var`. Validation\n" +
+			"rules that depend on something only known once the module is\n" +
+			"applied are skipped.",
+		Args:         cobra.MaximumNArgs(1),
+		SilenceUsage: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			dir, err := moduleDirectory(args)
+			if err != nil {
+				return err
+			}
+			parser, err := parseModule(dir)
+			if err != nil {
+				return err
+			}
+			inputs, err := opts.inputs(parser, dir)
+			if err != nil {
+				return err
+			}
+
+			findings, err := parser.CheckVariables(inputs)
+			if err != nil {
+				return err
+			}
+			for _, f := range findings {
+				f.Range.Filename = relativePath(f.Range.Filename)
+				fmt.Println(f)
+			}
+			if len(findings) > 0 {
+				return fmt.Errorf("found %d %s", len(findings), pluralize("problem", "problems", len(findings)))
+			}
+			log.Info("No problems found")
+			return nil
+		},
+	}
+
+	opts.addFlags(cmd)
+
+	return cmd
+}
@@ -0,0 +1,59 @@
+package cli
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+func newModuleCyclesCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "cycles [DIRECTORY]",
+		Short: "finds dependency cycles in the module at the given directory (`.` by default)",
+		Long: "Finds dependency cycles in the module at the given directory (`.` by\n" +
+			"default), and prints each one as a chain of references, with the\n" +
+			"location of every expression that forms a link in the chain. Exits\n" +
+			"non-zero if there are any cycles.",
+		Args:         cobra.MaximumNArgs(1),
+		SilenceUsage: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			dir, err := moduleDirectory(args)
+			if err != nil {
+				return err
+			}
+			parser, err := parseModule(dir)
+			if err != nil {
+				return err
+			}
+			graph, err := parser.Graph()
+			if err != nil {
+				return err
+			}
+
+			cycles := graph.Cycles()
+			for i, cycle := range cycles {
+				fmt.Printf("Cycle %d: %s\n", i+1, strings.Join(cycle.Nodes, ", "))
+				for _, edge := range cycle.Edges {
+					fmt.Printf("\t%s depends on %s\n", edge.From, edge.To)
+					for _, ref := range edge.References {
+						rng := ref.Range
+						rng.Filename = relativePath(rng.Filename)
+						if len(ref.Attribute) > 0 {
+							fmt.Printf("\t\t%s = ...%s... (%s)\n", ref.Attribute, ref.Address, rng)
+						} else {
+							fmt.Printf("\t\t...%s... (%s)\n", ref.Address, rng)
+						}
+					}
+				}
+			}
+			if len(cycles) > 0 {
+				return fmt.Errorf("found %d %s", len(cycles), pluralize("cycle", "cycles", len(cycles)))
+			}
+			log.Info("No cycles found")
+			return nil
+		},
+	}
+
+	return cmd
+}
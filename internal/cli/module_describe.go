@@ -0,0 +1,52 @@
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+)
+
+func newModuleDescribeCommand() *cobra.Command {
+	var printJSON bool
+
+	cmd := &cobra.Command{
+		Use:   "describe [DIRECTORY]",
+		Short: "summarizes the interface of the module at the given directory (`.` by default)",
+		Long: "Summarizes the interface of the module at the given directory (`.` by\n" +
+			"default): its variables (with their types, defaults, descriptions,\n" +
+			"validations and sensitivity), its outputs (with the resources they\n" +
+			"derive from), its required providers, its module calls, and how many\n" +
+			"managed and data resources it has of each type.\n\n" +
+			"Prints Markdown (suitable for a README) by default, or JSON with --json.",
+		Args: cobra.MaximumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			dir, err := moduleDirectory(args)
+			if err != nil {
+				return err
+			}
+			parser, err := parseModule(dir)
+			if err != nil {
+				return err
+			}
+			desc, err := parser.Describe()
+			if err != nil {
+				return err
+			}
+
+			if printJSON {
+				encoder := json.NewEncoder(os.Stdout)
+				encoder.SetEscapeHTML(false)
+				encoder.SetIndent("", "  ")
+				return encoder.Encode(desc)
+			}
+			fmt.Print(desc.Markdown())
+			return nil
+		},
+	}
+
+	cmd.Flags().BoolVar(&printJSON, "json", false, "print the description as JSON instead of Markdown")
+
+	return cmd
+}
@@ -0,0 +1,69 @@
+package cli
+
+import (
+	"fmt"
+	"path/filepath"
+
+	"github.com/spf13/cobra"
+	"github.com/spilliams/terrascope/internal/hcl"
+)
+
+func newModuleDiffCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "diff OLD_DIRECTORY NEW_DIRECTORY",
+		Short: "compares the interfaces of two versions of a module, and suggests a semantic version bump",
+		Long: "Compares the interfaces of two versions of a module: their\n" +
+			"variables, outputs, provider requirements, and the addresses of\n" +
+			"their resources and module calls. Addresses that the new version\n" +
+			"moves, imports or removes with `moved`, `import` or `removed`\n" +
+			"blocks aren't breaking. Each change is classified as\n" +
+			"breaking (it could break a caller) or non-breaking, and the most\n" +
+			"severe change decides the suggested semantic version bump.",
+		Args: cobra.ExactArgs(2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			modules := make([]hcl.Module, len(args))
+			for i, arg := range args {
+				dir, err := filepath.Abs(arg)
+				if err != nil {
+					return err
+				}
+				modules[i], err = parseModule(dir)
+				if err != nil {
+					return err
+				}
+			}
+
+			diff, err := hcl.DiffModules(modules[0], modules[1])
+			if err != nil {
+				return err
+			}
+			log.Infof("Found %d %s", len(diff.Changes), pluralize("change", "changes", len(diff.Changes)))
+
+			breaking := make([]*hcl.InterfaceChange, 0)
+			nonBreaking := make([]*hcl.InterfaceChange, 0)
+			for _, c := range diff.Changes {
+				if c.Breaking() {
+					breaking = append(breaking, c)
+				} else {
+					nonBreaking = append(nonBreaking, c)
+				}
+			}
+			if len(breaking) > 0 {
+				fmt.Println("Breaking changes:")
+				for _, c := range breaking {
+					fmt.Printf("\t%s\n", c)
+				}
+			}
+			if len(nonBreaking) > 0 {
+				fmt.Println("Non-breaking changes:")
+				for _, c := range nonBreaking {
+					fmt.Printf("\t%s (%s)\n", c, c.Bump)
+				}
+			}
+			fmt.Printf("Suggested version bump: %s\n", diff.Bump())
+			return nil
+		},
+	}
+
+	return cmd
+}
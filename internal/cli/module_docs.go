@@ -0,0 +1,88 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/spf13/cobra"
+	"github.com/spilliams/terrascope/internal/docs"
+)
+
+func newModuleDocsCommand() *cobra.Command {
+	var inject string
+	var check bool
+
+	cmd := &cobra.Command{
+		Use:   "docs [DIRECTORY]",
+		Short: "generates provider, input and output tables for the README of the module at the given directory (`.` by default)",
+		Long: "Generates provider, input and output tables for the README of the\n" +
+			"module at the given directory (`.` by default), and prints them.\n\n" +
+			"With --inject FILE, the tables replace the section of FILE between\n" +
+			"the lines\n\n" +
+			"    " + docs.BeginMarker + "\n" +
+			"    " + docs.EndMarker + "\n\n" +
+			"instead. FILE is relative to the module directory. Add --check to\n" +
+			"leave FILE alone, and exit non-zero if it is out of date (e.g. in CI).",
+		Args:         cobra.MaximumNArgs(1),
+		SilenceUsage: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if check && len(inject) == 0 {
+				return fmt.Errorf("--check needs a file to check, given by --inject")
+			}
+			dir, err := moduleDirectory(args)
+			if err != nil {
+				return err
+			}
+			parser, err := parseModule(dir)
+			if err != nil {
+				return err
+			}
+			desc, err := parser.Describe()
+			if err != nil {
+				return err
+			}
+			generated := desc.ReadmeMarkdown()
+
+			if len(inject) == 0 {
+				fmt.Print(generated)
+				return nil
+			}
+
+			filename := inject
+			if !filepath.IsAbs(filename) {
+				filename = filepath.Join(dir, filename)
+			}
+			b, err := os.ReadFile(filename)
+			if err != nil {
+				return err
+			}
+			document, err := docs.Inject(string(b), generated)
+			if err != nil {
+				return fmt.Errorf("%s: %w", relativePath(filename), err)
+			}
+
+			if document == string(b) {
+				log.Infof("%s is up to date", relativePath(filename))
+				return nil
+			}
+			if check {
+				return fmt.Errorf("%s is out of date. Run `terrascope module docs %s --inject %s` to update it", relativePath(filename), relativePath(dir), inject)
+			}
+			info, err := os.Stat(filename)
+			if err != nil {
+				return err
+			}
+			if err := os.WriteFile(filename, []byte(document), info.Mode()); err != nil {
+				return err
+			}
+			log.Infof("Updated %s", relativePath(filename))
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&inject, "inject", "", "the Markdown file (e.g. `README.md`) to inject the tables into")
+	cmd.Flags().BoolVar(&check, "check", false, "with --inject, don't change the file, but fail if it is out of date")
+
+	return cmd
+}
@@ -0,0 +1,141 @@
+package cli
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+	"github.com/spilliams/terrascope/internal/hcl"
+)
+
+// evalOptions holds the flags of the commands that evaluate a module's
+// expressions
+type evalOptions struct {
+	varFiles []string
+	vars     []string
+}
+
+func (opts *evalOptions) addFlags(cmd *cobra.Command) {
+	cmd.Flags().StringArrayVarP(&opts.varFiles, "var-file", "f", []string{}, "a .tfvars (or .tfvars.json) `FILE` to read variable values from. Can be given more than once")
+	cmd.Flags().StringArrayVar(&opts.vars, "var", []string{}, "a variable value, as `NAME=VALUE`. Can be given more than once")
+}
+
+// evaluator reads the module at the given directory, and returns an
+// evaluator for it.
+func (opts *evalOptions) evaluator(dir string) (*hcl.Evaluator, error) {
+	parser, err := parseModule(dir)
+	if err != nil {
+		return nil, err
+	}
+	inputs, err := opts.inputs(parser, dir)
+	if err != nil {
+		return nil, err
+	}
+
+	evaluator, err := parser.Evaluator(inputs)
+	if err != nil {
+		return nil, err
+	}
+	for _, diag := range evaluator.Diagnostics {
+		log.Warn(diag.Error())
+	}
+	return evaluator, nil
+}
+
+// inputs returns the variable values for the given module at the given
+// directory. Like terraform, they come from the module's automatic variables
+// files, then the given variables files, then the given variables, with later
+// values replacing earlier ones.
+func (opts *evalOptions) inputs(parser hcl.Module, dir string) (hcl.InputValues, error) {
+	filenames, err := hcl.AutoVariableFiles(dir)
+	if err != nil {
+		return nil, err
+	}
+	inputs := make(hcl.InputValues)
+	for _, filename := range append(filenames, opts.varFiles...) {
+		log.Debugf("reading variables from %s", filename)
+		values, err := parser.ParseVariableFile(filename)
+		if err != nil {
+			return nil, err
+		}
+		inputs.Merge(values)
+	}
+	for _, arg := range opts.vars {
+		values, err := hcl.ParseVariableFlag(arg)
+		if err != nil {
+			return nil, err
+		}
+		inputs.Merge(values)
+	}
+	return inputs, nil
+}
+
+func newModuleEvalCommand() *cobra.Command {
+	opts := &evalOptions{}
+
+	cmd := &cobra.Command{
+		Use:   "eval [DIRECTORY] EXPRESSION",
+		Short: "evaluates an expression in the module at the given directory (`.` by default), without planning it",
+		Long: "Evaluates an expression in the module at the given directory (`.`\n" +
+			"by default), without planning it. Variables have the values given\n" +
+			"for them (or their defaults), and locals are evaluated from those.\n" +
+			"Anything that is only known once the module is applied, like a\n" +
+			"resource's attributes, is printed as `(known after apply)`.\n\n" +
+			"Most of terraform's functions are available, except those that read\n" +
+			"files or talk to providers.",
+		Args:         cobra.RangeArgs(1, 2),
+		SilenceUsage: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			dir, err := moduleDirectory(args[:len(args)-1])
+			if err != nil {
+				return err
+			}
+			evaluator, err := opts.evaluator(dir)
+			if err != nil {
+				return err
+			}
+			value, err := evaluator.Evaluate(args[len(args)-1])
+			if err != nil {
+				return err
+			}
+			fmt.Println(hcl.FormatValue(value))
+			return nil
+		},
+	}
+
+	opts.addFlags(cmd)
+
+	return cmd
+}
+
+func newModuleLocalsCommand() *cobra.Command {
+	opts := &evalOptions{}
+
+	cmd := &cobra.Command{
+		Use:   "locals [DIRECTORY]",
+		Short: "prints the value of each local in the module at the given directory (`.` by default), without planning it",
+		Long: "Prints the value of each local in the module at the given directory\n" +
+			"(`.` by default), without planning it, in the order they are\n" +
+			"evaluated: each local comes after the locals it refers to. See\n" +
+			"`terrascope module eval` for how values are found.",
+		Args:         cobra.MaximumNArgs(1),
+		SilenceUsage: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			dir, err := moduleDirectory(args)
+			if err != nil {
+				return err
+			}
+			evaluator, err := opts.evaluator(dir)
+			if err != nil {
+				return err
+			}
+			for _, name := range evaluator.LocalOrder {
+				fmt.Printf("local.%s = %s\n", name, hcl.FormatValue(evaluator.Locals[name]))
+			}
+			return nil
+		},
+	}
+
+	opts.addFlags(cmd)
+
+	return cmd
+}
@@ -0,0 +1,62 @@
+package cli
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+// impactHeadings are the headings under which each kind of impact is printed
+var impactHeadings = map[string]string{
+	"resource": "Resources",
+	"data":     "Data sources",
+	"module":   "Module calls",
+	"output":   "Outputs",
+}
+
+func newModuleImpactCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "impact ADDRESS [DIRECTORY]",
+		Short: "lists everything in the module at the given directory (`.` by default) that would be affected by a change to the given address",
+		Long: "Lists every resource, data source, module call and output in the\n" +
+			"module at the given directory (`.` by default) that depends on the\n" +
+			"given address (e.g. `var.vpc_cidr` or `local.tags`), directly or\n" +
+			"transitively. Each is printed with the chain of references through\n" +
+			"which the change flows.",
+		Args: cobra.RangeArgs(1, 2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			dir, err := moduleDirectory(args[1:])
+			if err != nil {
+				return err
+			}
+			parser, err := parseModule(dir)
+			if err != nil {
+				return err
+			}
+			graph, err := parser.Graph()
+			if err != nil {
+				return err
+			}
+
+			impacts, err := graph.Impact(args[0])
+			if err != nil {
+				return err
+			}
+			log.Infof("%d %s affected by %s", len(impacts), pluralize("object", "objects", len(impacts)), args[0])
+
+			var kind string
+			for _, impact := range impacts {
+				if impact.Kind != kind {
+					kind = impact.Kind
+					fmt.Printf("%s:\n", impactHeadings[kind])
+				}
+				fmt.Printf("\t%s\n", impact.Address)
+				fmt.Printf("\t\t%s\n", strings.Join(impact.Path, " -> "))
+			}
+			return nil
+		},
+	}
+
+	return cmd
+}
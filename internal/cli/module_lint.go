@@ -0,0 +1,72 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/spf13/cobra"
+	"github.com/spilliams/terrascope/internal/hcl"
+)
+
+func newModuleLintCommand() *cobra.Command {
+	var unused bool
+
+	cmd := &cobra.Command{
+		Use:   "lint [DIRECTORY]",
+		Short: "checks the module at the given directory (`.` by default) for problems",
+		Long: "Checks the module at the given directory (`.` by default) for\n" +
+			"problems, and exits non-zero if it finds any. If no checks are\n" +
+			"named by flag, all of them are run.",
+		Args:         cobra.MaximumNArgs(1),
+		SilenceUsage: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			dir, err := moduleDirectory(args)
+			if err != nil {
+				return err
+			}
+			parser, err := parseModule(dir)
+			if err != nil {
+				return err
+			}
+
+			all := !unused
+			findings := make([]*hcl.Finding, 0)
+			if all || unused {
+				unusedFindings, err := parser.Unused()
+				if err != nil {
+					return err
+				}
+				findings = append(findings, unusedFindings...)
+			}
+
+			for _, f := range findings {
+				f.Range.Filename = relativePath(f.Range.Filename)
+				fmt.Println(f)
+			}
+			if len(findings) > 0 {
+				return fmt.Errorf("found %d %s", len(findings), pluralize("problem", "problems", len(findings)))
+			}
+			log.Info("No problems found")
+			return nil
+		},
+	}
+
+	cmd.Flags().BoolVar(&unused, "unused", false, "report variables, locals, data sources and provider configurations that nothing uses")
+
+	return cmd
+}
+
+// relativePath returns the given path relative to the working directory, if
+// it can.
+func relativePath(path string) string {
+	wd, err := os.Getwd()
+	if err != nil {
+		return path
+	}
+	rel, err := filepath.Rel(wd, path)
+	if err != nil {
+		return path
+	}
+	return rel
+}
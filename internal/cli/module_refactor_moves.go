@@ -0,0 +1,86 @@
+package cli
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+
+	"github.com/spf13/cobra"
+	"github.com/spilliams/terrascope/internal/hcl"
+)
+
+func newModuleRefactorMovesCommand() *cobra.Command {
+	var filename string
+	var threshold float64
+	var dryRun bool
+
+	cmd := &cobra.Command{
+		Use:   "refactor-moves OLD_DIRECTORY NEW_DIRECTORY",
+		Short: "writes `moved` blocks for the resources that moved between two versions of a configuration",
+		Long: "Matches the resources and module calls that are only in the old\n" +
+			"version of a configuration with those that are only in the new\n" +
+			"version (including those in modules it calls from local\n" +
+			"directories), by type and by how similar their arguments are. A\n" +
+			"`moved` block for each match is appended to a file in the new\n" +
+			"version.\n\n" +
+			"Moves, imports and removals already declared in the new version are\n" +
+			"taken into account, so they aren't written twice.",
+		Args: cobra.ExactArgs(2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			modules := make([]hcl.Module, len(args))
+			for i, arg := range args {
+				dir, err := filepath.Abs(arg)
+				if err != nil {
+					return err
+				}
+				modules[i], err = parseModule(dir)
+				if err != nil {
+					return err
+				}
+			}
+
+			moves, err := hcl.ProposeMoves(modules[0], modules[1], threshold, log.Logger)
+			if err != nil {
+				return err
+			}
+			log.Infof("Found %d %s", len(moves), pluralize("move", "moves", len(moves)))
+			for _, move := range moves {
+				log.Infof("%s (%.0f%% similar)", move, 100*move.Similarity)
+			}
+			if len(moves) == 0 {
+				return nil
+			}
+
+			path := filename
+			if !filepath.IsAbs(path) {
+				path = filepath.Join(args[1], path)
+			}
+			src, err := os.ReadFile(path)
+			if err != nil && !errors.Is(err, fs.ErrNotExist) {
+				return err
+			}
+			out, err := hcl.WriteMoves(src, path, moves)
+			if err != nil {
+				return err
+			}
+
+			if dryRun {
+				fmt.Print(string(out))
+				return nil
+			}
+			if err := os.WriteFile(path, out, 0644); err != nil {
+				return err
+			}
+			log.Infof("Wrote %s", relativePath(path))
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&filename, "file", "moved.tf", "the `FILE` (relative to NEW_DIRECTORY) to append the moved blocks to")
+	cmd.Flags().Float64Var(&threshold, "threshold", 0.5, "how similar (from 0 to 1) two resources' arguments must be for them to match")
+	cmd.Flags().BoolVar(&dryRun, "dry-run", false, "print what the file would contain, instead of writing it")
+
+	return cmd
+}
@@ -0,0 +1,94 @@
+package cli
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/spf13/cobra"
+	"github.com/spilliams/terrascope/internal/hcl"
+)
+
+func newModuleStateGraphCommand() *cobra.Command {
+	var stateFile string
+	var printDOT bool
+
+	cmd := &cobra.Command{
+		Use:   "state-graph [DIRECTORY]",
+		Short: "compares the resources and dependencies recorded in a state file to the configuration of the module at the given directory (`.` by default)",
+		Args:  cobra.MaximumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			dir, err := moduleDirectory(args)
+			if err != nil {
+				return err
+			}
+			state, err := hcl.ParseStateFile(stateFile)
+			if err != nil {
+				return err
+			}
+
+			if printDOT {
+				dot, err := state.Graph().DOT()
+				if err != nil {
+					return err
+				}
+				fmt.Println(dot)
+				return nil
+			}
+
+			parser, err := parseModule(dir)
+			if err != nil {
+				return err
+			}
+			config, err := parser.Graph()
+			if err != nil {
+				return err
+			}
+
+			printStateDrift(state.Drift(config))
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&stateFile, "state", "terraform.tfstate", "the local state file to read")
+	cmd.Flags().BoolVar(&printDOT, "dot", false, "print a DOT-format graph of the state's resource instances instead of comparing it to the configuration")
+
+	return cmd
+}
+
+func printStateDrift(drift *hcl.StateDrift) {
+	count := len(drift.Orphans) + len(drift.Uncreated) + len(drift.MissingDependencies) + len(drift.ExtraDependencies)
+	log.Infof("Found %d %s between the state and the configuration", count, pluralize("difference", "differences", count))
+
+	if len(drift.Orphans) > 0 {
+		fmt.Println("In state, but not in configuration:")
+		for _, address := range drift.Orphans {
+			fmt.Printf("\t%s\n", address)
+		}
+	}
+	if len(drift.Uncreated) > 0 {
+		fmt.Println("In configuration, but not in state:")
+		for _, address := range drift.Uncreated {
+			fmt.Printf("\t%s\n", address)
+		}
+	}
+	printDependencyDrift("Dependencies in configuration, but not in state:", drift.MissingDependencies)
+	printDependencyDrift("Dependencies in state, but not in configuration:", drift.ExtraDependencies)
+}
+
+func printDependencyDrift(heading string, deps map[string][]string) {
+	if len(deps) == 0 {
+		return
+	}
+	fmt.Println(heading)
+	names := make([]string, 0, len(deps))
+	for name := range deps {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		fmt.Printf("\t%s\n", name)
+		for _, dep := range deps[name] {
+			fmt.Printf("\t\t%s\n", dep)
+		}
+	}
+}
@@ -0,0 +1,103 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/spilliams/terrascope/internal/hcl"
+)
+
+func newModuleTreeCommand() *cobra.Command {
+	var printDOT bool
+	var ignore []string
+
+	cmd := &cobra.Command{
+		Use:   "tree [DIRECTORY]",
+		Short: "prints the tree of local modules called by the module at the given directory (`.` by default)",
+		Long: "Prints the tree of modules called from local paths by the module at\n" +
+			"the given directory (`.` by default). If the directory has no\n" +
+			"configuration of its own, every module under it is read, and the\n" +
+			"ones that no other module calls are the roots of the tree. Modules\n" +
+			"are named by their path relative to the working directory.\n\n" +
+			"After the tree, each shared module (one that another module calls)\n" +
+			"is listed with the roots that depend on it, directly or\n" +
+			"transitively: the roots to plan after changing it. Exits non-zero\n" +
+			"if any call forms a cycle, or names a directory that doesn't exist.",
+		Args:         cobra.MaximumNArgs(1),
+		SilenceUsage: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			dir, err := moduleDirectory(args)
+			if err != nil {
+				return err
+			}
+			wd, err := os.Getwd()
+			if err != nil {
+				return err
+			}
+
+			dirs, err := findModuleDirectories(dir, append(ignore, defaultIgnoreNames...))
+			if err != nil {
+				return err
+			}
+			if contains(dirs, dir) {
+				dirs = []string{dir}
+			}
+			tree, err := hcl.LoadModuleTree(dirs, wd)
+			if err != nil {
+				return err
+			}
+			unreadable := make([]string, 0, len(tree.Unreadable))
+			for name := range tree.Unreadable {
+				unreadable = append(unreadable, name)
+			}
+			sort.Strings(unreadable)
+			for _, name := range unreadable {
+				log.Warnf("could not read %s: %v", name, tree.Unreadable[name])
+			}
+			nodes := tree.Graph.Nodes()
+			log.Infof("Found %d %s", len(nodes), pluralize("module", "modules", len(nodes)))
+
+			if printDOT {
+				dot, err := tree.DOT()
+				if err != nil {
+					return err
+				}
+				fmt.Println(dot)
+			} else {
+				fmt.Print(tree)
+				if shared := tree.Shared(); len(shared) > 0 {
+					fmt.Println()
+					fmt.Println("Shared modules:")
+					for _, name := range shared {
+						fmt.Printf("\t%s is used by %s\n", name, strings.Join(tree.DependentRoots(name), ", "))
+					}
+				}
+			}
+
+			for _, site := range tree.Missing {
+				log.Errorf("%s:%d: module.%s calls %s, which doesn't exist", relativePath(site.Pos.Filename), site.Pos.Line, site.Name, site.Source.Address)
+			}
+			cycles := tree.Graph.Cycles()
+			for _, cycle := range cycles {
+				chain := make([]string, 0, len(cycle.Edges)+1)
+				for _, edge := range cycle.Edges {
+					chain = append(chain, edge.From)
+				}
+				chain = append(chain, cycle.Nodes[0])
+				log.Errorf("cycle: %s", strings.Join(chain, " -> "))
+			}
+			if len(tree.Missing)+len(cycles) > 0 {
+				return fmt.Errorf("found %d %s and %d missing %s", len(cycles), pluralize("cycle", "cycles", len(cycles)), len(tree.Missing), pluralize("module", "modules", len(tree.Missing)))
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().BoolVar(&printDOT, "dot", false, "print the tree as a DOT graph")
+	cmd.Flags().StringArrayVarP(&ignore, "ignore", "i", []string{}, "names to ignore. `.terraform/` is appended to this list internally.")
+
+	return cmd
+}
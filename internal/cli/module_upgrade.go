@@ -0,0 +1,112 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/spilliams/terrascope/internal/hcl"
+	"github.com/spilliams/terrascope/internal/textdiff"
+)
+
+func newModuleUpgradeCommand() *cobra.Command {
+	opts := &inventoryOptions{}
+	var dryRun bool
+
+	cmd := &cobra.Command{
+		Use:   "upgrade SOURCE[@VERSION] NEW_VERSION",
+		Short: "upgrades every call of the given module in or under the top directory to a new version",
+		Long: "Upgrades every call of the given module in or under the top\n" +
+			"directory to a new version, by rewriting the `version` argument of\n" +
+			"a registry module, or the `ref` of a git module. A git ref keeps\n" +
+			"its `v` prefix, or its lack of one, whichever way the new version is\n" +
+			"given. The rest of each file keeps its formatting. If a version is\n" +
+			"given with the source, only calls with exactly that version are\n" +
+			"upgraded.\n\n" +
+			"Only calls pinned to an exact version are upgraded. Calls pinned by\n" +
+			"a range (e.g. `~> 1.2`), or to a git ref that isn't a version, are\n" +
+			"skipped and listed at the end.",
+		Args: cobra.ExactArgs(2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			baseDir, err := filepath.Abs(opts.dir)
+			if err != nil {
+				return err
+			}
+			target, err := hcl.ParseModuleSourceArg(args[0], baseDir)
+			if err != nil {
+				return err
+			}
+			version := args[1]
+
+			sites, err := opts.moduleCalls()
+			if err != nil {
+				return err
+			}
+			filenames := make([]string, 0)
+			for _, site := range sites {
+				if site.Source.Address == target.Address && !contains(filenames, site.Pos.Filename) {
+					filenames = append(filenames, site.Pos.Filename)
+				}
+			}
+			sort.Strings(filenames)
+
+			upgraded := 0
+			skipped := make([]string, 0)
+			for _, filename := range filenames {
+				if strings.HasSuffix(filename, ".json") {
+					skipped = append(skipped, fmt.Sprintf("%s: JSON configuration can't be rewritten", relativePath(filename)))
+					continue
+				}
+				src, err := os.ReadFile(filename)
+				if err != nil {
+					return err
+				}
+				result, upgrades, err := hcl.UpgradeModuleCalls(src, filename, target, version, filepath.Dir(filename), baseDir)
+				if err != nil {
+					return fmt.Errorf("could not upgrade %s: %w", relativePath(filename), err)
+				}
+				for _, upgrade := range upgrades {
+					if len(upgrade.Skipped) > 0 {
+						skipped = append(skipped, fmt.Sprintf("%s: %s", relativePath(filename), upgrade))
+						continue
+					}
+					log.Infof("%s: %s", relativePath(filename), upgrade)
+					upgraded++
+				}
+				if string(result) == string(src) {
+					continue
+				}
+
+				if dryRun {
+					name := filepath.ToSlash(relativePath(filename))
+					fmt.Print(textdiff.Unified("a/"+name, "b/"+name, string(src), string(result)))
+					continue
+				}
+				if err := os.WriteFile(filename, result, 0644); err != nil {
+					return err
+				}
+			}
+
+			if dryRun {
+				log.Infof("Would upgrade %d %s", upgraded, pluralize("call", "calls", upgraded))
+			} else {
+				log.Infof("Upgraded %d %s", upgraded, pluralize("call", "calls", upgraded))
+			}
+			if len(skipped) > 0 {
+				log.Warnf("Skipped %d %s:", len(skipped), pluralize("call", "calls", len(skipped)))
+				for _, s := range skipped {
+					log.Warnf("  %s", s)
+				}
+			}
+			return nil
+		},
+	}
+
+	opts.addFlags(cmd)
+	cmd.Flags().BoolVar(&dryRun, "dry-run", false, "print a diff of each file instead of writing it")
+
+	return cmd
+}
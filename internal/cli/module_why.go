@@ -0,0 +1,191 @@
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/spilliams/terrascope/internal/hcl"
+)
+
+// inventoryOptions holds the flags of the commands that look at every module
+// under a directory
+type inventoryOptions struct {
+	dir    string
+	ignore []string
+}
+
+func (opts *inventoryOptions) addFlags(cmd *cobra.Command) {
+	cmd.Flags().StringVar(&opts.dir, "dir", ".", "the directory to search")
+	cmd.Flags().StringArrayVarP(&opts.ignore, "ignore", "i", []string{}, "names to ignore. `.terraform/` is appended to this list internally.")
+}
+
+// moduleCalls returns the module calls of every module in or under the
+// receiver's directory, with their sources normalized against that
+// directory. Modules that can't be read are skipped with a warning.
+func (opts *inventoryOptions) moduleCalls() ([]*hcl.ModuleCallSite, error) {
+	baseDir, err := filepath.Abs(opts.dir)
+	if err != nil {
+		return nil, err
+	}
+	dirs, err := findModuleDirectories(baseDir, append(opts.ignore, defaultIgnoreNames...))
+	if err != nil {
+		return nil, err
+	}
+	log.Infof("Found %d %s", len(dirs), pluralize("module", "modules", len(dirs)))
+
+	sites := make([]*hcl.ModuleCallSite, 0)
+	for _, dir := range dirs {
+		calls, err := hcl.LoadModuleCalls(dir, baseDir)
+		if err != nil {
+			log.Warnf("could not read %s: %v", relativePath(dir), err)
+			continue
+		}
+		sites = append(sites, calls...)
+	}
+	return sites, nil
+}
+
+// findModuleDirectories returns the directories in or under the given one
+// that contain terraform configuration files, sorted.
+func findModuleDirectories(dir string, ignoreNames []string) ([]string, error) {
+	dirs := make([]string, 0)
+	err := filepath.WalkDir(dir, func(fullpath string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		for _, ignoreName := range ignoreNames {
+			if strings.Contains(fullpath+"/", ignoreName) {
+				if entry.IsDir() {
+					return filepath.SkipDir
+				}
+				return nil
+			}
+		}
+		if entry.IsDir() {
+			return nil
+		}
+		if strings.HasSuffix(entry.Name(), ".tf") || strings.HasSuffix(entry.Name(), ".tf.json") {
+			parent := filepath.Dir(fullpath)
+			if !contains(dirs, parent) {
+				dirs = append(dirs, parent)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(dirs)
+	return dirs, nil
+}
+
+func newModuleWhyCommand() *cobra.Command {
+	opts := &inventoryOptions{}
+
+	cmd := &cobra.Command{
+		Use: "why SOURCE[@VERSION]",
+		Short: "prints out all the modules in or under the top directory that " +
+			"call the given module",
+		Long: "Prints out all the modules in or under the top directory that\n" +
+			"call the given module. Sources are compared after normalizing them,\n" +
+			"so `hashicorp/consul/aws` matches\n" +
+			"`registry.terraform.io/hashicorp/consul/aws`, and\n" +
+			"`git::https://github.com/acme/modules.git` matches\n" +
+			"`github.com/acme/modules`. Local paths are relative to the working\n" +
+			"directory.\n\n" +
+			"If a version is given, only calls with exactly that version\n" +
+			"constraint (or git ref) match.",
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			baseDir, err := filepath.Abs(opts.dir)
+			if err != nil {
+				return err
+			}
+			target, err := hcl.ParseModuleSourceArg(args[0], baseDir)
+			if err != nil {
+				return err
+			}
+			sites, err := opts.moduleCalls()
+			if err != nil {
+				return err
+			}
+
+			matches := make([]string, 0)
+			for _, site := range sites {
+				if site.Source.Address != target.Address {
+					continue
+				}
+				if len(target.Version) == 0 || site.Source.Version == target.Version {
+					matches = append(matches, fmt.Sprintf("%s calls %s as module.%s (%s:%d)",
+						relativePath(site.Dir), site.Source, site.Name, relativePath(site.Pos.Filename), site.Pos.Line))
+				}
+			}
+
+			log.Infof("%d %s found with the module %s", len(matches), pluralize("call", "calls", len(matches)), target)
+
+			for _, match := range matches {
+				fmt.Println(match)
+			}
+
+			return nil
+		},
+	}
+
+	opts.addFlags(cmd)
+
+	return cmd
+}
+
+func newModuleVersionsCommand() *cobra.Command {
+	opts := &inventoryOptions{}
+
+	cmd := &cobra.Command{
+		Use: "versions",
+		Short: "prints out all the versions of each module called in or under " +
+			"the top directory",
+		Long: "Prints out all the versions of each module called in or under the\n" +
+			"top directory, keyed by normalized source. A version is a registry\n" +
+			"module's version constraint, or a git module's ref. Local modules\n" +
+			"have no versions.",
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			sites, err := opts.moduleCalls()
+			if err != nil {
+				return err
+			}
+
+			versions := make(map[string][]string, 0)
+			versionCount := 0
+			for _, site := range sites {
+				address := site.Source.Address
+				if versions[address] == nil {
+					versions[address] = make([]string, 0)
+				}
+				if len(site.Source.Version) > 0 && !contains(versions[address], site.Source.Version) {
+					versions[address] = append(versions[address], site.Source.Version)
+					versionCount++
+				}
+			}
+			for _, v := range versions {
+				hcl.SortVersions(v)
+			}
+			log.Infof("Found %d module %s of %d %s", versionCount, pluralize("version", "versions", versionCount), len(versions), pluralize("source", "sources", len(versions)))
+
+			// constraints like "~> 1.0" shouldn't be escaped
+			encoder := json.NewEncoder(os.Stdout)
+			encoder.SetEscapeHTML(false)
+			encoder.SetIndent("", "  ")
+			return encoder.Encode(versions)
+		},
+	}
+
+	opts.addFlags(cmd)
+
+	return cmd
+}
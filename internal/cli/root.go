@@ -0,0 +1,368 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/spilliams/terrascope/internal/hcl"
+	"github.com/spilliams/terrascope/internal/textdiff"
+)
+
+func newRootCommand() *cobra.Command {
+	opts := &projectOptions{}
+
+	cmd := &cobra.Command{
+		Use:     "root COMMAND",
+		Aliases: []string{"roots"},
+		Short:   "A toolbox for working with the roots of a terrascope project",
+		Long: "A toolbox for working with the roots of a terrascope project.\n\n" +
+			"A root is a directory in the project's roots directory with a\n" +
+			"terrascope.hcl that has a root block. The block's `scopes` are the\n" +
+			"scope types the root is built for, which must be the first of the\n" +
+			"project's scope types, in order.",
+	}
+
+	opts.addFlags(cmd)
+
+	cmd.AddCommand(newRootNewCommand(opts))
+	cmd.AddCommand(newRootBuildCommand(opts))
+	cmd.AddCommand(newRootBackendsCommand(opts))
+
+	return cmd
+}
+
+func newRootNewCommand(opts *projectOptions) *cobra.Command {
+	var scopes, providers []string
+	var noInput bool
+
+	cmd := &cobra.Command{
+		Use:   "new NAME",
+		Short: "creates a new root in the project's roots directory",
+		Long: "Creates a new root in the project's roots directory, with:\n" +
+			"  - terrascope.hcl, with the scope types it is built for.\n" +
+			"  - main.tf, with a variable for each of them.\n" +
+			"  - versions.tf, which requires each provider locked in the\n" +
+			"    project's `.terraform.lock.hcl` files (or just the ones given\n" +
+			"    with `--providers`), pinned to the version most of them lock.\n" +
+			"  - backend.tf, with the project's backend, if it has one. Arguments\n" +
+			"    that depend on the scope are filled in by `terrascope root build`.\n\n" +
+			"When run at a terminal, asks for the scope types if `--scopes`\n" +
+			"isn't given.",
+		Args:         cobra.ExactArgs(1),
+		SilenceUsage: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			project, err := opts.project()
+			if err != nil {
+				return err
+			}
+			prompt := newPrompter(cmd, noInput)
+			if scopes, err = prompt.askList("scopes", "Scope types, from the outermost in", scopes, project.ScopeTypeNames()); err != nil {
+				return err
+			}
+			root, err := project.NewRoot(args[0], scopes)
+			if err != nil {
+				return err
+			}
+
+			required, err := projectProviders(project)
+			if err != nil {
+				return err
+			}
+			if cmd.Flags().Changed("providers") {
+				if required, err = selectProviders(required, providers); err != nil {
+					return err
+				}
+			}
+			files, err := project.ScaffoldRoot(root, required)
+			if err != nil {
+				return err
+			}
+
+			if err := os.MkdirAll(root.Dir, 0755); err != nil {
+				return err
+			}
+			names := make([]string, 0, len(files))
+			for name := range files {
+				names = append(names, name)
+			}
+			sort.Strings(names)
+			for _, name := range names {
+				filename := filepath.Join(root.Dir, name)
+				if err := os.WriteFile(filename, files[name], 0644); err != nil {
+					return err
+				}
+				fmt.Println(relativePath(filename))
+			}
+			log.Infof("Created root %s. Build it with `terrascope root build %s`", root.Name, root.Name)
+			return nil
+		},
+	}
+
+	cmd.Flags().StringSliceVar(&scopes, "scopes", []string{}, "the scope types the root is built for, which must be the first of the project's, in order (e.g. `org,platform`)")
+	cmd.Flags().StringSliceVar(&providers, "providers", []string{}, "the providers the root requires, by name or source (e.g. `aws,hashicorp/random`). Defaults to every provider locked in the project")
+	cmd.Flags().BoolVar(&noInput, "no-input", false, "don't ask for anything; use the flags' defaults instead")
+
+	return cmd
+}
+
+// projectProviders returns each provider locked by a lockfile in the given
+// project, with the version most of them lock it to.
+func projectProviders(project *hcl.Project) ([]*hcl.RequiredProvider, error) {
+	ignore := append([]string{project.BuildDir + "/"}, defaultIgnoreNames...)
+	filenames, err := findAll(".terraform.lock.hcl", filepath.Dir(project.Filename), ignore)
+	if err != nil {
+		return nil, err
+	}
+	lockfiles := make([]*hcl.Lockfile, 0, len(filenames))
+	for _, filename := range filenames {
+		lockfile, err := hcl.ParseLockfile(filename)
+		if err != nil {
+			return nil, err
+		}
+		lockfiles = append(lockfiles, lockfile)
+	}
+	providers := hcl.MostCommonProviderVersions(lockfiles)
+	log.Debugf("Found %d %s in %d %s", len(providers), pluralize("provider", "providers", len(providers)), len(lockfiles), pluralize("lockfile", "lockfiles", len(lockfiles)))
+	return providers, nil
+}
+
+// selectProviders returns the given providers that have one of the given
+// names or sources.
+func selectProviders(providers []*hcl.RequiredProvider, names []string) ([]*hcl.RequiredProvider, error) {
+	selected := make([]*hcl.RequiredProvider, 0, len(names))
+	for _, name := range names {
+		var found *hcl.RequiredProvider
+		for _, provider := range providers {
+			if provider.Name == name || provider.Source == name {
+				found = provider
+				break
+			}
+		}
+		if found == nil {
+			return nil, fmt.Errorf("no lockfile in the project locks a provider named %s", name)
+		}
+		if !contains(selected, found) {
+			selected = append(selected, found)
+		}
+	}
+	return selected, nil
+}
+
+func newRootBuildCommand(opts *projectOptions) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "build ROOT [FILTER...]",
+		Short: "renders a copy of the given root for each of its scope values that matches any of the given filters",
+		Long: "Renders a copy of the given root (a name, or a directory) for each of\n" +
+			"its scope values that matches any of the given filters, or for all\n" +
+			"of them if no filters are given, and prints the directories of the\n" +
+			"copies. Each part of a filter is a pattern for the name of the value\n" +
+			"of the same depth, like `acme.gold.*.*`.\n\n" +
+			"Each copy is in the project's build directory (`.terrascope` by\n" +
+			"default), under the root's name and the names of its scope value,\n" +
+			"and has two more files: `terrascope.auto.tfvars`, with a value for\n" +
+			"each of the root's variables that is named for a scope type or an\n" +
+			"attribute of the scope value, and `terrascope_backend.tf`, with the\n" +
+			"project's backend. If the root has a backend of the same type, that\n" +
+			"file is an override of it (`terrascope_backend_override.tf`); if it\n" +
+			"has one of another type, it keeps it. Local module sources are\n" +
+			"rewritten to work from the copy.",
+		Args:         cobra.MinimumNArgs(1),
+		SilenceUsage: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			project, err := opts.project()
+			if err != nil {
+				return err
+			}
+			root, err := project.FindRoot(args[0])
+			if err != nil {
+				return err
+			}
+			filters, err := scopeFilters(args[1:])
+			if err != nil {
+				return err
+			}
+			data, err := project.LoadScopeData()
+			if err != nil {
+				return err
+			}
+			values := data.Match(len(root.Scopes), filters)
+			if len(values) == 0 {
+				return fmt.Errorf("no %s values match %s", strings.Join(root.Scopes, "."), strings.Join(args[1:], ", "))
+			}
+
+			module, err := parseModule(root.Dir)
+			if err != nil {
+				return err
+			}
+			for _, value := range values {
+				dir, err := project.BuildRoot(root, module, value)
+				if err != nil {
+					return err
+				}
+				log.Debugf("built %s for %q", root.Name, value.Address())
+				fmt.Println(relativePath(dir))
+			}
+			log.Infof("Built %s for %d scope %s", root.Name, len(values), pluralize("value", "values", len(values)))
+			return nil
+		},
+	}
+
+	return cmd
+}
+
+func newRootBackendsCommand(opts *projectOptions) *cobra.Command {
+	var configPattern string
+	var generate, dryRun bool
+
+	cmd := &cobra.Command{
+		Use:   "backends [ROOT...]",
+		Short: "checks that each of the given roots keeps its state somewhere of its own",
+		Long: "Prints where each of the given roots (names, or directories) keeps\n" +
+			"its state, or every root in the project if none are given. A root\n" +
+			"that is built with the project's backend has a state for each of its\n" +
+			"scope values; otherwise it has the one its backend block says. Each\n" +
+			"of a root's backend configuration files (`*.tfbackend` by default,\n" +
+			"as given to `terraform init -backend-config`) is merged into its\n" +
+			"backend, and makes a state of its own.\n\n" +
+			"Exits non-zero if two states are kept in the same place (like the\n" +
+			"same S3 bucket and key), so would overwrite each other, or if a\n" +
+			"root has no backend.\n\n" +
+			"With `--generate`, writes each root's backend from the project's\n" +
+			"backend instead, which can use `path` (the root's directory,\n" +
+			"relative to the project's) as well as `root`. A root with no backend\n" +
+			"gets one in its backend.tf, which is made if it doesn't exist; a\n" +
+			"root with a backend of the same type has it replaced where it is\n" +
+			"declared. Arguments that depend on the scope are filled in by\n" +
+			"`terrascope root build`.",
+		SilenceUsage: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			project, err := opts.project()
+			if err != nil {
+				return err
+			}
+			roots := make([]*hcl.Root, 0, len(args))
+			if len(args) == 0 {
+				if roots, err = project.Roots(); err != nil {
+					return err
+				}
+			}
+			for _, arg := range args {
+				root, err := project.FindRoot(arg)
+				if err != nil {
+					return err
+				}
+				roots = append(roots, root)
+			}
+
+			modules := make([]hcl.Module, len(roots))
+			for i, root := range roots {
+				log.Debugf("reading configuration at %s", relativePath(root.Dir))
+				modules[i] = hcl.NewModule(log.Logger)
+				if err := modules[i].ParseModuleDirectory(root.Dir); err != nil {
+					return err
+				}
+			}
+			if generate {
+				return generateRootBackends(project, roots, modules, dryRun)
+			}
+
+			data, err := project.LoadScopeData()
+			if err != nil {
+				return err
+			}
+			states := make([]*hcl.RootState, 0)
+			noBackend := make([]string, 0)
+			for i, root := range roots {
+				switch hcl.ModuleBackendType(modules[i]) {
+				case "cloud":
+					log.Infof("%s uses HCP Terraform, which keeps each workspace's state", root.Name)
+					continue
+				case "":
+					if project.Backend == nil {
+						log.Errorf("%s has no backend, so it keeps its state wherever terraform runs", root.Name)
+						noBackend = append(noBackend, root.Name)
+						continue
+					}
+				}
+				rootStates, err := project.RootStates(root, modules[i], data, configPattern)
+				if err != nil {
+					return err
+				}
+				states = append(states, rootStates...)
+			}
+
+			for _, state := range states {
+				location, ok, missing := state.Location()
+				if !ok && len(missing) > 0 {
+					log.Warnf("%s: %s has no %s, so where its state is kept isn't known until terraform init", relativePath(state.DefRange.String()), state, strings.Join(missing, ", "))
+				}
+				fmt.Printf("%s\t%s\n", state, location)
+			}
+			duplicates := hcl.DuplicateStates(states)
+			for _, group := range duplicates {
+				names := make([]string, len(group))
+				for i, state := range group {
+					names[i] = state.String()
+				}
+				location, _, _ := group[0].Location()
+				log.Errorf("%s all keep their state at %s", strings.Join(names, ", "), location)
+			}
+			log.Infof("Found %d %s in %d %s", len(states), pluralize("state", "states", len(states)), len(roots), pluralize("root", "roots", len(roots)))
+			if len(duplicates)+len(noBackend) > 0 {
+				return fmt.Errorf("found %d duplicate %s and %d %s with no backend", len(duplicates), pluralize("state", "states", len(duplicates)), len(noBackend), pluralize("root", "roots", len(noBackend)))
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&configPattern, "backend-config", hcl.DefaultBackendConfigPattern, "the pattern of the backend configuration files in each root")
+	cmd.Flags().BoolVar(&generate, "generate", false, "write each root's backend from the project's backend, instead of checking them")
+	cmd.Flags().BoolVar(&dryRun, "dry-run", false, "with --generate, print a diff of each backend file instead of writing it")
+
+	return cmd
+}
+
+// generateRootBackends writes the backend of each of the given roots (read
+// into the given modules) from the given project's backend, or prints a diff
+// of each file that would change if dryRun is true.
+func generateRootBackends(project *hcl.Project, roots []*hcl.Root, modules []hcl.Module, dryRun bool) error {
+	changed := 0
+	for i, root := range roots {
+		filename, result, err := project.GenerateRootBackend(root, modules[i])
+		if err != nil {
+			return err
+		}
+		if len(filename) == 0 {
+			log.Infof("%s has a backend of another type, so it keeps it", root.Name)
+			continue
+		}
+		src, err := os.ReadFile(filename)
+		if err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		if string(src) == string(result) {
+			log.Debugf("%s's backend is already up to date", root.Name)
+			continue
+		}
+		changed++
+		if dryRun {
+			name := filepath.ToSlash(relativePath(filename))
+			fmt.Print(textdiff.Unified("a/"+name, "b/"+name, string(src), string(result)))
+			continue
+		}
+		if err := os.WriteFile(filename, result, 0644); err != nil {
+			return err
+		}
+		fmt.Println(relativePath(filename))
+	}
+	if dryRun {
+		log.Infof("%d %s would change", changed, pluralize("backend", "backends", changed))
+		return nil
+	}
+	log.Infof("Generated %d %s", changed, pluralize("backend", "backends", changed))
+	return nil
+}
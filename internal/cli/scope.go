@@ -0,0 +1,234 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/spf13/cobra"
+	"github.com/spilliams/terrascope/internal/hcl"
+	"github.com/spilliams/terrascope/internal/textdiff"
+)
+
+// projectOptions holds the flags of the commands that work on a terrascope
+// project
+type projectOptions struct {
+	filename string
+}
+
+func (opts *projectOptions) addFlags(cmd *cobra.Command) {
+	cmd.PersistentFlags().StringVar(&opts.filename, "project", "", "the project's `terrascope.hcl`. Defaults to the nearest one with a project block, in the working directory or its parents")
+}
+
+// project reads the receiver's project file, or finds it if none was given.
+func (opts *projectOptions) project() (*hcl.Project, error) {
+	filename := opts.filename
+	if len(filename) == 0 {
+		var err error
+		filename, err = hcl.FindProjectFile(".")
+		if err != nil {
+			return nil, err
+		}
+	}
+	log.Debugf("reading project %s", relativePath(filename))
+	return hcl.LoadProject(filename)
+}
+
+// scopeFilters parses each of the given scope filters.
+func scopeFilters(args []string) ([]hcl.ScopeFilter, error) {
+	filters := make([]hcl.ScopeFilter, len(args))
+	for i, arg := range args {
+		filter, err := hcl.ParseScopeFilter(arg)
+		if err != nil {
+			return nil, err
+		}
+		filters[i] = filter
+	}
+	return filters, nil
+}
+
+func newScopeCommand() *cobra.Command {
+	opts := &projectOptions{}
+
+	cmd := &cobra.Command{
+		Use:   "scope COMMAND",
+		Short: "A toolbox for working with the scope values of a terrascope project",
+		Long: "A toolbox for working with the scope values of a terrascope project.\n\n" +
+			"A project's terrascope.hcl declares its scope types (like org,\n" +
+			"platform and environment), from the outermost in. Its scope data\n" +
+			"files declare the values of those types, each nested in a value of\n" +
+			"the type before it. A value's address is the names of it and the\n" +
+			"values it is inside of, joined by dots (e.g. `acme.gold.product`).",
+	}
+
+	opts.addFlags(cmd)
+
+	cmd.AddCommand(newScopeListCommand(opts))
+	cmd.AddCommand(newScopeAddCommand(opts))
+
+	return cmd
+}
+
+func newScopeListCommand(opts *projectOptions) *cobra.Command {
+	var attributes bool
+
+	cmd := &cobra.Command{
+		Use:   "list [FILTER...]",
+		Short: "prints the address of each scope value in the project that matches any of the given filters",
+		Long: "Prints the address of each scope value in the project that matches\n" +
+			"any of the given filters, each followed by the values inside it.\n" +
+			"Each part of a filter is a pattern for the name of the value of the\n" +
+			"same depth, like `acme.gold.*.*`. A value matches if it is at\n" +
+			"least as deep as the filter. With no filters, every value is\n" +
+			"printed.",
+		SilenceUsage: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			project, err := opts.project()
+			if err != nil {
+				return err
+			}
+			filters, err := scopeFilters(args)
+			if err != nil {
+				return err
+			}
+			data, err := project.LoadScopeData()
+			if err != nil {
+				return err
+			}
+
+			count := 0
+			for _, value := range data.All() {
+				if !matchesAnyFilter(value, filters) {
+					continue
+				}
+				count++
+				fmt.Println(value.Address())
+				if !attributes {
+					continue
+				}
+				attrs := value.AllAttributes()
+				names := make([]string, 0, len(attrs))
+				for name := range attrs {
+					names = append(names, name)
+				}
+				sort.Strings(names)
+				for _, name := range names {
+					fmt.Printf("\t%s = %s\n", name, hcl.FormatValue(attrs[name]))
+				}
+			}
+			log.Infof("Found %d scope %s", count, pluralize("value", "values", count))
+			return nil
+		},
+	}
+
+	cmd.Flags().BoolVar(&attributes, "attributes", false, "print each value's attributes, including the ones it inherits")
+
+	return cmd
+}
+
+func newScopeAddCommand(opts *projectOptions) *cobra.Command {
+	var from string
+	var dryRun bool
+
+	cmd := &cobra.Command{
+		Use:   "add ADDRESS",
+		Short: "adds a new scope value to the project's scope data",
+		Long: "Adds a new scope value (e.g. `acme.gold.product.qa`) to the\n" +
+			"project's scope data, in the file that declares the value it is\n" +
+			"inside of. The value's type is the scope type of its depth, and the\n" +
+			"value it is inside of must already exist. The rest of the file keeps\n" +
+			"its comments and formatting.\n\n" +
+			"With `--from`, the new value starts with a copy of the attributes of\n" +
+			"a sibling (a value inside the same one). Either way, it inherits the\n" +
+			"attributes of the values it is inside of.\n\n" +
+			"Afterwards, prints the roots that are built for values of the new\n" +
+			"value's type, which now need building for it.",
+		Args:         cobra.ExactArgs(1),
+		SilenceUsage: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			project, err := opts.project()
+			if err != nil {
+				return err
+			}
+			data, err := project.LoadScopeData()
+			if err != nil {
+				return err
+			}
+			value, err := data.NewValue(args[0])
+			if err != nil {
+				return err
+			}
+			if len(from) > 0 {
+				sibling := value.Sibling(from)
+				if sibling == nil {
+					return fmt.Errorf("there is no %s %q next to %q to copy attributes from", value.Type, from, value.Address())
+				}
+				for name, attr := range sibling.Attributes {
+					value.Attributes[name] = attr
+				}
+			}
+
+			filename := project.ScopeDataFile(value.Parent)
+			src, err := os.ReadFile(filename)
+			if err != nil {
+				return err
+			}
+			result, err := hcl.AddScopeValue(src, filename, value)
+			if err != nil {
+				return err
+			}
+			if dryRun {
+				name := filepath.ToSlash(relativePath(filename))
+				fmt.Print(textdiff.Unified("a/"+name, "b/"+name, string(src), string(result)))
+			} else {
+				if err := os.WriteFile(filename, result, 0644); err != nil {
+					return err
+				}
+				log.Infof("Added %s %q to %s", value.Type, value.Address(), relativePath(filename))
+			}
+
+			roots, err := project.Roots()
+			if err != nil {
+				return err
+			}
+			toBuild := make([]string, 0)
+			for _, root := range roots {
+				switch {
+				case len(root.Scopes) == value.Depth():
+					toBuild = append(toBuild, root.Name)
+				case len(root.Scopes) > value.Depth():
+					log.Infof("%s is built for %s values, so it will need building once they are added inside %q", root.Name, root.Scopes[len(root.Scopes)-1], value.Address())
+				}
+			}
+			if len(toBuild) == 0 {
+				log.Infof("No roots are built for %s values", value.Type)
+				return nil
+			}
+			fmt.Printf("Roots to build for %q:\n", value.Address())
+			for _, name := range toBuild {
+				fmt.Printf("\t%s\n", name)
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&from, "from", "", "the name of a sibling value to copy the attributes of")
+	cmd.Flags().BoolVar(&dryRun, "dry-run", false, "print a diff of the data file instead of writing it")
+
+	return cmd
+}
+
+// matchesAnyFilter returns whether the given value is at least as deep as
+// one of the given filters, and matches it. Every value matches no filters.
+func matchesAnyFilter(value *hcl.ScopeValue, filters []hcl.ScopeFilter) bool {
+	if len(filters) == 0 {
+		return true
+	}
+	for _, filter := range filters {
+		if value.Depth() >= len(filter) && filter.Match(value) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,46 @@
+// Package docs provides functions for keeping generated documentation up to
+// date inside hand-written documents.
+package docs
+
+import (
+	"fmt"
+	"strings"
+)
+
+// BeginMarker and EndMarker mark the section of a document that Inject
+// replaces. Everything outside the markers is left alone.
+const (
+	BeginMarker = "<!-- BEGIN_TERRASCOPE_DOCS -->"
+	EndMarker   = "<!-- END_TERRASCOPE_DOCS -->"
+)
+
+// Inject returns the given document with the section between its markers
+// replaced by the given generated content. It is an error for the document
+// to be missing either marker, or to have them out of order.
+func Inject(document, generated string) (string, error) {
+	begin := strings.Index(document, BeginMarker)
+	if begin < 0 {
+		return "", fmt.Errorf("could not find %s. Add it (and %s) where the generated documentation should go", BeginMarker, EndMarker)
+	}
+	end := strings.Index(document, EndMarker)
+	if end < 0 {
+		return "", fmt.Errorf("could not find %s. Add it after %s", EndMarker, BeginMarker)
+	}
+	if end < begin {
+		return "", fmt.Errorf("%s must come after %s", EndMarker, BeginMarker)
+	}
+	if strings.Count(document, BeginMarker) > 1 || strings.Count(document, EndMarker) > 1 {
+		return "", fmt.Errorf("found more than one %s or %s, but only one section can be generated", BeginMarker, EndMarker)
+	}
+
+	generated = strings.TrimSpace(generated)
+	var b strings.Builder
+	b.WriteString(document[:begin+len(BeginMarker)])
+	b.WriteString("\n")
+	if len(generated) > 0 {
+		b.WriteString(generated)
+		b.WriteString("\n")
+	}
+	b.WriteString(document[end:])
+	return b.String(), nil
+}
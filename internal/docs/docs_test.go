@@ -0,0 +1,73 @@
+package docs
+
+import (
+	"testing"
+)
+
+func TestInject(t *testing.T) {
+	type test struct {
+		name        string
+		document    string
+		generated   string
+		expected    string
+		expectError bool
+	}
+	tests := []test{
+		{
+			name:      "empty section",
+			document:  "# module\n\n<!-- BEGIN_TERRASCOPE_DOCS -->\n<!-- END_TERRASCOPE_DOCS -->\n\nFooter\n",
+			generated: "## Inputs\n",
+			expected:  "# module\n\n<!-- BEGIN_TERRASCOPE_DOCS -->\n## Inputs\n<!-- END_TERRASCOPE_DOCS -->\n\nFooter\n",
+		},
+		{
+			name:      "stale section",
+			document:  "<!-- BEGIN_TERRASCOPE_DOCS -->\n## Old\n\nstuff\n<!-- END_TERRASCOPE_DOCS -->",
+			generated: "\n## New\n",
+			expected:  "<!-- BEGIN_TERRASCOPE_DOCS -->\n## New\n<!-- END_TERRASCOPE_DOCS -->",
+		},
+		{
+			name:      "nothing generated",
+			document:  "<!-- BEGIN_TERRASCOPE_DOCS -->old<!-- END_TERRASCOPE_DOCS -->",
+			generated: "",
+			expected:  "<!-- BEGIN_TERRASCOPE_DOCS -->\n<!-- END_TERRASCOPE_DOCS -->",
+		},
+		{
+			name:        "no markers",
+			document:    "# module\n",
+			expectError: true,
+		},
+		{
+			name:        "no end marker",
+			document:    "<!-- BEGIN_TERRASCOPE_DOCS -->\n",
+			expectError: true,
+		},
+		{
+			name:        "markers out of order",
+			document:    "<!-- END_TERRASCOPE_DOCS -->\n<!-- BEGIN_TERRASCOPE_DOCS -->\n",
+			expectError: true,
+		},
+		{
+			name:        "two sections",
+			document:    "<!-- BEGIN_TERRASCOPE_DOCS --><!-- END_TERRASCOPE_DOCS --><!-- BEGIN_TERRASCOPE_DOCS --><!-- END_TERRASCOPE_DOCS -->",
+			expectError: true,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			actual, err := Inject(tc.document, tc.generated)
+			if tc.expectError {
+				if err == nil {
+					t.Errorf("Expected an error, got document %q", actual)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if actual != tc.expected {
+				t.Errorf("Expected document %q, got %q", tc.expected, actual)
+			}
+		})
+	}
+}
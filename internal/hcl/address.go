@@ -0,0 +1,129 @@
+package hcl
+
+import (
+	"strings"
+
+	"github.com/hashicorp/hcl/v2"
+)
+
+// These are the kinds of object that can be declared in a module (and so the
+// kinds of node in a module's graph).
+const (
+	kindVariable  = "variable"
+	kindLocal     = "local"
+	kindOutput    = "output"
+	kindModule    = "module"
+	kindData      = "data"
+	kindResource  = "resource"
+	kindProvider  = "provider"
+	kindTerraform = "terraform"
+)
+
+// addressPrefixes maps each kind of object to the prefix of its address.
+// Managed resources are the only objects whose addresses have no prefix.
+var addressPrefixes = map[string]string{
+	kindVariable:  "var",
+	kindLocal:     "local",
+	kindOutput:    "output",
+	kindModule:    "module",
+	kindData:      "data",
+	kindProvider:  "provider",
+	kindTerraform: "terraform",
+}
+
+// Address identifies an object in a module, such as a variable, a resource or
+// a provider configuration.
+type Address struct {
+	// Kind is the kind of object, e.g. "variable" or "resource".
+	Kind string
+	// Type is the type of a resource or data source, or the name of a
+	// provider. It is empty for other kinds of object.
+	Type string
+	// Name is the name of the object, or the alias of a provider
+	// configuration.
+	Name string
+	// Key is the index of a particular instance of the object, as it is
+	// written in an address (e.g. `["a"]` or `[0]`). It is empty for the
+	// object as a whole.
+	Key string
+}
+
+// ParseAddress reads the given string as an address, e.g. "var.qty",
+// "data.aws_ami.ubuntu", `random_string.this["a"]` or "provider.aws.west".
+// If the string can't be an address, the result has no Kind.
+func ParseAddress(s string) Address {
+	var addr Address
+	if i := strings.Index(s, indexLeft); i >= 0 {
+		addr.Key = s[i:]
+		s = s[:i]
+	}
+	parts := strings.Split(s, separator)
+
+	for kind, prefix := range addressPrefixes {
+		if parts[0] != prefix {
+			continue
+		}
+		addr.Kind = kind
+		switch {
+		case kind == kindData && len(parts) == 3:
+			addr.Type, addr.Name = parts[1], parts[2]
+		case kind == kindProvider && len(parts) == 2:
+			addr.Type = parts[1]
+		case kind == kindProvider && len(parts) == 3:
+			addr.Type, addr.Name = parts[1], parts[2]
+		case kind != kindData && kind != kindProvider && len(parts) == 2:
+			addr.Name = parts[1]
+		default:
+			return Address{}
+		}
+		return addr
+	}
+
+	if len(parts) != 2 {
+		return Address{}
+	}
+	addr.Kind = kindResource
+	addr.Type, addr.Name = parts[0], parts[1]
+	return addr
+}
+
+// blockAddress returns the address of the object declared by the given block.
+func blockAddress(block *hcl.Block) Address {
+	switch block.Type {
+	case "variable":
+		return Address{Kind: kindVariable, Name: block.Labels[0]}
+	case "output":
+		return Address{Kind: kindOutput, Name: block.Labels[0]}
+	case "module":
+		return Address{Kind: kindModule, Name: block.Labels[0]}
+	case "data":
+		return Address{Kind: kindData, Type: block.Labels[0], Name: block.Labels[1]}
+	case "resource":
+		return Address{Kind: kindResource, Type: block.Labels[0], Name: block.Labels[1]}
+	case "provider":
+		return providerAddress(block)
+	}
+	return Address{}
+}
+
+// String returns the receiver the way Terraform writes it.
+func (a Address) String() string {
+	parts := make([]string, 0, 4)
+	if a.Kind != kindResource {
+		parts = append(parts, addressPrefixes[a.Kind])
+	}
+	if len(a.Type) > 0 {
+		parts = append(parts, a.Type)
+	}
+	if len(a.Name) > 0 {
+		parts = append(parts, a.Name)
+	}
+	return strings.Join(parts, separator) + a.Key
+}
+
+// Base returns the address of the whole object that the receiver is an
+// instance of.
+func (a Address) Base() Address {
+	a.Key = ""
+	return a
+}
@@ -0,0 +1,375 @@
+package hcl
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/hclparse"
+	"github.com/hashicorp/hcl/v2/hclsyntax"
+	"github.com/hashicorp/hcl/v2/hclwrite"
+	"github.com/zclconf/go-cty/cty"
+)
+
+// DefaultBackendConfigPattern matches the backend configuration files in a
+// root, which are given to `terraform init -backend-config`.
+const DefaultBackendConfigPattern = "*.tfbackend"
+
+// backendStateArguments are the arguments of each type of backend that say
+// where it keeps a state. Two states of the same type with the same values of
+// these arguments are the same state.
+var backendStateArguments = map[string][]string{
+	"azurerm":    {"storage_account_name", "container_name", "key"},
+	"consul":     {"path"},
+	"gcs":        {"bucket", "prefix"},
+	"http":       {"address"},
+	"kubernetes": {"namespace", "secret_suffix"},
+	"local":      {"path"},
+	"pg":         {"conn_str", "schema_name"},
+	"s3":         {"bucket", "key"},
+}
+
+// backendStateDefaults are the values of backendStateArguments that a type of
+// backend uses when they aren't given.
+var backendStateDefaults = map[string]map[string]string{
+	"gcs":        {"prefix": ""},
+	"kubernetes": {"namespace": "default"},
+	"local":      {"path": "terraform.tfstate"},
+	"pg":         {"schema_name": "terraform_remote_state"},
+}
+
+// RootState is one of the states that a root keeps: its backend (for one of
+// its scope values, if the backend comes from the project), merged with one
+// of its backend configuration files, if it has any.
+type RootState struct {
+	Root *Root
+	// Scope is the scope value that the root is built for, if the backend
+	// comes from the project's backend template.
+	Scope *ScopeValue
+	// ConfigFile is the backend configuration file merged into the backend,
+	// if there is one.
+	ConfigFile string
+	// Dir is the directory terraform runs in for the state.
+	Dir string
+	// Type is the type of the backend, e.g. "s3".
+	Type string
+	// Arguments are the backend's arguments that are known before terraform
+	// runs.
+	Arguments map[string]cty.Value
+	// DefRange is where the backend is declared.
+	DefRange hcl.Range
+}
+
+func (s *RootState) String() string {
+	name := s.Root.Name
+	if s.Scope != nil && s.Scope.Depth() > 0 {
+		name += fmt.Sprintf(" (%s)", s.Scope.Address())
+	}
+	if len(s.ConfigFile) > 0 {
+		name += " with " + filepath.Base(s.ConfigFile)
+	}
+	return name
+}
+
+// Location returns where the receiver's state is kept, e.g.
+// `s3 bucket="acme-terraform-state" key="network/terraform.tfstate"`, and
+// whether it is known. It isn't known if the backend is missing any of the
+// arguments that say where, or if terrascope doesn't know which arguments
+// those are for its type. In the first case, the missing arguments are
+// returned.
+func (s *RootState) Location() (string, bool, []string) {
+	names, ok := backendStateArguments[s.Type]
+	if !ok {
+		return s.Type, false, nil
+	}
+	parts := []string{s.Type}
+	missing := make([]string, 0)
+	for _, name := range names {
+		value, ok := s.argument(name)
+		if !ok {
+			missing = append(missing, name)
+			continue
+		}
+		if s.Type == "local" {
+			// a local state is relative to where terraform runs
+			if !filepath.IsAbs(value) {
+				value = filepath.Join(s.Dir, value)
+			}
+			value = filepath.Clean(value)
+		}
+		parts = append(parts, fmt.Sprintf("%s=%q", name, value))
+	}
+	if len(missing) > 0 {
+		return s.Type, false, missing
+	}
+	return strings.Join(parts, " "), true, nil
+}
+
+// argument returns the string value of the receiver's argument with the given
+// name, or its default, and whether it has one.
+func (s *RootState) argument(name string) (string, bool) {
+	value, ok := s.Arguments[name]
+	if !ok || value.IsNull() || value.Type() != cty.String {
+		value, ok := backendStateDefaults[s.Type][name]
+		return value, ok
+	}
+	return value.AsString(), true
+}
+
+// RootStates returns the states of the given root (read into the given
+// module), for the values in the given scope data.
+//
+// If the receiver has a backend template, and the root has no backend or one
+// of the same type, the root gets the template's backend when it is built
+// (see BuildRoot), so it has a state for each of its scope values. Otherwise
+// it has a state for its own backend, if it has one. Either way, each state
+// is merged with each of the root's backend configuration files that match
+// the given pattern (e.g. "*.tfbackend"), if there are any.
+//
+// A root with no backend, or that uses HCP Terraform, has no states.
+func (p *Project) RootStates(root *Root, module Module, data *ScopeData, configPattern string) ([]*RootState, error) {
+	states := make([]*RootState, 0)
+	block := moduleBackend(module)
+	switch {
+	case block != nil && block.Type == "cloud":
+		return states, nil
+	case p.Backend != nil && (block == nil || block.Labels[0] == p.Backend.Type):
+		for _, value := range data.Match(len(root.Scopes), nil) {
+			args, diags := bodyArguments(p.Backend.Body, p.BackendContext(root, value))
+			if err := handleDiags(diags, p.parser.Files(), nil); err != nil {
+				return nil, fmt.Errorf("could not render the backend of %s: %w", root.Name, err)
+			}
+			states = append(states, &RootState{
+				Root:      root,
+				Scope:     value,
+				Dir:       p.BuildPath(root, value),
+				Type:      p.Backend.Type,
+				Arguments: args,
+				DefRange:  p.Backend.Body.SrcRange,
+			})
+		}
+	case block != nil:
+		args, diags := bodyArguments(block.Body, nil)
+		if err := handleDiags(diags, nil, nil); err != nil {
+			return nil, fmt.Errorf("could not read the backend of %s: %w", root.Name, err)
+		}
+		states = append(states, &RootState{
+			Root:      root,
+			Dir:       root.Dir,
+			Type:      block.Labels[0],
+			Arguments: args,
+			DefRange:  block.DefRange,
+		})
+	default:
+		return states, nil
+	}
+
+	configFiles, err := filepath.Glob(filepath.Join(root.Dir, configPattern))
+	if err != nil {
+		return nil, err
+	}
+	if len(configFiles) == 0 {
+		return states, nil
+	}
+	sort.Strings(configFiles)
+	configured := make([]*RootState, 0, len(states)*len(configFiles))
+	for _, filename := range configFiles {
+		config, err := readBackendConfig(filename)
+		if err != nil {
+			return nil, err
+		}
+		for _, state := range states {
+			merged := *state
+			merged.ConfigFile = filename
+			merged.Arguments = make(map[string]cty.Value, len(state.Arguments)+len(config))
+			for name, value := range state.Arguments {
+				merged.Arguments[name] = value
+			}
+			for name, value := range config {
+				merged.Arguments[name] = value
+			}
+			configured = append(configured, &merged)
+		}
+	}
+	return configured, nil
+}
+
+// readBackendConfig returns the arguments in the given backend configuration
+// file.
+func readBackendConfig(filename string) (map[string]cty.Value, error) {
+	parser := hclparse.NewParser()
+	f, diags := parser.ParseHCLFile(filename)
+	if err := handleDiags(diags, parser.Files(), nil); err != nil {
+		return nil, err
+	}
+	args, diags := bodyArguments(f.Body, nil)
+	if err := handleDiags(diags, parser.Files(), nil); err != nil {
+		return nil, err
+	}
+	return args, nil
+}
+
+// bodyArguments evaluates each attribute of the given body in the given
+// context. Nested blocks, and attributes whose values aren't known, are left
+// out.
+func bodyArguments(body hcl.Body, ctx *hcl.EvalContext) (map[string]cty.Value, hcl.Diagnostics) {
+	var attrs hcl.Attributes
+	var diags hcl.Diagnostics
+	if syntaxBody, ok := body.(*hclsyntax.Body); ok {
+		// JustAttributes fails on nested blocks, like the s3 backend's
+		// assume_role
+		attrs = make(hcl.Attributes, len(syntaxBody.Attributes))
+		for name, attr := range syntaxBody.Attributes {
+			attrs[name] = attr.AsHCLAttribute()
+		}
+	} else {
+		attrs, diags = body.JustAttributes()
+	}
+
+	args := make(map[string]cty.Value, len(attrs))
+	for name, attr := range attrs {
+		value, valueDiags := attr.Expr.Value(ctx)
+		diags = diags.Extend(valueDiags)
+		if valueDiags.HasErrors() || !value.IsWhollyKnown() {
+			continue
+		}
+		args[name] = value
+	}
+	return args, diags
+}
+
+// DuplicateStates returns the groups of the given states that are kept in the
+// same place, so would overwrite each other. Each group is in the order
+// given, and the groups are sorted by location. States whose locations aren't
+// known are left out.
+func DuplicateStates(states []*RootState) [][]*RootState {
+	byLocation := make(map[string][]*RootState)
+	for _, state := range states {
+		location, ok, _ := state.Location()
+		if !ok {
+			continue
+		}
+		byLocation[location] = append(byLocation[location], state)
+	}
+	duplicates := make([][]*RootState, 0)
+	for _, location := range sortedKeys(byLocation) {
+		if len(byLocation[location]) > 1 {
+			duplicates = append(duplicates, byLocation[location])
+		}
+	}
+	return duplicates
+}
+
+// GenerateRootBackend returns the given root's backend (read into the given
+// module), generated from the receiver's backend template: the name of the
+// file it belongs in, and that file's new source. A root with no backend gets
+// a new backend.tf, like the one ScaffoldRoot makes, or if it already has a
+// backend.tf, a backend block in that file's terraform block. A root with a backend of
+// the template's type gets its backend block replaced in the file that
+// declares it, which keeps the rest of its formatting. Roots with a backend
+// of another type, or that use HCP Terraform, are left alone, and get an
+// empty file name.
+func (p *Project) GenerateRootBackend(root *Root, module Module) (string, []byte, error) {
+	if p.Backend == nil {
+		return "", nil, fmt.Errorf("project %s has no backend to generate", p.Name)
+	}
+	block := moduleBackend(module)
+	if block == nil {
+		filename := filepath.Join(root.Dir, RootBackendFilename)
+		src, err := os.ReadFile(filename)
+		if os.IsNotExist(err) {
+			src, err = p.newRootBackend(root)
+		} else if err == nil {
+			src, err = p.addRootBackend(root, filename, src)
+		}
+		if err != nil {
+			return "", nil, err
+		}
+		return filename, src, nil
+	}
+	if block.Type == "cloud" || block.Labels[0] != p.Backend.Type {
+		return "", nil, nil
+	}
+
+	filename := block.DefRange.Filename
+	if !strings.HasSuffix(filename, ".tf") {
+		return "", nil, fmt.Errorf("%s: can't generate a backend in a JSON configuration file", block.DefRange)
+	}
+	src, err := os.ReadFile(filename)
+	if err != nil {
+		return "", nil, err
+	}
+	file, diags := hclwrite.ParseConfig(src, filename, hcl.InitialPos)
+	if diags.HasErrors() {
+		return "", nil, diags
+	}
+	var backend *hclwrite.Block
+	for _, terraform := range file.Body().Blocks() {
+		if terraform.Type() != "terraform" {
+			continue
+		}
+		if backend = terraform.Body().FirstMatchingBlock("backend", block.Labels); backend != nil {
+			break
+		}
+	}
+	if backend == nil {
+		return "", nil, fmt.Errorf("%s: could not find the backend block", block.DefRange)
+	}
+	// Body.Clear would keep the old attributes for SetAttributeValue to find
+	for name := range backend.Body().Attributes() {
+		backend.Body().RemoveAttribute(name)
+	}
+	for _, nested := range backend.Body().Blocks() {
+		backend.Body().RemoveBlock(nested)
+	}
+	diags = renderBody(p.Backend.Body, backend.Body(), p.unknownBackendContext(root))
+	if err := handleDiags(diags, p.parser.Files(), nil); err != nil {
+		return "", nil, fmt.Errorf("could not render the backend of %s: %w", root.Name, err)
+	}
+	return filename, file.Bytes(), nil
+}
+
+// newRootBackend returns the backend.tf of the given root, which has no
+// backend yet.
+func (p *Project) newRootBackend(root *Root) ([]byte, error) {
+	backend, err := p.renderBackend(root, p.unknownBackendContext(root))
+	if err != nil {
+		return nil, err
+	}
+	if len(root.Scopes) > 0 {
+		backend = append([]byte("# `terrascope root build` fills in the rest of the backend for each scope value.\n\n"), backend...)
+	}
+	return backend, nil
+}
+
+// addRootBackend returns the given source of the given root's backend.tf
+// (which has no backend yet), with a backend block added to its terraform
+// block, or to a new one. The rest of the file is kept.
+func (p *Project) addRootBackend(root *Root, filename string, src []byte) ([]byte, error) {
+	file, diags := hclwrite.ParseConfig(src, filename, hcl.InitialPos)
+	if diags.HasErrors() {
+		return nil, diags
+	}
+	var terraform *hclwrite.Block
+	for _, block := range file.Body().Blocks() {
+		if block.Type() == "terraform" {
+			terraform = block
+			break
+		}
+	}
+	if terraform == nil {
+		if len(file.Body().Attributes())+len(file.Body().Blocks()) > 0 {
+			file.Body().AppendNewline()
+		}
+		terraform = file.Body().AppendNewBlock("terraform", nil)
+	}
+	backend := terraform.Body().AppendNewBlock("backend", []string{p.Backend.Type})
+	diags = renderBody(p.Backend.Body, backend.Body(), p.unknownBackendContext(root))
+	if err := handleDiags(diags, p.parser.Files(), nil); err != nil {
+		return nil, fmt.Errorf("could not render the backend of %s: %w", root.Name, err)
+	}
+	return file.Bytes(), nil
+}
@@ -0,0 +1,284 @@
+package hcl
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/hclsyntax"
+	"github.com/sirupsen/logrus"
+)
+
+func TestRootStates(t *testing.T) {
+	project, err := LoadProject(acmeProject)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := project.LoadScopeData()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := map[string][]string{
+		"network": {
+			`network (acme.gold)	s3 bucket="acme-terraform-state" key="network/acme/gold/terraform.tfstate"`,
+			`network (acme.silver)	s3 bucket="acme-terraform-state" key="network/acme/silver/terraform.tfstate"`,
+		},
+		// its own backend is of another type, so it keeps it
+		"legacy": {
+			`legacy	local path="` + filepath.Join(filepath.Dir(project.Filename), "roots", "legacy", "legacy.tfstate") + `"`,
+		},
+	}
+	for name, expected := range tests {
+		t.Run(name, func(t *testing.T) {
+			root, err := project.FindRoot(name)
+			if err != nil {
+				t.Fatal(err)
+			}
+			module := NewModule(logrus.StandardLogger())
+			if err := module.ParseModuleDirectory(root.Dir); err != nil {
+				t.Fatal(err)
+			}
+			states, err := project.RootStates(root, module, data, DefaultBackendConfigPattern)
+			if err != nil {
+				t.Fatal(err)
+			}
+			actual := make([]string, len(states))
+			for i, state := range states {
+				location, ok, _ := state.Location()
+				if !ok {
+					t.Errorf("Expected the location of %s to be known", state)
+				}
+				actual[i] = state.String() + "\t" + location
+			}
+			if strings.Join(actual, "\n") != strings.Join(expected, "\n") {
+				t.Errorf("Expected\n%s\ngot\n%s", strings.Join(expected, "\n"), strings.Join(actual, "\n"))
+			}
+		})
+	}
+}
+
+func TestDuplicateStates(t *testing.T) {
+	src, err := NewProjectFile("acme", "roots", "data.hcl", []string{"org"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	dir := t.TempDir()
+	files := map[string]string{
+		ProjectFilename:                      string(src),
+		"data.hcl":                           `scope "org" "acme" {}`,
+		"roots/a/" + ProjectFilename:         `root "a" {}`,
+		"roots/a/main.tf":                    "terraform {\n  backend \"s3\" {\n    bucket = \"state\"\n    key    = \"shared\"\n  }\n}\n",
+		"roots/b/" + ProjectFilename:         `root "b" {}`,
+		"roots/b/main.tf":                    "terraform {\n  backend \"s3\" {\n    bucket = \"state\"\n  }\n}\n",
+		"roots/b/dev.tfbackend":              `key = "shared"`,
+		"roots/b/prod.tfbackend":             `key = "prod"`,
+		"roots/c/" + ProjectFilename:         `root "c" {}`,
+		"roots/c/main.tf":                    "terraform {\n  backend \"s3\" {\n    bucket = \"state\"\n  }\n}\n",
+		"roots/unchecked/" + ProjectFilename: `root "unchecked" {}`,
+		"roots/unchecked/main.tf":            "terraform {\n  backend \"remote\" {}\n}\n",
+		"roots/nobackend/" + ProjectFilename: `root "nobackend" {}`,
+		"roots/nobackend/main.tf":            "",
+		"roots/cloud/" + ProjectFilename:     `root "cloud" {}`,
+		"roots/cloud/main.tf":                "terraform {\n  cloud {}\n}\n",
+		"roots/local/" + ProjectFilename:     `root "local" {}`,
+		"roots/local/main.tf":                "terraform {\n  backend \"local\" {\n    path = \"../local-too/terraform.tfstate\"\n  }\n}\n",
+		"roots/local-too/" + ProjectFilename: `root "local-too" {}`,
+		"roots/local-too/main.tf":            "terraform {\n  backend \"local\" {}\n}\n",
+	}
+	for name, contents := range files {
+		filename := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(filename), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filename, []byte(contents), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	project, err := LoadProject(filepath.Join(dir, ProjectFilename))
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := project.LoadScopeData()
+	if err != nil {
+		t.Fatal(err)
+	}
+	roots, err := project.Roots()
+	if err != nil {
+		t.Fatal(err)
+	}
+	states := make([]*RootState, 0)
+	for _, root := range roots {
+		module := NewModule(logrus.StandardLogger())
+		if err := module.ParseModuleDirectory(root.Dir); err != nil {
+			t.Fatal(err)
+		}
+		rootStates, err := project.RootStates(root, module, data, DefaultBackendConfigPattern)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if (root.Name == "nobackend" || root.Name == "cloud") != (len(rootStates) == 0) {
+			t.Errorf("Expected %s to have states only if it has a backend, got %d", root.Name, len(rootStates))
+		}
+		states = append(states, rootStates...)
+	}
+
+	for _, state := range states {
+		_, ok, missing := state.Location()
+		switch state.Root.Name {
+		case "c":
+			if ok || strings.Join(missing, ",") != "key" {
+				t.Errorf("Expected %s to be missing its key, got %v", state, missing)
+			}
+		case "unchecked":
+			if ok || len(missing) > 0 {
+				t.Errorf("Expected the location of %s not to be known, got %v", state, missing)
+			}
+		}
+	}
+
+	duplicates := DuplicateStates(states)
+	actual := make([]string, len(duplicates))
+	for i, group := range duplicates {
+		names := make([]string, len(group))
+		for j, state := range group {
+			names[j] = state.String()
+		}
+		actual[i] = strings.Join(names, ", ")
+	}
+	expected := []string{"local, local-too", "a, b with dev.tfbackend"}
+	if strings.Join(actual, "\n") != strings.Join(expected, "\n") {
+		t.Errorf("Expected duplicates\n%s\ngot\n%s", strings.Join(expected, "\n"), strings.Join(actual, "\n"))
+	}
+}
+
+func TestGenerateRootBackend(t *testing.T) {
+	project, err := LoadProject(acmeProject)
+	if err != nil {
+		t.Fatal(err)
+	}
+	project.RootsDir = t.TempDir()
+	files := map[string]string{
+		"dns/" + ProjectFilename: `root "dns" { scopes = ["org"] }`,
+		"dns/main.tf": `# the state
+terraform {
+  required_version = ">= 1.5"
+
+  backend "s3" {
+    bucket = "old"
+  }
+}
+
+variable "org" {
+  type = string
+}
+`,
+		"new/" + ProjectFilename:   `root "new" { scopes = ["org"] }`,
+		"new/main.tf":              "",
+		"extra/" + ProjectFilename: `root "extra" { scopes = ["org"] }`,
+		"extra/" + RootBackendFilename: `terraform {
+  required_version = ">= 1.5"
+}
+
+provider "aws" {
+  region = "us-west-2"
+}
+`,
+	}
+	for name, contents := range files {
+		filename := filepath.Join(project.RootsDir, name)
+		if err := os.MkdirAll(filepath.Dir(filename), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filename, []byte(contents), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// the template is keyed on the root's directory, and its bucket on the
+	// org, which isn't known until the root is built
+	key, diags := hclsyntax.ParseExpression([]byte(`"${path}/terraform.tfstate"`), "test.hcl", hcl.InitialPos)
+	if diags.HasErrors() {
+		t.Fatal(diags)
+	}
+	project.Backend.Body.Attributes["key"].Expr = key
+	path, err := filepath.Rel(filepath.Dir(project.Filename), project.RootsDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	path = filepath.ToSlash(path)
+
+	tests := []struct {
+		root             string
+		expectedFilename string
+		expected         string
+	}{
+		{
+			root:             "dns",
+			expectedFilename: "main.tf",
+			expected: strings.Replace(files["dns/main.tf"], `    bucket = "old"
+`, `    key    = "`+path+`/dns/terraform.tfstate"
+    region = "us-west-2"
+`, 1),
+		},
+		{
+			root:             "new",
+			expectedFilename: RootBackendFilename,
+			expected: "# `terrascope root build` fills in the rest of the backend for each scope value.\n\n" + `terraform {
+  backend "s3" {
+    key    = "` + path + `/new/terraform.tfstate"
+    region = "us-west-2"
+  }
+}
+`,
+		},
+		{
+			// its backend.tf is kept, and gets the backend
+			root:             "extra",
+			expectedFilename: RootBackendFilename,
+			expected: strings.Replace(files["extra/"+RootBackendFilename], `  required_version = ">= 1.5"
+`, `  required_version = ">= 1.5"
+  backend "s3" {
+    key    = "`+path+`/extra/terraform.tfstate"
+    region = "us-west-2"
+  }
+`, 1),
+		},
+		{
+			root: "legacy",
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.root, func(t *testing.T) {
+			dir := filepath.Join(project.RootsDir, tc.root)
+			if tc.root == "legacy" {
+				dir = "../../fixtures/projects/acme/roots/legacy"
+			}
+			root, err := project.LoadRoot(dir)
+			if err != nil {
+				t.Fatal(err)
+			}
+			module := NewModule(logrus.StandardLogger())
+			if err := module.ParseModuleDirectory(root.Dir); err != nil {
+				t.Fatal(err)
+			}
+			filename, src, err := project.GenerateRootBackend(root, module)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(tc.expectedFilename) == 0 {
+				if len(filename) > 0 {
+					t.Errorf("Expected %s to keep its backend, got %s", tc.root, filename)
+				}
+				return
+			}
+			if expected := filepath.Join(root.Dir, tc.expectedFilename); filename != expected {
+				t.Errorf("Expected the backend in %s, got %s", expected, filename)
+			}
+			if string(src) != tc.expected {
+				t.Errorf("Expected\n%s\ngot\n%s", tc.expected, src)
+			}
+		})
+	}
+}
@@ -0,0 +1,366 @@
+package hcl
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/hclsyntax"
+	"github.com/hashicorp/hcl/v2/hclwrite"
+	"github.com/zclconf/go-cty/cty"
+)
+
+// BuildVariablesFilename is the name of the variables file that a built
+// root's scope values are written to.
+const BuildVariablesFilename = "terrascope.auto.tfvars"
+
+// BuildBackendFilename is the name of the file that a built root's backend
+// block is written to.
+const BuildBackendFilename = "terrascope_backend.tf"
+
+// BuildBackendOverrideFilename is the name of the file that a built root's
+// backend block is written to when the root has a backend of the same type,
+// which the override replaces.
+const BuildBackendOverrideFilename = "terrascope_backend_override.tf"
+
+// generatedHeader starts each file that terrascope generates.
+const generatedHeader = "# This file is generated by terrascope. Do not edit it.\n\n"
+
+// buildKeepNames are the names of the files that terraform makes in a root,
+// which are kept when it is built again.
+var buildKeepNames = []string{
+	".terraform",
+	".terraform.lock.hcl",
+	"terraform.tfstate",
+	"terraform.tfstate.backup",
+	"terraform.tfstate.d",
+}
+
+// BuildPath returns the directory that the given root is built into for the
+// given scope value, e.g. `.terrascope/network/acme/gold`.
+func (p *Project) BuildPath(root *Root, value *ScopeValue) string {
+	return filepath.Join(append([]string{p.BuildDir, root.Name}, value.Names()...)...)
+}
+
+// BuildRoot renders a copy of the given root (read into the given module)
+// for the given scope value, which must be of the root's innermost scope
+// type, and returns the directory it is in (see BuildPath).
+//
+// The copy has the root's files, except its terrascope.hcl. Local module
+// sources that point outside the root are rewritten to point to the same
+// place from the copy. Two files are added:
+//   - terrascope.auto.tfvars, which has a value for each of the root's
+//     variables that is named for one of its scope types, or for one of the
+//     scope value's attributes.
+//   - terrascope_backend.tf, which has the project's backend, if the
+//     project has one. If the root has a backend of the same type (like the
+//     partial one `root new` makes), the file is an override of it instead:
+//     terrascope_backend_override.tf. If the root has a backend of another
+//     type, or uses HCP Terraform, it keeps it.
+//
+// The files that terraform makes when it runs in the copy (like the
+// .terraform directory) are kept when the root is built again.
+func (p *Project) BuildRoot(root *Root, module Module, value *ScopeValue) (string, error) {
+	if value.Depth() != len(root.Scopes) {
+		return "", fmt.Errorf("root %s is built for %s values, not for %q", root.Name, p.rootScopeTypeName(root), value.Address())
+	}
+	dir := p.BuildPath(root, value)
+	if err := cleanBuildDirectory(dir); err != nil {
+		return "", err
+	}
+	if err := p.copyRoot(root, dir); err != nil {
+		return "", err
+	}
+
+	if variables := buildVariables(module, value); variables != nil {
+		if err := os.WriteFile(filepath.Join(dir, BuildVariablesFilename), variables, 0644); err != nil {
+			return "", err
+		}
+	}
+
+	if p.Backend == nil {
+		return dir, nil
+	}
+	filename := BuildBackendFilename
+	switch ModuleBackendType(module) {
+	case "":
+	case p.Backend.Type:
+		filename = BuildBackendOverrideFilename
+	default:
+		return dir, nil
+	}
+	backend, err := p.renderBackend(root, p.BackendContext(root, value))
+	if err != nil {
+		return "", err
+	}
+	if err := os.WriteFile(filepath.Join(dir, filename), append([]byte(generatedHeader), backend...), 0644); err != nil {
+		return "", err
+	}
+	return dir, nil
+}
+
+// rootScopeTypeName returns the name of the given root's innermost scope
+// type, for messages.
+func (p *Project) rootScopeTypeName(root *Root) string {
+	if len(root.Scopes) == 0 {
+		return "no scope"
+	}
+	return root.Scopes[len(root.Scopes)-1]
+}
+
+// cleanBuildDirectory makes the given directory, or removes everything in it
+// except the files terraform made there.
+func cleanBuildDirectory(dir string) error {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return err
+	}
+	for _, entry := range entries {
+		if contains(buildKeepNames, entry.Name()) {
+			continue
+		}
+		if err := os.RemoveAll(filepath.Join(dir, entry.Name())); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// copyRoot copies the given root's files into the given directory.
+func (p *Project) copyRoot(root *Root, dir string) error {
+	return filepath.WalkDir(root.Dir, func(fullpath string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if contains(buildKeepNames, entry.Name()) || fullpath == p.BuildDir {
+			if entry.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		rel, err := filepath.Rel(root.Dir, fullpath)
+		if err != nil {
+			return err
+		}
+		if rel == ProjectFilename {
+			return nil
+		}
+		target := filepath.Join(dir, rel)
+		info, err := entry.Info()
+		if err != nil {
+			return err
+		}
+		if entry.IsDir() {
+			return os.MkdirAll(target, info.Mode().Perm())
+		}
+
+		src, err := os.ReadFile(fullpath)
+		if err != nil {
+			return err
+		}
+		if strings.HasSuffix(entry.Name(), ".tf") {
+			src, err = rewriteModuleSources(src, fullpath, root.Dir, filepath.Dir(target))
+			if err != nil {
+				return err
+			}
+		}
+		return os.WriteFile(target, src, info.Mode().Perm())
+	})
+}
+
+// rewriteModuleSources returns the given configuration file source with each
+// local module source that points outside of rootDir changed to point to the
+// same directory from targetDir. The rest of the file keeps its formatting.
+func rewriteModuleSources(src []byte, filename, rootDir, targetDir string) ([]byte, error) {
+	file, diags := hclwrite.ParseConfig(src, filename, hcl.InitialPos)
+	if diags.HasErrors() {
+		return nil, diags
+	}
+	changed := false
+	for _, block := range file.Body().Blocks() {
+		if block.Type() != kindModule {
+			continue
+		}
+		attr := block.Body().GetAttribute("source")
+		if attr == nil {
+			continue
+		}
+		source, ok := literalString(attr)
+		if !ok || !(strings.HasPrefix(source, "./") || strings.HasPrefix(source, "../")) {
+			continue
+		}
+		moduleDir := filepath.Join(filepath.Dir(filename), source)
+		if rel, err := filepath.Rel(rootDir, moduleDir); err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+			continue
+		}
+		rel, err := filepath.Rel(targetDir, moduleDir)
+		if err != nil {
+			return nil, err
+		}
+		rel = filepath.ToSlash(rel)
+		if !strings.HasPrefix(rel, "../") {
+			rel = "./" + rel
+		}
+		block.Body().SetAttributeValue("source", cty.StringVal(rel))
+		changed = true
+	}
+	if !changed {
+		return src, nil
+	}
+	return file.Bytes(), nil
+}
+
+// buildVariables returns the variables file for the given root module and
+// scope value, or nil if the module has no variables for it.
+func buildVariables(module Module, value *ScopeValue) []byte {
+	values := value.AllAttributes()
+	for _, scopeValue := range value.Path() {
+		values[scopeValue.Type] = cty.StringVal(scopeValue.Name)
+	}
+
+	file := hclwrite.NewEmptyFile()
+	empty := true
+	for _, name := range sortedKeys(values) {
+		if _, ok := module.Module().Variables[name]; !ok {
+			continue
+		}
+		file.Body().SetAttributeValue(name, values[name])
+		empty = false
+	}
+	if empty {
+		return nil
+	}
+	return append([]byte(generatedHeader), file.Bytes()...)
+}
+
+// ModuleBackendType returns the type of the given module's backend, "cloud"
+// if it uses HCP Terraform, or an empty string if it has neither.
+func ModuleBackendType(module Module) string {
+	block := moduleBackend(module)
+	if block == nil {
+		return ""
+	}
+	if block.Type == "cloud" {
+		return block.Type
+	}
+	return block.Labels[0]
+}
+
+// moduleBackend returns the given module's backend block, or its cloud block
+// if it uses HCP Terraform, or nil if it has neither.
+func moduleBackend(module Module) *hcl.Block {
+	schema := &hcl.BodySchema{
+		Blocks: []hcl.BlockHeaderSchema{
+			{Type: "backend", LabelNames: []string{"type"}},
+			{Type: "cloud"},
+		},
+	}
+	for _, block := range module.Configuration().terraform {
+		content, _, _ := block.Body.PartialContent(schema)
+		if len(content.Blocks) > 0 {
+			return content.Blocks[0]
+		}
+	}
+	return nil
+}
+
+// BackendContext returns the context that the receiver's backend template is
+// evaluated in for the given root and scope value. It has the variables:
+//   - root: the root's name.
+//   - scope: an object with the name of the value of each of the root's scope
+//     types, e.g. `scope.platform`.
+//   - scope_path: the names of the scope value's path, joined by slashes,
+//     e.g. "acme/gold".
+//   - path: the root's directory, relative to the receiver's and joined by
+//     slashes, e.g. "roots/network".
+func (p *Project) BackendContext(root *Root, value *ScopeValue) *hcl.EvalContext {
+	return &hcl.EvalContext{
+		Variables: map[string]cty.Value{
+			"root":       cty.StringVal(root.Name),
+			"path":       cty.StringVal(p.rootPath(root)),
+			"scope":      value.Values(),
+			"scope_path": cty.StringVal(strings.Join(value.Names(), "/")),
+		},
+		Functions: functions(),
+	}
+}
+
+// unknownBackendContext returns the context that the receiver's backend
+// template is evaluated in for the given root before it is built, where the
+// root's scope values are unknown.
+func (p *Project) unknownBackendContext(root *Root) *hcl.EvalContext {
+	scope := make(map[string]cty.Value)
+	for _, name := range root.Scopes {
+		scope[name] = cty.UnknownVal(cty.String)
+	}
+	scopePath := cty.StringVal("")
+	if len(root.Scopes) > 0 {
+		scopePath = cty.UnknownVal(cty.String)
+	}
+	return &hcl.EvalContext{
+		Variables: map[string]cty.Value{
+			"root":       cty.StringVal(root.Name),
+			"path":       cty.StringVal(p.rootPath(root)),
+			"scope":      cty.ObjectVal(scope),
+			"scope_path": scopePath,
+		},
+		Functions: functions(),
+	}
+}
+
+// rootPath returns the given root's directory, relative to the receiver's
+// and joined by slashes.
+func (p *Project) rootPath(root *Root) string {
+	rel, err := filepath.Rel(filepath.Dir(p.Filename), root.Dir)
+	if err != nil {
+		return filepath.ToSlash(root.Dir)
+	}
+	return filepath.ToSlash(rel)
+}
+
+// renderBackend returns a configuration file with the receiver's backend
+// block, evaluated for the given root in the given context.
+func (p *Project) renderBackend(root *Root, ctx *hcl.EvalContext) ([]byte, error) {
+	file := hclwrite.NewEmptyFile()
+	backend := file.Body().AppendNewBlock("terraform", nil).Body().AppendNewBlock("backend", []string{p.Backend.Type})
+	diags := renderBody(p.Backend.Body, backend.Body(), ctx)
+	if err := handleDiags(diags, p.parser.Files(), nil); err != nil {
+		return nil, fmt.Errorf("could not render the backend of %s: %w", root.Name, err)
+	}
+	return hclwrite.Format(file.Bytes()), nil
+}
+
+// renderBody evaluates each attribute of the given body in the given
+// context, and writes its value to the given body, along with each nested
+// block. Attributes are written in the order they are declared. Attributes
+// whose values aren't known are left out.
+func renderBody(src *hclsyntax.Body, dst *hclwrite.Body, ctx *hcl.EvalContext) hcl.Diagnostics {
+	var diags hcl.Diagnostics
+	attrs := make([]*hclsyntax.Attribute, 0, len(src.Attributes))
+	for _, attr := range src.Attributes {
+		attrs = append(attrs, attr)
+	}
+	sort.Slice(attrs, func(i, j int) bool {
+		return attrs[i].SrcRange.Start.Byte < attrs[j].SrcRange.Start.Byte
+	})
+	for _, attr := range attrs {
+		value, valueDiags := attr.Expr.Value(ctx)
+		diags = diags.Extend(valueDiags)
+		if valueDiags.HasErrors() || !value.IsWhollyKnown() {
+			continue
+		}
+		dst.SetAttributeValue(attr.Name, value)
+	}
+	for _, block := range src.Blocks {
+		nested := dst.AppendNewBlock(block.Type, block.Labels)
+		diags = diags.Extend(renderBody(block.Body, nested.Body(), ctx))
+	}
+	return diags
+}
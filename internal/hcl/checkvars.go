@@ -0,0 +1,140 @@
+package hcl
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/zclconf/go-cty/cty"
+	"github.com/zclconf/go-cty/cty/convert"
+)
+
+// CheckVariables checks the given values against the receiver's variables,
+// and returns a finding for each:
+//   - value given for a variable that isn't declared.
+//   - required variable that isn't given a value.
+//   - value that doesn't match its variable's type.
+//   - validation rule that a variable's value breaks.
+//
+// Validation rules are evaluated with the static evaluator, so rules that
+// depend on something unknown (like a resource attribute) are skipped.
+func (m *module) CheckVariables(inputs InputValues) ([]*Finding, error) {
+	findings := make([]*Finding, 0)
+
+	names := make([]string, 0, len(inputs))
+	for name := range inputs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		addr := Address{Kind: kindVariable, Name: name}
+		if _, ok := m.cfg.blocks[addr]; !ok {
+			findings = append(findings, &Finding{
+				Address: addr.String(),
+				Range:   inputs[name].Range,
+				Message: fmt.Sprintf("a value is given for %s, which is not declared", addr),
+			})
+		}
+	}
+
+	evaluator, _ := m.newEvaluator(inputs)
+	for addr, block := range m.cfg.blocks {
+		if addr.Kind != kindVariable {
+			continue
+		}
+		input := inputs[addr.Name]
+		content, _, _ := block.Body.PartialContent(variableSchema)
+		if _, hasDefault := content.Attributes["default"]; input == nil && !hasDefault {
+			findings = append(findings, &Finding{
+				Address: addr.String(),
+				Range:   block.DefRange,
+				Message: fmt.Sprintf("%s is required, but no value is given", addr),
+			})
+			continue
+		}
+
+		_, diags := m.variableValue(block, input)
+		for _, diag := range diags {
+			if diag.Severity != hcl.DiagError {
+				continue
+			}
+			rng := block.DefRange
+			if diag.Subject != nil {
+				rng = *diag.Subject
+			}
+			message := diag.Detail
+			if len(message) == 0 {
+				message = diag.Summary
+			}
+			findings = append(findings, &Finding{
+				Address: addr.String(),
+				Range:   rng,
+				Message: message,
+			})
+		}
+		if diags.HasErrors() {
+			continue
+		}
+
+		rng := block.DefRange
+		if input != nil {
+			rng = input.Range
+		}
+		for _, message := range m.brokenValidations(block, evaluator) {
+			findings = append(findings, &Finding{
+				Address: addr.String(),
+				Range:   rng,
+				Message: fmt.Sprintf("%s is invalid: %s", addr, message),
+			})
+		}
+	}
+
+	sort.SliceStable(findings, func(i, j int) bool {
+		if findings[i].Range.Filename != findings[j].Range.Filename {
+			return findings[i].Range.Filename < findings[j].Range.Filename
+		}
+		if findings[i].Range.Start.Line != findings[j].Range.Start.Line {
+			return findings[i].Range.Start.Line < findings[j].Range.Start.Line
+		}
+		return findings[i].Address < findings[j].Address
+	})
+	return findings, nil
+}
+
+// brokenValidations returns the error message of each of the given variable
+// block's validation rules whose condition is false.
+func (m *module) brokenValidations(block *hcl.Block, evaluator *Evaluator) []string {
+	messages := make([]string, 0)
+	content, _, _ := block.Body.PartialContent(validationSchema)
+	for _, nested := range content.Blocks {
+		attrs, _ := nested.Body.JustAttributes()
+		condition, ok := attrs["condition"]
+		if !ok {
+			continue
+		}
+		value, diags := condition.Expr.Value(evaluator.ctx)
+		if diags.HasErrors() {
+			m.Debugf("could not evaluate the validation of var.%s: %s", block.Labels[0], diags)
+			continue
+		}
+		value, err := convert.Convert(value, cty.Bool)
+		if err != nil || !value.IsKnown() || value.IsNull() {
+			m.Debugf("could not evaluate the validation of var.%s statically", block.Labels[0])
+			continue
+		}
+		if value.True() {
+			continue
+		}
+
+		message := "the validation condition is false"
+		if attr, ok := attrs["error_message"]; ok {
+			message = m.source(attr.Expr.Range())
+			errorMessage, diags := attr.Expr.Value(evaluator.ctx)
+			if !diags.HasErrors() && errorMessage.IsKnown() && !errorMessage.IsNull() && errorMessage.Type() == cty.String {
+				message = errorMessage.AsString()
+			}
+		}
+		messages = append(messages, message)
+	}
+	return messages
+}
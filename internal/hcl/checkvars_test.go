@@ -0,0 +1,79 @@
+package hcl
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+)
+
+func TestCheckVariables(t *testing.T) {
+	type test struct {
+		name     string
+		files    []string
+		flags    []string
+		expected []string
+	}
+	tests := []test{
+		{
+			name:  "valid",
+			files: []string{"prod.tfvars"},
+		},
+		{
+			name:     "missing",
+			expected: []string{"main.tf:1: var.env is required, but no value is given"},
+		},
+		{
+			name:  "broken",
+			files: []string{"terraform.tfvars", "broken.tfvars"},
+			flags: []string{"extra=1"},
+			expected: []string{
+				"broken.tfvars:1: The value for var.zones is not compatible with its type list(string): list of string required, but have string.",
+				"broken.tfvars:2: var.instance_count is invalid: instance_count must be between 1 and 5.",
+				"broken.tfvars:3: a value is given for var.region, which is not declared",
+				"<command line>: a value is given for var.extra, which is not declared",
+			},
+		},
+		{
+			name:     "json",
+			files:    []string{"dev.tfvars.json"},
+			expected: []string{"dev.tfvars.json:3: The value for var.tags is not compatible with its type object({ team  = string, owner = optional(string, \"platform\") }): attribute \"team\" is required."},
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			parser := NewModule(logrus.StandardLogger())
+			if err := parser.ParseModuleDirectory("../../fixtures/roots/evaluated"); err != nil {
+				t.Fatal(err)
+			}
+			inputs := make(InputValues)
+			for _, file := range tc.files {
+				values, err := parser.ParseVariableFile("../../fixtures/roots/evaluated/" + file)
+				if err != nil {
+					t.Fatal(err)
+				}
+				inputs.Merge(values)
+			}
+			for _, flag := range tc.flags {
+				values, err := ParseVariableFlag(flag)
+				if err != nil {
+					t.Fatal(err)
+				}
+				inputs.Merge(values)
+			}
+
+			findings, err := parser.CheckVariables(inputs)
+			if err != nil {
+				t.Fatal(err)
+			}
+			actual := make([]string, len(findings))
+			for i, f := range findings {
+				f.Range.Filename = strings.TrimPrefix(f.Range.Filename, "../../fixtures/roots/evaluated/")
+				actual[i] = f.String()
+			}
+			if strings.Join(actual, "\n") != strings.Join(tc.expected, "\n") {
+				t.Errorf("Expected findings:\n%s\nActual findings:\n%s", strings.Join(tc.expected, "\n"), strings.Join(actual, "\n"))
+			}
+		})
+	}
+}
@@ -0,0 +1,143 @@
+package hcl
+
+import "sort"
+
+// Cycle is a set of nodes in a graph that all depend on each other, directly
+// or transitively.
+type Cycle struct {
+	// Nodes are all the nodes in the cycle, sorted.
+	Nodes []string
+	// Edges are one chain of dependencies around the cycle, starting and
+	// ending at its first node.
+	Edges []*CycleEdge
+}
+
+// CycleEdge is a single dependency in a cycle.
+type CycleEdge struct {
+	// From is the node that depends on To.
+	From string
+	To   string
+	// References are the places in From's configuration that refer to To.
+	References []*Reference
+}
+
+// Cycles returns the cycles in the receiver. Each cycle is a strongly
+// connected component of the graph, found with Tarjan's algorithm.
+func (g *Graph) Cycles() []*Cycle {
+	t := &tarjan{
+		graph:   g,
+		index:   make(map[string]int),
+		lowlink: make(map[string]int),
+		onStack: make(map[string]bool),
+	}
+	for _, name := range g.Nodes() {
+		if _, ok := t.index[name]; !ok {
+			t.strongConnect(name)
+		}
+	}
+
+	cycles := make([]*Cycle, 0, len(t.components))
+	for _, component := range t.components {
+		if len(component) < 2 && !contains(g.Dependencies(component[0]), component[0]) {
+			continue
+		}
+		sort.Strings(component)
+		cycles = append(cycles, &Cycle{
+			Nodes: component,
+			Edges: g.cycleEdges(component),
+		})
+	}
+	sort.Slice(cycles, func(i, j int) bool {
+		return cycles[i].Nodes[0] < cycles[j].Nodes[0]
+	})
+	return cycles
+}
+
+// cycleEdges returns the shortest chain of dependencies from the first node
+// of the given component back to itself, without leaving the component.
+func (g *Graph) cycleEdges(component []string) []*CycleEdge {
+	start := component[0]
+	previous := make(map[string]string)
+	queue := []string{start}
+	found := false
+	for len(queue) > 0 && !found {
+		next := queue[0]
+		queue = queue[1:]
+		deps := append([]string{}, g.Dependencies(next)...)
+		sort.Strings(deps)
+		for _, dep := range deps {
+			if !contains(component, dep) {
+				continue
+			}
+			if _, ok := previous[dep]; ok {
+				continue
+			}
+			previous[dep] = next
+			if dep == start {
+				found = true
+				break
+			}
+			queue = append(queue, dep)
+		}
+	}
+
+	edges := make([]*CycleEdge, 0)
+	to := start
+	for {
+		from := previous[to]
+		edges = append([]*CycleEdge{{
+			From:       from,
+			To:         to,
+			References: g.References(from, to),
+		}}, edges...)
+		to = from
+		if to == start {
+			break
+		}
+	}
+	return edges
+}
+
+// tarjan holds the state of one run of Tarjan's strongly connected
+// components algorithm.
+type tarjan struct {
+	graph      *Graph
+	counter    int
+	index      map[string]int
+	lowlink    map[string]int
+	stack      []string
+	onStack    map[string]bool
+	components [][]string
+}
+
+func (t *tarjan) strongConnect(name string) {
+	t.index[name] = t.counter
+	t.lowlink[name] = t.counter
+	t.counter++
+	t.stack = append(t.stack, name)
+	t.onStack[name] = true
+
+	for _, dep := range t.graph.Dependencies(name) {
+		if _, ok := t.index[dep]; !ok {
+			t.strongConnect(dep)
+			t.lowlink[name] = min(t.lowlink[name], t.lowlink[dep])
+		} else if t.onStack[dep] {
+			t.lowlink[name] = min(t.lowlink[name], t.index[dep])
+		}
+	}
+
+	if t.lowlink[name] != t.index[name] {
+		return
+	}
+	component := make([]string, 0)
+	for {
+		top := t.stack[len(t.stack)-1]
+		t.stack = t.stack[:len(t.stack)-1]
+		t.onStack[top] = false
+		component = append(component, top)
+		if top == name {
+			break
+		}
+	}
+	t.components = append(t.components, component)
+}
@@ -0,0 +1,373 @@
+package hcl
+
+import (
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/gohcl"
+	"github.com/hashicorp/terraform-config-inspect/tfconfig"
+)
+
+// ModuleDescription summarizes the interface of a module: what it takes,
+// what it gives back, and what it needs to run.
+type ModuleDescription struct {
+	Path              string                 `json:"path"`
+	RequiredCore      []string               `json:"required_core,omitempty"`
+	Variables         []*VariableDescription `json:"variables"`
+	Outputs           []*OutputDescription   `json:"outputs"`
+	RequiredProviders []*ProviderDescription `json:"required_providers"`
+	ModuleCalls       []*tfconfig.ModuleCall `json:"module_calls"`
+	// ManagedResources is the number of managed resources of each type.
+	ManagedResources map[string]int `json:"managed_resources"`
+	// DataResources is the number of data sources of each type.
+	DataResources map[string]int `json:"data_resources"`
+}
+
+// VariableDescription describes one of a module's input variables.
+type VariableDescription struct {
+	*tfconfig.Variable
+	Validations []*Validation `json:"validations,omitempty"`
+}
+
+// Validation is a custom validation rule of a variable.
+type Validation struct {
+	// Condition is the source of the rule's condition expression.
+	Condition    string `json:"condition"`
+	ErrorMessage string `json:"error_message"`
+}
+
+// OutputDescription describes one of a module's outputs.
+type OutputDescription struct {
+	*tfconfig.Output
+	// Value is the source of the output's value expression.
+	Value string `json:"value"`
+	// DerivedFrom are the addresses of the resources, data sources and module
+	// calls that the output's value depends on, directly or transitively.
+	DerivedFrom []string `json:"derived_from"`
+}
+
+// ProviderDescription describes one of a module's required providers.
+type ProviderDescription struct {
+	Name string `json:"name"`
+	*tfconfig.ProviderRequirement
+}
+
+// derivedKinds are the kinds of node an output can be derived from.
+var derivedKinds = []string{kindResource, kindData, kindModule}
+
+// validationSchema picks out the validation blocks of a variable
+var validationSchema = &hcl.BodySchema{
+	Blocks: []hcl.BlockHeaderSchema{
+		{Type: "validation"},
+	},
+}
+
+// Describe returns a description of the receiver's interface. Everything in
+// the description is sorted by name.
+func (m *module) Describe() (*ModuleDescription, error) {
+	if m.module == nil {
+		return nil, fmt.Errorf("no module directory has been parsed")
+	}
+	graph, err := m.Graph()
+	if err != nil {
+		return nil, err
+	}
+
+	desc := &ModuleDescription{
+		Path:              m.module.Path,
+		RequiredCore:      m.module.RequiredCore,
+		Variables:         make([]*VariableDescription, 0, len(m.module.Variables)),
+		Outputs:           make([]*OutputDescription, 0, len(m.module.Outputs)),
+		RequiredProviders: make([]*ProviderDescription, 0, len(m.module.RequiredProviders)),
+		ModuleCalls:       make([]*tfconfig.ModuleCall, 0, len(m.module.ModuleCalls)),
+		ManagedResources:  make(map[string]int),
+		DataResources:     make(map[string]int),
+	}
+
+	for _, v := range m.module.Variables {
+		addr := Address{Kind: kindVariable, Name: v.Name}
+		desc.Variables = append(desc.Variables, &VariableDescription{
+			Variable:    v,
+			Validations: m.validations(m.cfg.blocks[addr]),
+		})
+	}
+	sort.Slice(desc.Variables, func(i, j int) bool { return desc.Variables[i].Name < desc.Variables[j].Name })
+
+	for _, o := range m.module.Outputs {
+		name := Address{Kind: kindOutput, Name: o.Name}.String()
+		derivedFrom := make([]string, 0)
+		if graph.Has(name) {
+			for dep := range graph.walk(name, -1, graph.Dependencies) {
+				if contains(derivedKinds, nodeKind(dep)) {
+					derivedFrom = append(derivedFrom, dep)
+				}
+			}
+		}
+		sort.Strings(derivedFrom)
+		desc.Outputs = append(desc.Outputs, &OutputDescription{
+			Output:      o,
+			Value:       m.outputValue(ParseAddress(name)),
+			DerivedFrom: derivedFrom,
+		})
+	}
+	sort.Slice(desc.Outputs, func(i, j int) bool { return desc.Outputs[i].Name < desc.Outputs[j].Name })
+
+	for name, req := range m.module.RequiredProviders {
+		desc.RequiredProviders = append(desc.RequiredProviders, &ProviderDescription{Name: name, ProviderRequirement: req})
+	}
+	sort.Slice(desc.RequiredProviders, func(i, j int) bool { return desc.RequiredProviders[i].Name < desc.RequiredProviders[j].Name })
+
+	for _, call := range m.module.ModuleCalls {
+		desc.ModuleCalls = append(desc.ModuleCalls, call)
+	}
+	sort.Slice(desc.ModuleCalls, func(i, j int) bool { return desc.ModuleCalls[i].Name < desc.ModuleCalls[j].Name })
+
+	for _, r := range m.module.ManagedResources {
+		desc.ManagedResources[r.Type]++
+	}
+	for _, r := range m.module.DataResources {
+		desc.DataResources[r.Type]++
+	}
+
+	return desc, nil
+}
+
+// validations returns the validation rules of the given variable block.
+func (m *module) validations(block *hcl.Block) []*Validation {
+	if block == nil {
+		return nil
+	}
+	content, _, _ := block.Body.PartialContent(validationSchema)
+	validations := make([]*Validation, 0, len(content.Blocks))
+	for _, nested := range content.Blocks {
+		attrs, _ := nested.Body.JustAttributes()
+		validation := &Validation{}
+		if condition, ok := attrs["condition"]; ok {
+			validation.Condition = m.source(condition.Expr.Range())
+		}
+		if message, ok := attrs["error_message"]; ok {
+			if diags := gohcl.DecodeExpression(message.Expr, nil, &validation.ErrorMessage); diags.HasErrors() {
+				validation.ErrorMessage = m.source(message.Expr.Range())
+			}
+		}
+		validations = append(validations, validation)
+	}
+	return validations
+}
+
+// outputValueSchema picks out the value of an output
+var outputValueSchema = &hcl.BodySchema{
+	Attributes: []hcl.AttributeSchema{
+		{Name: "value"},
+	},
+}
+
+// outputValue returns the source of the value expression of the given output.
+func (m *module) outputValue(addr Address) string {
+	block, ok := m.cfg.blocks[addr]
+	if !ok {
+		return ""
+	}
+	content, _, _ := block.Body.PartialContent(outputValueSchema)
+	if attr, ok := content.Attributes["value"]; ok {
+		return m.source(attr.Expr.Range())
+	}
+	return ""
+}
+
+// source returns the source code at the given range.
+func (m *module) source(rng hcl.Range) string {
+	file, ok := m.fundamental.Files()[rng.Filename]
+	if !ok {
+		return ""
+	}
+	return string(rng.SliceBytes(file.Bytes))
+}
+
+// Markdown returns a Markdown document describing the receiver, with a
+// section (and a table) for each part of the module's interface. Sections
+// with nothing in them are left out.
+func (d *ModuleDescription) Markdown() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "# %s\n", filepath.Base(d.Path))
+	d.writeRequirements(&b)
+	d.writeProviders(&b)
+	d.writeModules(&b)
+	d.writeResources(&b)
+	d.writeInputs(&b)
+	d.writeOutputs(&b)
+	return b.String()
+}
+
+// ReadmeMarkdown returns the sections of a module's README that people need
+// to use it: its providers, inputs and outputs. Sections with nothing in them
+// are left out.
+func (d *ModuleDescription) ReadmeMarkdown() string {
+	var b strings.Builder
+	d.writeProviders(&b)
+	d.writeInputs(&b)
+	d.writeOutputs(&b)
+	return strings.TrimPrefix(b.String(), "\n")
+}
+
+func (d *ModuleDescription) writeRequirements(b *strings.Builder) {
+	if len(d.RequiredCore) == 0 {
+		return
+	}
+	fmt.Fprintf(b, "\n## Requirements\n\n")
+	fmt.Fprintf(b, "Terraform %s\n", markdownCode(strings.Join(d.RequiredCore, ", ")))
+}
+
+func (d *ModuleDescription) writeProviders(b *strings.Builder) {
+	if len(d.RequiredProviders) == 0 {
+		return
+	}
+	rows := make([][]string, 0, len(d.RequiredProviders))
+	for _, p := range d.RequiredProviders {
+		rows = append(rows, []string{p.Name, p.Source, strings.Join(p.VersionConstraints, ", ")})
+	}
+	writeMarkdownSection(b, "Providers", []string{"Name", "Source", "Version"}, rows)
+}
+
+func (d *ModuleDescription) writeModules(b *strings.Builder) {
+	if len(d.ModuleCalls) == 0 {
+		return
+	}
+	rows := make([][]string, 0, len(d.ModuleCalls))
+	for _, call := range d.ModuleCalls {
+		rows = append(rows, []string{call.Name, call.Source, call.Version})
+	}
+	writeMarkdownSection(b, "Modules", []string{"Name", "Source", "Version"}, rows)
+}
+
+func (d *ModuleDescription) writeResources(b *strings.Builder) {
+	if len(d.ManagedResources)+len(d.DataResources) == 0 {
+		return
+	}
+	types := make([]string, 0, len(d.ManagedResources)+len(d.DataResources))
+	for t := range d.ManagedResources {
+		types = append(types, t)
+	}
+	for t := range d.DataResources {
+		if !contains(types, t) {
+			types = append(types, t)
+		}
+	}
+	sort.Strings(types)
+	rows := make([][]string, 0, len(types))
+	for _, t := range types {
+		rows = append(rows, []string{t, fmt.Sprint(d.ManagedResources[t]), fmt.Sprint(d.DataResources[t])})
+	}
+	writeMarkdownSection(b, "Resources", []string{"Type", "Managed", "Data"}, rows)
+}
+
+func (d *ModuleDescription) writeInputs(b *strings.Builder) {
+	if len(d.Variables) == 0 {
+		return
+	}
+	rows := make([][]string, 0, len(d.Variables))
+	for _, v := range d.Variables {
+		defaultValue := "n/a"
+		if !v.Required {
+			defaultValue = markdownCode(jsonString(v.Default))
+		}
+		messages := make([]string, 0, len(v.Validations))
+		for _, validation := range v.Validations {
+			messages = append(messages, validation.ErrorMessage)
+		}
+		rows = append(rows, []string{
+			v.Name,
+			v.Description,
+			markdownCode(compactType(v.Type)),
+			defaultValue,
+			yesNo(v.Required),
+			yesNo(v.Sensitive),
+			strings.Join(messages, "\n"),
+		})
+	}
+	writeMarkdownSection(b, "Inputs", []string{"Name", "Description", "Type", "Default", "Required", "Sensitive", "Validations"}, rows)
+}
+
+func (d *ModuleDescription) writeOutputs(b *strings.Builder) {
+	if len(d.Outputs) == 0 {
+		return
+	}
+	rows := make([][]string, 0, len(d.Outputs))
+	for _, o := range d.Outputs {
+		rows = append(rows, []string{o.Name, o.Description, yesNo(o.Sensitive), strings.Join(o.DerivedFrom, "\n")})
+	}
+	writeMarkdownSection(b, "Outputs", []string{"Name", "Description", "Sensitive", "Derived from"}, rows)
+}
+
+// writeMarkdownSection writes a second-level heading and a table with the
+// given header and rows.
+func writeMarkdownSection(b *strings.Builder, heading string, header []string, rows [][]string) {
+	fmt.Fprintf(b, "\n## %s\n\n", heading)
+	fmt.Fprintf(b, "| %s |\n", strings.Join(header, " | "))
+	fmt.Fprintf(b, "|%s\n", strings.Repeat(" --- |", len(header)))
+	for _, row := range rows {
+		cells := make([]string, len(row))
+		for i, cell := range row {
+			cells[i] = markdownCell(cell)
+		}
+		fmt.Fprintf(b, "| %s |\n", strings.Join(cells, " | "))
+	}
+}
+
+// jsonString returns the given value as compact JSON.
+func jsonString(v interface{}) string {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return "?"
+	}
+	return string(b)
+}
+
+// compactType puts a multi-line type constraint on a single line, e.g.
+// "object({ team = string, cost = optional(string) })".
+func compactType(s string) string {
+	var b strings.Builder
+	for _, line := range strings.Split(s, "\n") {
+		line = strings.TrimSpace(line)
+		if len(line) == 0 {
+			continue
+		}
+		if b.Len() > 0 {
+			previous := b.String()[b.Len()-1]
+			if strings.ContainsRune("{([,", rune(previous)) || strings.ContainsRune("})]", rune(line[0])) {
+				b.WriteString(" ")
+			} else {
+				b.WriteString(", ")
+			}
+		}
+		b.WriteString(line)
+	}
+	return b.String()
+}
+
+// markdownCell escapes the given text so that it fits in a table cell.
+func markdownCell(s string) string {
+	s = strings.TrimSpace(s)
+	s = strings.ReplaceAll(s, "|", `\|`)
+	return strings.ReplaceAll(s, "\n", "<br>")
+}
+
+// markdownCode formats the given text as inline code, or returns an empty
+// string if there is no text.
+func markdownCode(s string) string {
+	if len(s) == 0 {
+		return ""
+	}
+	return "`" + s + "`"
+}
+
+func yesNo(b bool) string {
+	if b {
+		return "yes"
+	}
+	return "no"
+}
@@ -0,0 +1,73 @@
+package hcl
+
+import (
+	"os"
+	"reflect"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+	"github.com/spilliams/terrascope/internal/docs"
+)
+
+func TestDescribe(t *testing.T) {
+	parser := NewModule(logrus.StandardLogger())
+	if err := parser.ParseModuleDirectory("../../fixtures/roots/described"); err != nil {
+		t.Fatal(err)
+	}
+	desc, err := parser.Describe()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(desc.Variables) != 3 || desc.Variables[1].Name != "name" {
+		t.Fatalf("Expected 3 variables with `name` second, got %v", desc.Variables)
+	}
+	expectedValidations := []*Validation{
+		{Condition: "length(var.name) <= 16", ErrorMessage: "The name must be 16 characters or fewer."},
+		{Condition: `can(regex("^[a-z-]+$", var.name))`, ErrorMessage: "The name must only contain lowercase letters and dashes."},
+	}
+	if !reflect.DeepEqual(desc.Variables[1].Validations, expectedValidations) {
+		t.Errorf("Expected validations %v, got %v", expectedValidations, desc.Variables[1].Validations)
+	}
+
+	expectedDerivedFrom := []string{"aws_instance.web", "data.aws_ami.ubuntu", "module.network", "random_string.suffix"}
+	if derivedFrom := desc.Outputs[1].DerivedFrom; !reflect.DeepEqual(derivedFrom, expectedDerivedFrom) {
+		t.Errorf("Expected web_ids to derive from %v, got %v", expectedDerivedFrom, derivedFrom)
+	}
+
+	expectedManaged := map[string]int{"aws_db_instance": 1, "aws_instance": 1, "random_string": 1}
+	if !reflect.DeepEqual(desc.ManagedResources, expectedManaged) {
+		t.Errorf("Expected managed resources %v, got %v", expectedManaged, desc.ManagedResources)
+	}
+
+	expectedMarkdown, err := os.ReadFile("../../fixtures/descriptions/described.md")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if actual := desc.Markdown(); actual != string(expectedMarkdown) {
+		t.Errorf("Expected Markdown:\n%s\nActual Markdown:\n%s", expectedMarkdown, actual)
+	}
+}
+
+func TestReadmeMarkdown(t *testing.T) {
+	parser := NewModule(logrus.StandardLogger())
+	if err := parser.ParseModuleDirectory("../../fixtures/roots/described"); err != nil {
+		t.Fatal(err)
+	}
+	desc, err := parser.Describe()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	readme, err := os.ReadFile("../../fixtures/roots/described/README.md")
+	if err != nil {
+		t.Fatal(err)
+	}
+	actual, err := docs.Inject(string(readme), desc.ReadmeMarkdown())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if actual != string(readme) {
+		t.Errorf("Expected the fixture README to be up to date. Expected:\n%s\nActual:\n%s", readme, actual)
+	}
+}
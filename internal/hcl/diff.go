@@ -0,0 +1,351 @@
+package hcl
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/ext/typeexpr"
+	"github.com/hashicorp/hcl/v2/hclsyntax"
+	"github.com/zclconf/go-cty/cty"
+	"github.com/zclconf/go-cty/cty/convert"
+)
+
+// SemverBump is the part of a module's semantic version that a change to the
+// module requires bumping.
+type SemverBump int
+
+// These are the possible bumps, from least to most severe.
+const (
+	BumpNone SemverBump = iota
+	BumpPatch
+	BumpMinor
+	BumpMajor
+)
+
+func (b SemverBump) String() string {
+	switch b {
+	case BumpPatch:
+		return "patch"
+	case BumpMinor:
+		return "minor"
+	case BumpMajor:
+		return "major"
+	}
+	return "none"
+}
+
+// InterfaceChange is a single difference between the interfaces of two
+// versions of a module.
+type InterfaceChange struct {
+	// Address is the address of the thing that changed, e.g. "var.region",
+	// "output.vpc_id", "provider.aws" or "aws_instance.web".
+	Address string
+	// Message describes the change, and how it affects callers.
+	Message string
+	// Bump is the version bump the change requires.
+	Bump SemverBump
+}
+
+// Breaking returns whether the receiver could break a caller of the module.
+func (c *InterfaceChange) Breaking() bool {
+	return c.Bump == BumpMajor
+}
+
+func (c *InterfaceChange) String() string {
+	return fmt.Sprintf("%s: %s", c.Address, c.Message)
+}
+
+// ModuleDiff is the set of differences between the interfaces of two
+// versions of a module.
+type ModuleDiff struct {
+	// Changes are sorted with the most severe first, then by address.
+	Changes []*InterfaceChange
+}
+
+// Bump returns the version bump that the receiver requires, which is that of
+// its most severe change.
+func (d *ModuleDiff) Bump() SemverBump {
+	bump := BumpNone
+	for _, c := range d.Changes {
+		if c.Bump > bump {
+			bump = c.Bump
+		}
+	}
+	return bump
+}
+
+func (d *ModuleDiff) add(address string, bump SemverBump, format string, args ...interface{}) {
+	d.Changes = append(d.Changes, &InterfaceChange{
+		Address: address,
+		Message: fmt.Sprintf(format, args...),
+		Bump:    bump,
+	})
+}
+
+// DiffModules compares the interfaces of two versions of a module: their
+// variables, outputs, provider requirements, and the addresses of their
+// managed resources and module calls (which hold state). The `moved`,
+// `import` and `removed` blocks of the new version say what happens to the
+// state of the addresses that changed.
+func DiffModules(oldModule, newModule Module) (*ModuleDiff, error) {
+	oldDesc, err := oldModule.Describe()
+	if err != nil {
+		return nil, err
+	}
+	newDesc, err := newModule.Describe()
+	if err != nil {
+		return nil, err
+	}
+
+	diff := &ModuleDiff{Changes: make([]*InterfaceChange, 0)}
+	diffVariables(diff, oldDesc.Variables, newDesc.Variables)
+	diffOutputs(diff, oldDesc.Outputs, newDesc.Outputs)
+	diffProviders(diff, oldDesc.RequiredProviders, newDesc.RequiredProviders)
+	refactors, err := newModule.Refactors()
+	if err != nil {
+		return nil, err
+	}
+	diffStatefulAddresses(diff, statefulAddresses(oldModule), statefulAddresses(newModule), refactors)
+
+	sort.SliceStable(diff.Changes, func(i, j int) bool {
+		if diff.Changes[i].Bump != diff.Changes[j].Bump {
+			return diff.Changes[i].Bump > diff.Changes[j].Bump
+		}
+		return diff.Changes[i].Address < diff.Changes[j].Address
+	})
+	return diff, nil
+}
+
+func diffVariables(diff *ModuleDiff, oldVars, newVars []*VariableDescription) {
+	oldByName := make(map[string]*VariableDescription, len(oldVars))
+	for _, v := range oldVars {
+		oldByName[v.Name] = v
+	}
+	newByName := make(map[string]*VariableDescription, len(newVars))
+	for _, v := range newVars {
+		newByName[v.Name] = v
+	}
+
+	for _, oldVar := range oldVars {
+		address := Address{Kind: kindVariable, Name: oldVar.Name}.String()
+		newVar, ok := newByName[oldVar.Name]
+		if !ok {
+			diff.add(address, BumpMajor, "removed. Callers that set it will fail")
+			continue
+		}
+
+		if changed, compatible := typeChange(oldVar.Type, newVar.Type); changed {
+			bump := BumpMajor
+			if compatible {
+				bump = BumpMinor
+			}
+			diff.add(address, bump, "type changed from %s to %s", typeName(oldVar.Type), typeName(newVar.Type))
+		}
+
+		switch {
+		case !oldVar.Required && newVar.Required:
+			diff.add(address, BumpMajor, "is now required. Callers that relied on its default will fail")
+		case oldVar.Required && !newVar.Required:
+			diff.add(address, BumpMinor, "is now optional")
+		case !oldVar.Required && !reflect.DeepEqual(oldVar.Default, newVar.Default):
+			diff.add(address, BumpMinor, "default changed from %s to %s. Callers that rely on the default will get the new one", jsonString(oldVar.Default), jsonString(newVar.Default))
+		}
+	}
+
+	for _, newVar := range newVars {
+		if _, ok := oldByName[newVar.Name]; ok {
+			continue
+		}
+		address := Address{Kind: kindVariable, Name: newVar.Name}.String()
+		if newVar.Required {
+			diff.add(address, BumpMajor, "added, and required. Every caller must set it")
+		} else {
+			diff.add(address, BumpMinor, "added, with a default")
+		}
+	}
+}
+
+func diffOutputs(diff *ModuleDiff, oldOutputs, newOutputs []*OutputDescription) {
+	oldByName := make(map[string]*OutputDescription, len(oldOutputs))
+	for _, o := range oldOutputs {
+		oldByName[o.Name] = o
+	}
+	newByName := make(map[string]*OutputDescription, len(newOutputs))
+	for _, o := range newOutputs {
+		newByName[o.Name] = o
+	}
+
+	for _, oldOutput := range oldOutputs {
+		address := Address{Kind: kindOutput, Name: oldOutput.Name}.String()
+		newOutput, ok := newByName[oldOutput.Name]
+		if !ok {
+			message := "removed. Callers that refer to it will fail"
+			// an added output with the same value was probably renamed
+			for _, o := range newOutputs {
+				if _, existed := oldByName[o.Name]; !existed && len(o.Value) > 0 && o.Value == oldOutput.Value {
+					message = fmt.Sprintf("renamed to %s. Callers that refer to it will fail", Address{Kind: kindOutput, Name: o.Name})
+					break
+				}
+			}
+			diff.add(address, BumpMajor, "%s", message)
+			continue
+		}
+		if !oldOutput.Sensitive && newOutput.Sensitive {
+			diff.add(address, BumpMajor, "is now sensitive. Callers that use it in non-sensitive places will fail")
+		}
+		if oldOutput.Sensitive && !newOutput.Sensitive {
+			diff.add(address, BumpPatch, "is no longer sensitive")
+		}
+	}
+
+	for _, newOutput := range newOutputs {
+		if _, ok := oldByName[newOutput.Name]; !ok {
+			diff.add(Address{Kind: kindOutput, Name: newOutput.Name}.String(), BumpMinor, "added")
+		}
+	}
+}
+
+func diffProviders(diff *ModuleDiff, oldProviders, newProviders []*ProviderDescription) {
+	oldByName := make(map[string]*ProviderDescription, len(oldProviders))
+	for _, p := range oldProviders {
+		oldByName[p.Name] = p
+	}
+	newByName := make(map[string]*ProviderDescription, len(newProviders))
+	for _, p := range newProviders {
+		newByName[p.Name] = p
+	}
+
+	for _, oldProvider := range oldProviders {
+		address := Address{Kind: kindProvider, Type: oldProvider.Name}.String()
+		newProvider, ok := newByName[oldProvider.Name]
+		if !ok {
+			diff.add(address, BumpPatch, "is no longer required")
+			continue
+		}
+		if oldProvider.Source != newProvider.Source {
+			diff.add(address, BumpMajor, "source changed from %q to %q. Callers must configure the new provider", oldProvider.Source, newProvider.Source)
+		}
+		oldVersions := strings.Join(oldProvider.VersionConstraints, ", ")
+		newVersions := strings.Join(newProvider.VersionConstraints, ", ")
+		if oldVersions != newVersions {
+			diff.add(address, BumpMajor, "version constraints changed from %q to %q. Callers whose lock file falls outside them must upgrade the provider", oldVersions, newVersions)
+		}
+		for _, alias := range newProvider.ConfigurationAliases {
+			if !contains(oldProvider.ConfigurationAliases, alias) {
+				diff.add(address, BumpMajor, "requires a new configuration alias %s.%s. Callers must pass it in `providers`", alias.Name, alias.Alias)
+			}
+		}
+	}
+
+	for _, newProvider := range newProviders {
+		if _, ok := oldByName[newProvider.Name]; !ok {
+			address := Address{Kind: kindProvider, Type: newProvider.Name}.String()
+			diff.add(address, BumpMinor, "is now required. Callers that pass providers explicitly must pass it too")
+		}
+	}
+}
+
+// statefulAddresses returns the addresses of the given module's managed
+// resources and module calls: the things that hold state.
+func statefulAddresses(m Module) []string {
+	addresses := make([]string, 0)
+	for _, r := range m.Module().ManagedResources {
+		addresses = append(addresses, Address{Kind: kindResource, Type: r.Type, Name: r.Name}.String())
+	}
+	for _, call := range m.Module().ModuleCalls {
+		addresses = append(addresses, Address{Kind: kindModule, Name: call.Name}.String())
+	}
+	sort.Strings(addresses)
+	return addresses
+}
+
+func diffStatefulAddresses(diff *ModuleDiff, oldAddresses, newAddresses []string, refactors *Refactors) {
+	movedTo := make(map[string]string, len(refactors.Moves))
+	for _, move := range refactors.Moves {
+		movedTo[move.From] = move.To
+	}
+	added := setSubtract(newAddresses, oldAddresses)
+	// added addresses that are already accounted for can't be where
+	// something else moved to
+	unexplained := setSubtract(added, refactors.Imports)
+	for _, move := range refactors.Moves {
+		unexplained = setSubtract(unexplained, []string{move.To})
+	}
+
+	for _, address := range setSubtract(oldAddresses, newAddresses) {
+		if to, ok := movedTo[address]; ok {
+			diff.add(address, BumpPatch, "moved to %s by a `moved` block", to)
+			continue
+		}
+		if contains(refactors.Removals, address) {
+			diff.add(address, BumpMinor, "removed by a `removed` block")
+			continue
+		}
+		addr := ParseAddress(address)
+		candidates := make([]string, 0)
+		for _, other := range unexplained {
+			otherAddr := ParseAddress(other)
+			if otherAddr.Kind == addr.Kind && otherAddr.Type == addr.Type {
+				candidates = append(candidates, other)
+			}
+		}
+		if len(candidates) > 0 {
+			diff.add(address, BumpMajor, "removed. If it moved to %s, add a `moved` block, or it will be destroyed and recreated", strings.Join(candidates, " or "))
+		} else {
+			diff.add(address, BumpMajor, "removed. It will be destroyed, unless a `moved` block gives it a new address")
+		}
+	}
+	for _, address := range added {
+		switch {
+		case contains(refactors.Imports, address):
+			diff.add(address, BumpMinor, "added, and imported by an `import` block")
+		case contains(unexplained, address):
+			diff.add(address, BumpMinor, "added")
+		}
+	}
+}
+
+// typeChange returns whether the given variable type constraints are
+// different, and if so, whether every value of the old type can be converted
+// to the new type (so that no caller breaks).
+func typeChange(oldSource, newSource string) (changed, compatible bool) {
+	oldType, oldErr := parseTypeConstraint(oldSource)
+	newType, newErr := parseTypeConstraint(newSource)
+	if oldErr != nil || newErr != nil {
+		changed = compactType(oldSource) != compactType(newSource)
+		return changed, !changed
+	}
+	if oldType.Equals(newType) {
+		return false, true
+	}
+	return true, convert.GetConversion(oldType, newType) != nil
+}
+
+// parseTypeConstraint reads the source of a variable's type constraint, e.g.
+// "list(string)". An empty constraint accepts any type.
+func parseTypeConstraint(source string) (cty.Type, error) {
+	if len(strings.TrimSpace(source)) == 0 {
+		return cty.DynamicPseudoType, nil
+	}
+	expr, diags := hclsyntax.ParseExpression([]byte(source), "", hcl.InitialPos)
+	if diags.HasErrors() {
+		return cty.NilType, diags
+	}
+	ty, _, diags := typeexpr.TypeConstraintWithDefaults(expr)
+	if diags.HasErrors() {
+		return cty.NilType, diags
+	}
+	return ty, nil
+}
+
+// typeName returns the given type constraint on a single line, or "any" if
+// there is none.
+func typeName(source string) string {
+	if len(strings.TrimSpace(source)) == 0 {
+		return "any"
+	}
+	return compactType(source)
+}
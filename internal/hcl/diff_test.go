@@ -0,0 +1,107 @@
+package hcl
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+)
+
+func TestDiffModules(t *testing.T) {
+	oldModule := NewModule(logrus.StandardLogger())
+	if err := oldModule.ParseModuleDirectory("../../fixtures/modules/diff/v1"); err != nil {
+		t.Fatal(err)
+	}
+	newModule := NewModule(logrus.StandardLogger())
+	if err := newModule.ParseModuleDirectory("../../fixtures/modules/diff/v2"); err != nil {
+		t.Fatal(err)
+	}
+
+	diff, err := DiffModules(oldModule, newModule)
+	if err != nil {
+		t.Fatal(err)
+	}
+	actual := make([]string, len(diff.Changes))
+	for i, c := range diff.Changes {
+		actual[i] = c.Bump.String() + " " + c.String()
+	}
+	expected := []string{
+		"major aws_instance.web: removed. If it moved to aws_instance.app, add a `moved` block, or it will be destroyed and recreated",
+		"major output.ip: renamed to output.public_ip. Callers that refer to it will fail",
+		`major provider.aws: version constraints changed from "~> 4.0" to "~> 5.0". Callers whose lock file falls outside them must upgrade the provider`,
+		"major var.legacy: removed. Callers that set it will fail",
+		"major var.owner: added, and required. Every caller must set it",
+		"minor aws_instance.app: added",
+		"minor output.public_ip: added",
+		"minor provider.random: is now required. Callers that pass providers explicitly must pass it too",
+		"minor random_pet.this: added",
+		"minor var.debug: added, with a default",
+		"minor var.size: default changed from 1 to 2. Callers that rely on the default will get the new one",
+		"minor var.zones: type changed from list(string) to any",
+	}
+	if strings.Join(actual, "\n") != strings.Join(expected, "\n") {
+		t.Errorf("Expected changes:\n%s\nActual changes:\n%s", strings.Join(expected, "\n"), strings.Join(actual, "\n"))
+	}
+	if diff.Bump() != BumpMajor {
+		t.Errorf("Expected a major bump, got %s", diff.Bump())
+	}
+}
+
+func TestTypeChange(t *testing.T) {
+	type test struct {
+		oldType            string
+		newType            string
+		expectedChanged    bool
+		expectedCompatible bool
+	}
+	tests := []test{
+		{oldType: "string", newType: "string", expectedChanged: false, expectedCompatible: true},
+		{oldType: "list(string)", newType: "list( string )", expectedChanged: false, expectedCompatible: true},
+		{oldType: "number", newType: "string", expectedChanged: true, expectedCompatible: true},
+		{oldType: "string", newType: "number", expectedChanged: true, expectedCompatible: false},
+		{oldType: "string", newType: "", expectedChanged: true, expectedCompatible: true},
+		{oldType: "object({a = string})", newType: "object({a = string, b = optional(string)})", expectedChanged: true, expectedCompatible: true},
+		{oldType: "object({a = string})", newType: "object({a = string, b = string})", expectedChanged: true, expectedCompatible: false},
+	}
+	for _, tc := range tests {
+		t.Run(tc.oldType+" to "+tc.newType, func(t *testing.T) {
+			changed, compatible := typeChange(tc.oldType, tc.newType)
+			if changed != tc.expectedChanged || compatible != tc.expectedCompatible {
+				t.Errorf("Expected changed=%v compatible=%v, got changed=%v compatible=%v", tc.expectedChanged, tc.expectedCompatible, changed, compatible)
+			}
+		})
+	}
+}
+
+func TestDiffModulesRefactors(t *testing.T) {
+	oldModule := NewModule(logrus.StandardLogger())
+	if err := oldModule.ParseModuleDirectory("../../fixtures/modules/refactor/v1"); err != nil {
+		t.Fatal(err)
+	}
+	newModule := NewModule(logrus.StandardLogger())
+	if err := newModule.ParseModuleDirectory("../../fixtures/modules/refactor/v2"); err != nil {
+		t.Fatal(err)
+	}
+
+	diff, err := DiffModules(oldModule, newModule)
+	if err != nil {
+		t.Fatal(err)
+	}
+	actual := make([]string, len(diff.Changes))
+	for i, c := range diff.Changes {
+		actual[i] = c.Bump.String() + " " + c.String()
+	}
+	expected := []string{
+		"major aws_instance.web: removed. If it moved to aws_instance.app, add a `moved` block, or it will be destroyed and recreated",
+		"major aws_subnet.public: removed. It will be destroyed, unless a `moved` block gives it a new address",
+		"major aws_vpc.main: removed. It will be destroyed, unless a `moved` block gives it a new address",
+		"minor aws_instance.app: added",
+		"minor aws_s3_bucket.imported: added, and imported by an `import` block",
+		"minor aws_s3_bucket.old: removed by a `removed` block",
+		"minor module.network: added",
+		"patch aws_subnet.private: moved to module.network.aws_subnet.private by a `moved` block",
+	}
+	if strings.Join(actual, "\n") != strings.Join(expected, "\n") {
+		t.Errorf("Expected changes:\n%s\nActual changes:\n%s", strings.Join(expected, "\n"), strings.Join(actual, "\n"))
+	}
+}
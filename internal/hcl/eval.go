@@ -0,0 +1,444 @@
+package hcl
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/ext/typeexpr"
+	"github.com/hashicorp/hcl/v2/hclsyntax"
+	"github.com/sirupsen/logrus"
+	"github.com/zclconf/go-cty/cty"
+	"github.com/zclconf/go-cty/cty/convert"
+)
+
+// variableSchema picks out the arguments of a variable block that decide its
+// value
+var variableSchema = &hcl.BodySchema{
+	Attributes: []hcl.AttributeSchema{
+		{Name: "type"},
+		{Name: "default"},
+	},
+}
+
+// commandLineFilename stands in for the file name of values given on the
+// command line
+const commandLineFilename = "<command line>"
+
+// InputValue is a value given for a variable, in a variables file or on the
+// command line.
+type InputValue struct {
+	// Value is the value from a variables file. It is cty.NilVal for a value
+	// from the command line, which can't be read until the variable's type is
+	// known.
+	Value cty.Value
+	// Raw is the value from the command line, as it was given.
+	Raw string
+	// Range is where the value was given. For a value from the command line,
+	// it has no position, and its file name is commandLineFilename.
+	Range hcl.Range
+}
+
+// InputValues are the values given for a module's variables, keyed by
+// variable name.
+type InputValues map[string]*InputValue
+
+// Merge copies the given values into the receiver, replacing any it already
+// has.
+func (iv InputValues) Merge(other InputValues) {
+	for name, v := range other {
+		iv[name] = v
+	}
+}
+
+// value returns the receiver's value. Like terraform, a value from the
+// command line is read as a literal string if its variable is given as one
+// (it has a primitive type, or no type at all), or as an expression
+// otherwise.
+func (v *InputValue) value(literal bool) (cty.Value, hcl.Diagnostics) {
+	if v.Value != cty.NilVal {
+		return v.Value, nil
+	}
+	if literal {
+		return cty.StringVal(v.Raw), nil
+	}
+	expr, diags := hclsyntax.ParseExpression([]byte(v.Raw), "<value for var>", hcl.InitialPos)
+	if diags.HasErrors() {
+		return cty.DynamicVal, diags
+	}
+	return expr.Value(nil)
+}
+
+// ParseVariableFile reads a variables file (`.tfvars`, or `.tfvars.json` in
+// JSON syntax).
+func (m *module) ParseVariableFile(filename string) (InputValues, error) {
+	var file *hcl.File
+	var diags hcl.Diagnostics
+	if strings.HasSuffix(filename, ".json") {
+		file, diags = m.fundamental.ParseJSONFile(filename)
+	} else {
+		file, diags = m.fundamental.ParseHCLFile(filename)
+	}
+	if err := handleDiags(diags, m.fundamental.Files(), m.Logger.WriterLevel(logrus.WarnLevel)); err != nil {
+		return nil, err
+	}
+
+	attrs, diags := file.Body.JustAttributes()
+	values := make(InputValues, len(attrs))
+	for name, attr := range attrs {
+		value, valueDiags := attr.Expr.Value(nil)
+		diags = append(diags, valueDiags...)
+		values[name] = &InputValue{Value: value, Range: attr.Range}
+	}
+	if err := handleDiags(diags, m.fundamental.Files(), m.Logger.WriterLevel(logrus.WarnLevel)); err != nil {
+		return nil, err
+	}
+	return values, nil
+}
+
+// ParseVariableFlag reads a variable given on the command line as
+// `NAME=VALUE`.
+func ParseVariableFlag(arg string) (InputValues, error) {
+	name, raw, ok := strings.Cut(arg, "=")
+	if !ok || len(strings.TrimSpace(name)) == 0 {
+		return nil, fmt.Errorf("%q is not a variable: expected NAME=VALUE", arg)
+	}
+	return InputValues{strings.TrimSpace(name): &InputValue{
+		Raw:   raw,
+		Range: hcl.Range{Filename: commandLineFilename},
+	}}, nil
+}
+
+// AutoVariableFiles returns the variables files in the given directory that
+// terraform reads without being told to, in the order it reads them:
+// `terraform.tfvars`, `terraform.tfvars.json`, then any `*.auto.tfvars` or
+// `*.auto.tfvars.json`, sorted by name.
+func AutoVariableFiles(dir string) ([]string, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	files := make([]string, 0)
+	auto := make([]string, 0)
+	for _, entry := range entries {
+		name := entry.Name()
+		switch {
+		case entry.IsDir():
+			continue
+		case name == "terraform.tfvars" || name == "terraform.tfvars.json":
+			files = append(files, filepath.Join(dir, name))
+		case strings.HasSuffix(name, ".auto.tfvars") || strings.HasSuffix(name, ".auto.tfvars.json"):
+			auto = append(auto, filepath.Join(dir, name))
+		}
+	}
+	sort.Strings(files)
+	sort.Strings(auto)
+	return append(files, auto...), nil
+}
+
+// Evaluator evaluates expressions in a module without planning it. Variables
+// get the values given for them (or their defaults), and locals are
+// evaluated from those. Everything that only exists once the module is
+// applied, like resource attributes and module outputs, is unknown.
+type Evaluator struct {
+	// Variables are the values of the module's variables, keyed by name.
+	// Variables with no value are unknown.
+	Variables map[string]cty.Value
+	// Locals are the values of the module's locals, keyed by name. Locals
+	// that can't be evaluated are unknown.
+	Locals map[string]cty.Value
+	// LocalOrder is the order the locals were evaluated in, so that each
+	// comes after the locals it refers to.
+	LocalOrder []string
+	// Diagnostics are the problems found evaluating the variables and
+	// locals.
+	Diagnostics hcl.Diagnostics
+
+	ctx *hcl.EvalContext
+}
+
+// Evaluator returns an evaluator for the receiver, with the given values for
+// its variables. It is an error to give a value that doesn't match its
+// variable's type, but values for undeclared variables are ignored.
+func (m *module) Evaluator(inputs InputValues) (*Evaluator, error) {
+	e, diags := m.newEvaluator(inputs)
+	if err := handleDiags(diags, m.fundamental.Files(), m.Logger.WriterLevel(logrus.WarnLevel)); err != nil {
+		return nil, err
+	}
+	return e, nil
+}
+
+// newEvaluator returns an evaluator for the receiver, and the problems with
+// the given values. Variables whose values have problems are unknown.
+func (m *module) newEvaluator(inputs InputValues) (*Evaluator, hcl.Diagnostics) {
+	e := &Evaluator{
+		Variables: make(map[string]cty.Value),
+		Locals:    make(map[string]cty.Value),
+	}
+
+	var diags hcl.Diagnostics
+	for addr, block := range m.cfg.blocks {
+		if addr.Kind != kindVariable {
+			continue
+		}
+		value, valueDiags := m.variableValue(block, inputs[addr.Name])
+		diags = append(diags, valueDiags...)
+		e.Variables[addr.Name] = value
+	}
+
+	e.ctx = &hcl.EvalContext{
+		Variables: m.unknownObjects(),
+		Functions: functions(),
+	}
+	e.ctx.Variables["var"] = cty.ObjectVal(e.Variables)
+	if m.module != nil {
+		e.ctx.Variables["path"] = cty.ObjectVal(map[string]cty.Value{
+			"module": cty.StringVal(m.module.Path),
+			"root":   cty.StringVal(m.module.Path),
+			"cwd":    cty.UnknownVal(cty.String),
+		})
+	}
+	e.evaluateLocals(m.cfg.locals)
+	return e, diags
+}
+
+// variableValue returns the value of the given variable block: the given
+// input, or else its default, converted to its type. It is unknown if there
+// is neither.
+func (m *module) variableValue(block *hcl.Block, input *InputValue) (cty.Value, hcl.Diagnostics) {
+	content, _, diags := block.Body.PartialContent(variableSchema)
+	ty := cty.DynamicPseudoType
+	typeSource := ""
+	var defaults *typeexpr.Defaults
+	if attr, ok := content.Attributes["type"]; ok {
+		typeSource = m.source(attr.Expr.Range())
+		var typeDiags hcl.Diagnostics
+		ty, defaults, typeDiags = typeexpr.TypeConstraintWithDefaults(attr.Expr)
+		diags = append(diags, typeDiags...)
+		if typeDiags.HasErrors() {
+			return cty.DynamicVal, diags
+		}
+	}
+
+	var value cty.Value
+	subject := block.DefRange
+	switch attr, ok := content.Attributes["default"]; {
+	case input != nil:
+		var valueDiags hcl.Diagnostics
+		value, valueDiags = input.value(len(typeSource) == 0 || ty.IsPrimitiveType())
+		diags = append(diags, valueDiags...)
+		if input.Value != cty.NilVal {
+			subject = input.Range
+		}
+	case ok:
+		var valueDiags hcl.Diagnostics
+		value, valueDiags = attr.Expr.Value(nil)
+		diags = append(diags, valueDiags...)
+		subject = attr.Expr.Range()
+	default:
+		return cty.UnknownVal(ty), diags
+	}
+	if diags.HasErrors() {
+		return cty.UnknownVal(ty), diags
+	}
+
+	if defaults != nil {
+		value = defaults.Apply(value)
+	}
+	converted, err := convert.Convert(value, ty)
+	if err != nil {
+		diags = append(diags, &hcl.Diagnostic{
+			Severity: hcl.DiagError,
+			Summary:  "Invalid value for variable",
+			Detail:   fmt.Sprintf("The value for var.%s is not compatible with its type %s: %s.", block.Labels[0], typeName(typeSource), err),
+			Subject:  subject.Ptr(),
+		})
+		return cty.UnknownVal(ty), diags
+	}
+	return converted, diags
+}
+
+// unknownObjects returns the root objects that refer to things that only
+// exist once the receiver is applied, with their attributes unknown: each
+// resource type, `data`, `module` and `terraform`.
+func (m *module) unknownObjects() map[string]cty.Value {
+	resources := make(map[string]map[string]cty.Value)
+	data := make(map[string]map[string]cty.Value)
+	modules := make(map[string]cty.Value)
+	for addr := range m.cfg.blocks {
+		switch addr.Kind {
+		case kindResource:
+			if resources[addr.Type] == nil {
+				resources[addr.Type] = make(map[string]cty.Value)
+			}
+			resources[addr.Type][addr.Name] = cty.DynamicVal
+		case kindData:
+			if data[addr.Type] == nil {
+				data[addr.Type] = make(map[string]cty.Value)
+			}
+			data[addr.Type][addr.Name] = cty.DynamicVal
+		case kindModule:
+			modules[addr.Name] = cty.DynamicVal
+		}
+	}
+
+	objects := map[string]cty.Value{
+		"module": cty.ObjectVal(modules),
+		"terraform": cty.ObjectVal(map[string]cty.Value{
+			"workspace": cty.UnknownVal(cty.String),
+		}),
+	}
+	dataTypes := make(map[string]cty.Value, len(data))
+	for ty, names := range data {
+		dataTypes[ty] = cty.ObjectVal(names)
+	}
+	objects["data"] = cty.ObjectVal(dataTypes)
+	for ty, names := range resources {
+		objects[ty] = cty.ObjectVal(names)
+	}
+	return objects
+}
+
+// evaluateLocals evaluates the given locals, each after the locals it refers
+// to. Locals that refer to each other in a cycle are unknown.
+func (e *Evaluator) evaluateLocals(locals map[Address]*hcl.Attribute) {
+	// map from local to the locals it refers to
+	pending := make(map[string][]string, len(locals))
+	attrs := make(map[string]*hcl.Attribute, len(locals))
+	for addr, attr := range locals {
+		deps := make([]string, 0)
+		for _, traversal := range attr.Expr.Variables() {
+			if traversal.RootName() != kindLocal || len(traversal) < 2 {
+				continue
+			}
+			if step, ok := traversal[1].(hcl.TraverseAttr); ok {
+				if _, declared := locals[Address{Kind: kindLocal, Name: step.Name}]; declared && step.Name != addr.Name {
+					deps = append(deps, step.Name)
+				}
+			}
+		}
+		pending[addr.Name] = unique(deps)
+		attrs[addr.Name] = attr
+	}
+
+	e.LocalOrder = make([]string, 0, len(locals))
+	for len(pending) > 0 {
+		ready := make([]string, 0)
+		for name, deps := range pending {
+			if len(setSubtract(deps, e.LocalOrder)) == 0 {
+				ready = append(ready, name)
+			}
+		}
+		if len(ready) == 0 {
+			// everything left is in (or depends on) a cycle
+			for name := range pending {
+				ready = append(ready, name)
+			}
+			sort.Strings(ready)
+			for _, name := range ready {
+				e.Locals[name] = cty.DynamicVal
+				e.LocalOrder = append(e.LocalOrder, name)
+				e.Diagnostics = append(e.Diagnostics, &hcl.Diagnostic{
+					Severity: hcl.DiagWarning,
+					Summary:  "Local value in a cycle",
+					Detail:   fmt.Sprintf("local.%s is in (or refers to) a cycle of locals that refer to each other, so it can't be evaluated.", name),
+					Subject:  attrs[name].NameRange.Ptr(),
+				})
+			}
+			break
+		}
+
+		sort.Strings(ready)
+		for _, name := range ready {
+			e.ctx.Variables["local"] = cty.ObjectVal(e.Locals)
+			value, diags := attrs[name].Expr.Value(e.ctx)
+			if diags.HasErrors() {
+				value = cty.DynamicVal
+			}
+			e.Diagnostics = append(e.Diagnostics, diags...)
+			e.Locals[name] = value
+			e.LocalOrder = append(e.LocalOrder, name)
+			delete(pending, name)
+		}
+	}
+	e.ctx.Variables["local"] = cty.ObjectVal(e.Locals)
+}
+
+// EvalContext returns the context the receiver evaluates expressions in.
+func (e *Evaluator) EvalContext() *hcl.EvalContext {
+	return e.ctx
+}
+
+// Evaluate parses and evaluates the given expression, e.g.
+// `cidrsubnet(var.cidr, 8, 1)`.
+func (e *Evaluator) Evaluate(expression string) (cty.Value, error) {
+	expr, diags := hclsyntax.ParseExpression([]byte(expression), "<expression>", hcl.InitialPos)
+	if diags.HasErrors() {
+		return cty.DynamicVal, diags
+	}
+	value, diags := expr.Value(e.ctx)
+	if diags.HasErrors() {
+		return cty.DynamicVal, diags
+	}
+	return value, nil
+}
+
+// FormatValue returns the given value in terraform's syntax, with unknown
+// values written as `(known after apply)`.
+func FormatValue(value cty.Value) string {
+	var b strings.Builder
+	writeValue(&b, value, "")
+	return b.String()
+}
+
+func writeValue(b *strings.Builder, value cty.Value, indent string) {
+	switch {
+	case !value.IsKnown():
+		b.WriteString("(known after apply)")
+		return
+	case value.IsNull():
+		b.WriteString("null")
+		return
+	}
+
+	ty := value.Type()
+	switch {
+	case ty == cty.String:
+		fmt.Fprintf(b, "%q", value.AsString())
+	case ty == cty.Number:
+		b.WriteString(value.AsBigFloat().Text('f', -1))
+	case ty == cty.Bool:
+		fmt.Fprintf(b, "%t", value.True())
+	case ty.IsListType() || ty.IsSetType() || ty.IsTupleType():
+		if value.LengthInt() == 0 {
+			b.WriteString("[]")
+			return
+		}
+		b.WriteString("[\n")
+		for it := value.ElementIterator(); it.Next(); {
+			_, element := it.Element()
+			b.WriteString(indent + "  ")
+			writeValue(b, element, indent+"  ")
+			b.WriteString(",\n")
+		}
+		b.WriteString(indent + "]")
+	case ty.IsMapType() || ty.IsObjectType():
+		if value.LengthInt() == 0 {
+			b.WriteString("{}")
+			return
+		}
+		b.WriteString("{\n")
+		for it := value.ElementIterator(); it.Next(); {
+			key, element := it.Element()
+			fmt.Fprintf(b, "%s  %q = ", indent, key.AsString())
+			writeValue(b, element, indent+"  ")
+			b.WriteString("\n")
+		}
+		b.WriteString(indent + "}")
+	default:
+		b.WriteString("(unknown)")
+	}
+}
@@ -0,0 +1,204 @@
+package hcl
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/sirupsen/logrus"
+	"github.com/zclconf/go-cty/cty"
+)
+
+func TestEvaluator(t *testing.T) {
+	parser := NewModule(logrus.StandardLogger())
+	if err := parser.ParseModuleDirectory("../../fixtures/roots/evaluated"); err != nil {
+		t.Fatal(err)
+	}
+	inputs, err := parser.ParseVariableFile("../../fixtures/roots/evaluated/prod.tfvars")
+	if err != nil {
+		t.Fatal(err)
+	}
+	flag, err := ParseVariableFlag(`tags={ team = "data" }`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	inputs.Merge(flag)
+
+	evaluator, err := parser.Evaluator(inputs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(evaluator.Diagnostics) > 0 {
+		t.Errorf("Unexpected diagnostics: %s", evaluator.Diagnostics)
+	}
+
+	// prefix comes before name, which comes before tags
+	order := strings.Join(evaluator.LocalOrder, ", ")
+	if order != "prefix, subnets, vpc_id, name, tags" {
+		t.Errorf("Unexpected local order %s", order)
+	}
+
+	expected := map[string]string{
+		"name":    `"acme-prod"`,
+		"vpc_id":  "(known after apply)",
+		"subnets": "{\n  \"a\" = \"10.0.0.0/24\"\n  \"b\" = \"10.0.1.0/24\"\n  \"c\" = \"10.0.2.0/24\"\n}",
+		"tags":    "{\n  \"Name\" = \"acme-prod\"\n  \"owner\" = \"platform\"\n  \"team\" = \"data\"\n}",
+	}
+	for name, value := range expected {
+		if actual := FormatValue(evaluator.Locals[name]); actual != value {
+			t.Errorf("Expected local.%s to be %s, got %s", name, value, actual)
+		}
+	}
+
+	value, err := evaluator.Evaluate(`upper(var.env)`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !value.RawEquals(cty.StringVal("PROD")) {
+		t.Errorf("Expected \"PROD\", got %s", FormatValue(value))
+	}
+}
+
+func TestEvaluatorTypeMismatch(t *testing.T) {
+	parser := NewModule(logrus.StandardLogger())
+	if err := parser.ParseModuleDirectory("../../fixtures/roots/evaluated"); err != nil {
+		t.Fatal(err)
+	}
+	flag, err := ParseVariableFlag("zones=nope")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := parser.Evaluator(flag); err == nil {
+		t.Error("Expected an error for a value that doesn't match its variable's type")
+	}
+}
+
+func TestCidrFunctions(t *testing.T) {
+	type test struct {
+		expression  string
+		expected    string
+		expectError bool
+	}
+	tests := []test{
+		{expression: `cidrsubnet("10.1.2.0/24", 4, 15)`, expected: `"10.1.2.240/28"`},
+		{expression: `cidrsubnet("fd00:fd12:3456:7890::/56", 16, 162)`, expected: `"fd00:fd12:3456:7800:a200::/72"`},
+		{expression: `cidrsubnet("10.1.2.0/24", 4, 16)`, expectError: true},
+		{expression: `cidrhost("10.12.112.0/20", 16)`, expected: `"10.12.112.16"`},
+		{expression: `cidrhost("10.12.112.0/20", 268)`, expected: `"10.12.113.12"`},
+		{expression: `cidrhost("fd00:fd12:3456:7890:00a2::/72", 34)`, expected: `"fd00:fd12:3456:7890::22"`},
+		{expression: `cidrhost("10.0.0.0/30", 4)`, expectError: true},
+		{expression: `cidrnetmask("172.16.0.0/12")`, expected: `"255.240.0.0"`},
+		{expression: `cidrsubnets("10.1.0.0/16", 4, 4, 8, 4)`, expected: "[\n  \"10.1.0.0/20\",\n  \"10.1.16.0/20\",\n  \"10.1.32.0/24\",\n  \"10.1.48.0/20\",\n]"},
+		{expression: `cidrsubnets("10.0.0.0/30", 1, 1, 1)`, expectError: true},
+	}
+	evaluator := &Evaluator{ctx: &hcl.EvalContext{Functions: functions()}}
+	for _, tc := range tests {
+		t.Run(tc.expression, func(t *testing.T) {
+			value, err := evaluator.Evaluate(tc.expression)
+			if tc.expectError {
+				if err == nil {
+					t.Errorf("Expected an error, got %s", FormatValue(value))
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if actual := FormatValue(value); actual != tc.expected {
+				t.Errorf("Expected %s, got %s", tc.expected, actual)
+			}
+		})
+	}
+}
+
+func TestFunctions(t *testing.T) {
+	type test struct {
+		expression  string
+		expected    string
+		expectError bool
+	}
+	tests := []test{
+		{expression: `one(["a"])`, expected: `"a"`},
+		{expression: `one([])`, expected: "null"},
+		{expression: `one(["a", "b"])`, expectError: true},
+		{expression: `sum([1, 2.5, 3])`, expected: "6.5"},
+		{expression: `sum([])`, expectError: true},
+		{expression: `alltrue([true, true])`, expected: "true"},
+		{expression: `alltrue([true, false])`, expected: "false"},
+		{expression: `anytrue([false, true])`, expected: "true"},
+		{expression: `anytrue([])`, expected: "false"},
+		{expression: `startswith("hello world", "hello")`, expected: "true"},
+		{expression: `endswith("hello world", "hello")`, expected: "false"},
+		{expression: `base64encode("Hello World")`, expected: `"SGVsbG8gV29ybGQ="`},
+		{expression: `base64decode("SGVsbG8gV29ybGQ=")`, expected: `"Hello World"`},
+		{expression: `base64decode("not base64")`, expectError: true},
+		{expression: `md5("hello world")`, expected: `"5eb63bbbe01eeed093cb22bb8f5acdc3"`},
+		{expression: `sha256("hello world")`, expected: `"b94d27b9934d3e08a52e52d7da7dabfac484efe37a5380ee9088f7ace2efcde9"`},
+		{expression: `nonsensitive(sensitive("secret"))`, expected: `"secret"`},
+		{expression: `transpose({a = ["1", "2"], b = ["2", "3"]})`, expected: "{\n  \"1\" = [\n    \"a\",\n  ]\n  \"2\" = [\n    \"a\",\n    \"b\",\n  ]\n  \"3\" = [\n    \"b\",\n  ]\n}"},
+		{expression: `matchkeys(["i-1", "i-2", "i-3"], ["us-west", "us-east", "us-east"], ["us-east"])`, expected: "[\n  \"i-2\",\n  \"i-3\",\n]"},
+	}
+	evaluator := &Evaluator{ctx: &hcl.EvalContext{Functions: functions()}}
+	for _, tc := range tests {
+		t.Run(tc.expression, func(t *testing.T) {
+			value, err := evaluator.Evaluate(tc.expression)
+			if tc.expectError {
+				if err == nil {
+					t.Errorf("Expected an error, got %s", FormatValue(value))
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if actual := FormatValue(value); actual != tc.expected {
+				t.Errorf("Expected %s, got %s", tc.expected, actual)
+			}
+		})
+	}
+}
+
+func TestEvaluatorUntypedVariable(t *testing.T) {
+	dir := t.TempDir()
+	src := `variable "name" {}
+
+variable "names" {
+  type = any
+}
+
+locals {
+  greeting = "hello, ${var.name}"
+  count    = length(var.names)
+}
+`
+	if err := os.WriteFile(filepath.Join(dir, "main.tf"), []byte(src), 0644); err != nil {
+		t.Fatal(err)
+	}
+	parser := NewModule(logrus.StandardLogger())
+	if err := parser.ParseModuleDirectory(dir); err != nil {
+		t.Fatal(err)
+	}
+	inputs := make(InputValues)
+	// like terraform, a value for a variable with no type is a literal
+	// string, and one for a variable of type any is an expression
+	for _, arg := range []string{"name=world", `names=["a", "b"]`} {
+		flag, err := ParseVariableFlag(arg)
+		if err != nil {
+			t.Fatal(err)
+		}
+		inputs.Merge(flag)
+	}
+
+	evaluator, err := parser.Evaluator(inputs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if actual := FormatValue(evaluator.Locals["greeting"]); actual != `"hello, world"` {
+		t.Errorf("Expected local.greeting to be \"hello, world\", got %s", actual)
+	}
+	if actual := FormatValue(evaluator.Locals["count"]); actual != "2" {
+		t.Errorf("Expected local.count to be 2, got %s", actual)
+	}
+}
@@ -0,0 +1,142 @@
+package hcl
+
+import (
+	"fmt"
+	"math/big"
+	"sort"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/zclconf/go-cty/cty"
+)
+
+// ExpandedGraph returns a graph like Graph does, except that each resource,
+// data source and module call with a `count` or `for_each` has a node for
+// each of its instances (e.g. `random_string.this["a"]`) instead of a node of
+// its own. The instances are found by evaluating the meta-argument with the
+// given variable values (see Evaluator).
+//
+// If the meta-argument can't be evaluated statically (because it depends on
+// something that is only known once the module is applied), the node is kept
+// and drawn dashed, and its label says its instances are unknown.
+func (m *module) ExpandedGraph(inputs InputValues) (*Graph, error) {
+	graph, err := m.Graph()
+	if err != nil {
+		return nil, err
+	}
+	evaluator, err := m.Evaluator(inputs)
+	if err != nil {
+		return nil, err
+	}
+
+	for addr, block := range m.cfg.blocks {
+		iteration := blockIteration(block)
+		if len(iteration) == 0 {
+			continue
+		}
+		name := addr.String()
+		content, _, _ := block.Body.PartialContent(iterationSchema)
+		keys, err := instanceKeys(iteration, content.Attributes[iteration].Expr, evaluator.ctx)
+		if err != nil {
+			m.Debugf("could not expand %s: %v", name, err)
+			graph.SetNodeAttribute(name, "style", "dashed")
+			graph.SetNodeAttribute(name, "xlabel", iteration+": instances unknown")
+			continue
+		}
+		if len(keys) == 0 {
+			graph.SetNodeAttribute(name, "style", "dashed")
+			graph.SetNodeAttribute(name, "xlabel", iteration+": no instances")
+			continue
+		}
+		instances := make([]string, len(keys))
+		for i, key := range keys {
+			instances[i] = name + indexLeft + key + indexRight
+		}
+		graph.expand(name, instances)
+	}
+	return graph, nil
+}
+
+// instanceKeys evaluates the given count or for_each expression, and returns
+// the index of each instance it makes, as it appears in the instance's
+// address: a number for count, or a quoted string for for_each. The keys are
+// sorted.
+func instanceKeys(iteration string, expr hcl.Expression, ctx *hcl.EvalContext) ([]string, error) {
+	value, diags := expr.Value(ctx)
+	if diags.HasErrors() {
+		return nil, diags
+	}
+	if !value.IsWhollyKnown() {
+		return nil, fmt.Errorf("%s is not known until the module is applied", iteration)
+	}
+	if value.IsNull() {
+		return nil, fmt.Errorf("%s is null", iteration)
+	}
+
+	keys := make([]string, 0)
+	if iteration == "count" {
+		if value.Type() != cty.Number {
+			return nil, fmt.Errorf("count must be a number, not %s", value.Type().FriendlyName())
+		}
+		count, accuracy := value.AsBigFloat().Int64()
+		if accuracy != big.Exact || count < 0 {
+			return nil, fmt.Errorf("count must be a whole number, not %s", value.AsBigFloat().Text('f', -1))
+		}
+		for i := int64(0); i < count; i++ {
+			keys = append(keys, fmt.Sprintf("%d", i))
+		}
+		return keys, nil
+	}
+
+	ty := value.Type()
+	switch {
+	case ty.IsSetType():
+		if !ty.ElementType().Equals(cty.String) {
+			return nil, fmt.Errorf("for_each must be a map, or a set of strings, not %s", ty.FriendlyName())
+		}
+		for it := value.ElementIterator(); it.Next(); {
+			_, element := it.Element()
+			keys = append(keys, fmt.Sprintf("%q", element.AsString()))
+		}
+	case ty.IsMapType() || ty.IsObjectType():
+		for it := value.ElementIterator(); it.Next(); {
+			key, _ := it.Element()
+			keys = append(keys, fmt.Sprintf("%q", key.AsString()))
+		}
+	default:
+		return nil, fmt.Errorf("for_each must be a map, or a set of strings, not %s", ty.FriendlyName())
+	}
+	sort.Strings(keys)
+	return keys, nil
+}
+
+// expand replaces the given node with the given instances of it. Each
+// instance has the node's dependencies, and everything that depended on the
+// node depends on every instance instead.
+func (g *Graph) expand(name string, instances []string) {
+	deps := g.dependencies[name]
+	for _, instance := range instances {
+		g.AddNode(instance, append([]string{}, deps...))
+		for _, dep := range deps {
+			for _, ref := range g.references[name][dep] {
+				g.addReference(instance, dep, ref)
+			}
+		}
+		for k, v := range g.attributes[name] {
+			g.SetNodeAttribute(instance, k, v)
+		}
+	}
+
+	for _, other := range g.Dependents(name) {
+		otherDeps := setSubtract(g.dependencies[other], []string{name})
+		g.AddNode(other, append(otherDeps, instances...))
+		for _, instance := range instances {
+			for _, ref := range g.references[other][name] {
+				g.addReference(other, instance, ref)
+			}
+		}
+		delete(g.references[other], name)
+	}
+
+	g.removeNode(name)
+	g.instances[name] = instances
+}
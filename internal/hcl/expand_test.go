@@ -0,0 +1,99 @@
+package hcl
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+)
+
+func TestExpandedGraph(t *testing.T) {
+	type test struct {
+		fixture       string
+		flag          string
+		expectedNodes []string
+		expectedAttrs map[string]string
+	}
+	tests := []test{
+		{
+			fixture:       "mapped-resource",
+			flag:          `keys=["b", "a", "b"]`,
+			expectedNodes: []string{`random_string.this["a"]`, `random_string.this["b"]`, "var.keys"},
+		},
+		{
+			fixture:       "listed-resource",
+			flag:          "qty=2",
+			expectedNodes: []string{"random_string.this[0]", "random_string.this[1]", "var.qty"},
+		},
+		{
+			fixture:       "listed-resource",
+			flag:          "qty=0",
+			expectedNodes: []string{"random_string.this", "var.qty"},
+			expectedAttrs: map[string]string{"xlabel": "count: no instances", "style": "dashed"},
+		},
+		{
+			fixture:       "listed-resource",
+			expectedNodes: []string{"random_string.this", "var.qty"},
+			expectedAttrs: map[string]string{"xlabel": "count: instances unknown", "style": "dashed"},
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.fixture+" "+tc.flag, func(t *testing.T) {
+			parser := NewModule(logrus.StandardLogger())
+			if err := parser.ParseModuleDirectory("../../fixtures/roots/" + tc.fixture); err != nil {
+				t.Fatal(err)
+			}
+			inputs := make(InputValues)
+			if len(tc.flag) > 0 {
+				values, err := ParseVariableFlag(tc.flag)
+				if err != nil {
+					t.Fatal(err)
+				}
+				inputs.Merge(values)
+			}
+
+			graph, err := parser.ExpandedGraph(inputs)
+			if err != nil {
+				t.Fatal(err)
+			}
+			nodes := make([]string, 0)
+			for _, name := range graph.Nodes() {
+				if nodeKind(name) != kindProvider {
+					nodes = append(nodes, name)
+				}
+			}
+			if strings.Join(nodes, ", ") != strings.Join(tc.expectedNodes, ", ") {
+				t.Errorf("Expected nodes %v, got %v", tc.expectedNodes, nodes)
+			}
+			for _, name := range nodes {
+				if strings.HasPrefix(name, "random_string.this") && !contains(graph.Dependencies(name), nodes[len(nodes)-1]) {
+					t.Errorf("Expected %s to depend on %s, got %v", name, nodes[len(nodes)-1], graph.Dependencies(name))
+				}
+			}
+			for k, v := range tc.expectedAttrs {
+				if actual := graph.attributes["random_string.this"][k]; actual != v {
+					t.Errorf("Expected %s to be %q, got %q", k, v, actual)
+				}
+			}
+		})
+	}
+}
+
+func TestExpandDependents(t *testing.T) {
+	graph := newGraph()
+	graph.AddNode("var.qty", []string{})
+	graph.AddNode("aws_instance.web", []string{"var.qty"})
+	graph.AddNode("output.ips", []string{"aws_instance.web"})
+
+	graph.expand("aws_instance.web", []string{"aws_instance.web[0]", "aws_instance.web[1]"})
+
+	if graph.Has("aws_instance.web") {
+		t.Error("Expected aws_instance.web to be replaced by its instances")
+	}
+	if deps := strings.Join(graph.Dependencies("output.ips"), ", "); deps != "aws_instance.web[0], aws_instance.web[1]" {
+		t.Errorf("Expected output.ips to depend on every instance, got %s", deps)
+	}
+	if deps := strings.Join(graph.Dependencies("aws_instance.web[1]"), ", "); deps != "var.qty" {
+		t.Errorf("Expected aws_instance.web[1] to depend on var.qty, got %s", deps)
+	}
+}
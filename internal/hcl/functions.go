@@ -0,0 +1,555 @@
+package hcl
+
+import (
+	"crypto/md5"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"math/big"
+	"net"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/hashicorp/hcl/v2/ext/tryfunc"
+	"github.com/zclconf/go-cty/cty"
+	"github.com/zclconf/go-cty/cty/convert"
+	"github.com/zclconf/go-cty/cty/function"
+	"github.com/zclconf/go-cty/cty/function/stdlib"
+	"github.com/zclconf/go-cty/cty/gocty"
+)
+
+// functions returns the Terraform functions that can be evaluated without
+// reading files or talking to providers, keyed by name.
+func functions() map[string]function.Function {
+	return map[string]function.Function{
+		"abs":             stdlib.AbsoluteFunc,
+		"alltrue":         allTrueFunc,
+		"anytrue":         anyTrueFunc,
+		"base64decode":    base64DecodeFunc,
+		"base64encode":    base64EncodeFunc,
+		"can":             tryfunc.CanFunc,
+		"ceil":            stdlib.CeilFunc,
+		"chomp":           stdlib.ChompFunc,
+		"chunklist":       stdlib.ChunklistFunc,
+		"cidrhost":        cidrHostFunc,
+		"cidrnetmask":     cidrNetmaskFunc,
+		"cidrsubnet":      cidrSubnetFunc,
+		"cidrsubnets":     cidrSubnetsFunc,
+		"coalesce":        stdlib.CoalesceFunc,
+		"coalescelist":    stdlib.CoalesceListFunc,
+		"compact":         stdlib.CompactFunc,
+		"concat":          stdlib.ConcatFunc,
+		"contains":        stdlib.ContainsFunc,
+		"csvdecode":       stdlib.CSVDecodeFunc,
+		"distinct":        stdlib.DistinctFunc,
+		"element":         stdlib.ElementFunc,
+		"endswith":        endsWithFunc,
+		"flatten":         stdlib.FlattenFunc,
+		"floor":           stdlib.FloorFunc,
+		"format":          stdlib.FormatFunc,
+		"formatdate":      stdlib.FormatDateFunc,
+		"formatlist":      stdlib.FormatListFunc,
+		"indent":          stdlib.IndentFunc,
+		"index":           stdlib.IndexFunc,
+		"join":            stdlib.JoinFunc,
+		"jsondecode":      stdlib.JSONDecodeFunc,
+		"jsonencode":      stdlib.JSONEncodeFunc,
+		"keys":            stdlib.KeysFunc,
+		"length":          stdlib.LengthFunc,
+		"log":             stdlib.LogFunc,
+		"lookup":          stdlib.LookupFunc,
+		"lower":           stdlib.LowerFunc,
+		"matchkeys":       matchKeysFunc,
+		"max":             stdlib.MaxFunc,
+		"md5":             makeHashFunc(md5.New),
+		"merge":           stdlib.MergeFunc,
+		"min":             stdlib.MinFunc,
+		"nonsensitive":    sensitivityFunc,
+		"one":             oneFunc,
+		"parseint":        stdlib.ParseIntFunc,
+		"pow":             stdlib.PowFunc,
+		"range":           stdlib.RangeFunc,
+		"regex":           stdlib.RegexFunc,
+		"regexall":        stdlib.RegexAllFunc,
+		"replace":         stdlib.ReplaceFunc,
+		"reverse":         stdlib.ReverseListFunc,
+		"sensitive":       sensitivityFunc,
+		"setintersection": stdlib.SetIntersectionFunc,
+		"setproduct":      stdlib.SetProductFunc,
+		"setsubtract":     stdlib.SetSubtractFunc,
+		"setunion":        stdlib.SetUnionFunc,
+		"sha1":            makeHashFunc(sha1.New),
+		"sha256":          makeHashFunc(sha256.New),
+		"sha512":          makeHashFunc(sha512.New),
+		"signum":          stdlib.SignumFunc,
+		"slice":           stdlib.SliceFunc,
+		"sort":            stdlib.SortFunc,
+		"split":           stdlib.SplitFunc,
+		"startswith":      startsWithFunc,
+		"strrev":          stdlib.ReverseFunc,
+		"substr":          stdlib.SubstrFunc,
+		"sum":             sumFunc,
+		"timeadd":         stdlib.TimeAddFunc,
+		"title":           stdlib.TitleFunc,
+		"tobool":          stdlib.MakeToFunc(cty.Bool),
+		"tolist":          stdlib.MakeToFunc(cty.List(cty.DynamicPseudoType)),
+		"tomap":           stdlib.MakeToFunc(cty.Map(cty.DynamicPseudoType)),
+		"tonumber":        stdlib.MakeToFunc(cty.Number),
+		"toset":           stdlib.MakeToFunc(cty.Set(cty.DynamicPseudoType)),
+		"tostring":        stdlib.MakeToFunc(cty.String),
+		"transpose":       transposeFunc,
+		"trim":            stdlib.TrimFunc,
+		"trimprefix":      stdlib.TrimPrefixFunc,
+		"trimspace":       stdlib.TrimSpaceFunc,
+		"trimsuffix":      stdlib.TrimSuffixFunc,
+		"try":             tryfunc.TryFunc,
+		"upper":           stdlib.UpperFunc,
+		"values":          stdlib.ValuesFunc,
+		"zipmap":          stdlib.ZipmapFunc,
+	}
+}
+
+// cidrHostFunc calculates a full host IP address within a given IP network
+// address prefix. A negative host number counts back from the end of the
+// prefix.
+var cidrHostFunc = function.New(&function.Spec{
+	Params: []function.Parameter{
+		{Name: "prefix", Type: cty.String},
+		{Name: "hostnum", Type: cty.Number},
+	},
+	Type:         function.StaticReturnType(cty.String),
+	RefineResult: refineNotNull,
+	Impl: func(args []cty.Value, retType cty.Type) (cty.Value, error) {
+		network, err := parsePrefix(args[0])
+		if err != nil {
+			return cty.UnknownVal(cty.String), function.NewArgError(0, err)
+		}
+		var hostnum int64
+		if err := gocty.FromCtyValue(args[1], &hostnum); err != nil {
+			return cty.UnknownVal(cty.String), function.NewArgError(1, err)
+		}
+
+		size := new(big.Int).Lsh(big.NewInt(1), uint(network.bits-network.length))
+		host := big.NewInt(hostnum)
+		if hostnum < 0 {
+			host.Add(host, size)
+		}
+		if host.Sign() < 0 || host.Cmp(size) >= 0 {
+			return cty.UnknownVal(cty.String), function.NewArgErrorf(1, "prefix of %d bits cannot accommodate host number %d", network.length, hostnum)
+		}
+		return cty.StringVal(intToIP(host.Add(host, network.start), network.bits).String()), nil
+	},
+})
+
+// cidrNetmaskFunc converts an IPv4 address prefix given in CIDR notation into
+// a subnet mask address.
+var cidrNetmaskFunc = function.New(&function.Spec{
+	Params: []function.Parameter{
+		{Name: "prefix", Type: cty.String},
+	},
+	Type:         function.StaticReturnType(cty.String),
+	RefineResult: refineNotNull,
+	Impl: func(args []cty.Value, retType cty.Type) (cty.Value, error) {
+		network, err := parsePrefix(args[0])
+		if err != nil {
+			return cty.UnknownVal(cty.String), function.NewArgError(0, err)
+		}
+		if network.bits != 32 {
+			return cty.UnknownVal(cty.String), function.NewArgErrorf(0, "IPv6 addresses cannot have a netmask: %s", args[0].AsString())
+		}
+		return cty.StringVal(net.IP(net.CIDRMask(network.length, network.bits)).String()), nil
+	},
+})
+
+// cidrSubnetFunc calculates a subnet address within a given IP network
+// address prefix.
+var cidrSubnetFunc = function.New(&function.Spec{
+	Params: []function.Parameter{
+		{Name: "prefix", Type: cty.String},
+		{Name: "newbits", Type: cty.Number},
+		{Name: "netnum", Type: cty.Number},
+	},
+	Type:         function.StaticReturnType(cty.String),
+	RefineResult: refineNotNull,
+	Impl: func(args []cty.Value, retType cty.Type) (cty.Value, error) {
+		network, err := parsePrefix(args[0])
+		if err != nil {
+			return cty.UnknownVal(cty.String), function.NewArgError(0, err)
+		}
+		var newbits, netnum int64
+		if err := gocty.FromCtyValue(args[1], &newbits); err != nil {
+			return cty.UnknownVal(cty.String), function.NewArgError(1, err)
+		}
+		if err := gocty.FromCtyValue(args[2], &netnum); err != nil {
+			return cty.UnknownVal(cty.String), function.NewArgError(2, err)
+		}
+
+		length := network.length + int(newbits)
+		if newbits < 0 || length > network.bits {
+			return cty.UnknownVal(cty.String), function.NewArgErrorf(1, "insufficient address space to extend prefix of %d by %d", network.length, newbits)
+		}
+		max := new(big.Int).Lsh(big.NewInt(1), uint(newbits))
+		if netnum < 0 || big.NewInt(netnum).Cmp(max) >= 0 {
+			return cty.UnknownVal(cty.String), function.NewArgErrorf(2, "prefix extension of %d does not accommodate a subnet numbered %d", newbits, netnum)
+		}
+		start := new(big.Int).Lsh(big.NewInt(netnum), uint(network.bits-length))
+		start.Add(start, network.start)
+		return cty.StringVal(fmt.Sprintf("%s/%d", intToIP(start, network.bits), length)), nil
+	},
+})
+
+// cidrSubnetsFunc calculates a sequence of consecutive subnet prefixes within
+// a given IP network address prefix, each extending it by a number of bits.
+var cidrSubnetsFunc = function.New(&function.Spec{
+	Params: []function.Parameter{
+		{Name: "prefix", Type: cty.String},
+	},
+	VarParam:     &function.Parameter{Name: "newbits", Type: cty.Number},
+	Type:         function.StaticReturnType(cty.List(cty.String)),
+	RefineResult: refineNotNull,
+	Impl: func(args []cty.Value, retType cty.Type) (cty.Value, error) {
+		network, err := parsePrefix(args[0])
+		if err != nil {
+			return cty.UnknownVal(cty.List(cty.String)), function.NewArgError(0, err)
+		}
+		if len(args) == 1 {
+			return cty.ListValEmpty(cty.String), nil
+		}
+
+		end := new(big.Int).Lsh(big.NewInt(1), uint(network.bits-network.length))
+		end.Add(end, network.start)
+		// each subnet starts at the first address after the previous one
+		// that is aligned to the subnet's size
+		next := new(big.Int).Set(network.start)
+		subnets := make([]cty.Value, 0, len(args)-1)
+		for i, arg := range args[1:] {
+			var newbits int64
+			if err := gocty.FromCtyValue(arg, &newbits); err != nil {
+				return cty.UnknownVal(cty.List(cty.String)), function.NewArgError(i+1, err)
+			}
+			length := network.length + int(newbits)
+			if newbits < 1 || length > network.bits {
+				return cty.UnknownVal(cty.List(cty.String)), function.NewArgErrorf(i+1, "would extend prefix to %d bits, which is too long for this address family", length)
+			}
+			size := new(big.Int).Lsh(big.NewInt(1), uint(network.bits-length))
+			start := new(big.Int).Add(next, new(big.Int).Sub(size, big.NewInt(1)))
+			start.Div(start, size).Mul(start, size)
+			next = new(big.Int).Add(start, size)
+			if next.Cmp(end) > 0 {
+				return cty.UnknownVal(cty.List(cty.String)), function.NewArgErrorf(i+1, "not enough remaining address space for a subnet with a prefix of %d bits after %s", length, subnets[len(subnets)-1].AsString())
+			}
+			subnets = append(subnets, cty.StringVal(fmt.Sprintf("%s/%d", intToIP(start, network.bits), length)))
+		}
+		return cty.ListVal(subnets), nil
+	},
+})
+
+// oneFunc returns the only element of a list, set or tuple, or null if it is
+// empty.
+var oneFunc = function.New(&function.Spec{
+	Params: []function.Parameter{
+		{Name: "list", Type: cty.DynamicPseudoType},
+	},
+	Type: func(args []cty.Value) (cty.Type, error) {
+		ty := args[0].Type()
+		switch {
+		case ty == cty.DynamicPseudoType:
+			return cty.DynamicPseudoType, nil
+		case ty.IsListType() || ty.IsSetType():
+			return ty.ElementType(), nil
+		case ty.IsTupleType():
+			switch len(ty.TupleElementTypes()) {
+			case 0:
+				return cty.DynamicPseudoType, nil
+			case 1:
+				return ty.TupleElementType(0), nil
+			}
+			return cty.NilType, function.NewArgErrorf(0, "must be a list, set, or tuple value with either zero or one elements")
+		}
+		return cty.NilType, function.NewArgErrorf(0, "must be a list, set, or tuple value with either zero or one elements")
+	},
+	Impl: func(args []cty.Value, retType cty.Type) (cty.Value, error) {
+		list := args[0]
+		if !list.IsKnown() {
+			return cty.UnknownVal(retType), nil
+		}
+		switch list.LengthInt() {
+		case 0:
+			return cty.NullVal(retType), nil
+		case 1:
+			return list.AsValueSlice()[0], nil
+		}
+		return cty.NilVal, function.NewArgErrorf(0, "must be a list, set, or tuple value with either zero or one elements")
+	},
+})
+
+// sumFunc returns the total of a list, set or tuple of numbers.
+var sumFunc = function.New(&function.Spec{
+	Params: []function.Parameter{
+		{Name: "list", Type: cty.DynamicPseudoType},
+	},
+	Type:         function.StaticReturnType(cty.Number),
+	RefineResult: refineNotNull,
+	Impl: func(args []cty.Value, retType cty.Type) (cty.Value, error) {
+		list := args[0]
+		ty := list.Type()
+		if !ty.IsListType() && !ty.IsSetType() && !ty.IsTupleType() {
+			return cty.NilVal, function.NewArgErrorf(0, "argument must be list, set, or tuple. Received %s", ty.FriendlyName())
+		}
+		if !list.IsWhollyKnown() {
+			return cty.UnknownVal(cty.Number), nil
+		}
+		if list.LengthInt() == 0 {
+			return cty.NilVal, function.NewArgErrorf(0, "cannot sum an empty list")
+		}
+		total := cty.Zero
+		for _, element := range list.AsValueSlice() {
+			number, err := convert.Convert(element, cty.Number)
+			if err != nil || number.IsNull() {
+				return cty.NilVal, function.NewArgErrorf(0, "argument must be a list of numbers")
+			}
+			total = total.Add(number)
+		}
+		return total, nil
+	},
+})
+
+// allTrueFunc returns whether every element of a list of bools is true.
+var allTrueFunc = function.New(&function.Spec{
+	Params: []function.Parameter{
+		{Name: "list", Type: cty.List(cty.Bool)},
+	},
+	Type:         function.StaticReturnType(cty.Bool),
+	RefineResult: refineNotNull,
+	Impl: func(args []cty.Value, retType cty.Type) (cty.Value, error) {
+		result := cty.True
+		for _, element := range args[0].AsValueSlice() {
+			if !element.IsKnown() {
+				result = cty.UnknownVal(cty.Bool)
+				continue
+			}
+			if element.IsNull() || element.False() {
+				return cty.False, nil
+			}
+		}
+		return result, nil
+	},
+})
+
+// anyTrueFunc returns whether any element of a list of bools is true.
+var anyTrueFunc = function.New(&function.Spec{
+	Params: []function.Parameter{
+		{Name: "list", Type: cty.List(cty.Bool)},
+	},
+	Type:         function.StaticReturnType(cty.Bool),
+	RefineResult: refineNotNull,
+	Impl: func(args []cty.Value, retType cty.Type) (cty.Value, error) {
+		result := cty.False
+		for _, element := range args[0].AsValueSlice() {
+			if !element.IsKnown() {
+				result = cty.UnknownVal(cty.Bool)
+				continue
+			}
+			if !element.IsNull() && element.True() {
+				return cty.True, nil
+			}
+		}
+		return result, nil
+	},
+})
+
+// startsWithFunc returns whether a string starts with a prefix.
+var startsWithFunc = function.New(&function.Spec{
+	Params: []function.Parameter{
+		{Name: "str", Type: cty.String},
+		{Name: "prefix", Type: cty.String},
+	},
+	Type:         function.StaticReturnType(cty.Bool),
+	RefineResult: refineNotNull,
+	Impl: func(args []cty.Value, retType cty.Type) (cty.Value, error) {
+		return cty.BoolVal(strings.HasPrefix(args[0].AsString(), args[1].AsString())), nil
+	},
+})
+
+// endsWithFunc returns whether a string ends with a suffix.
+var endsWithFunc = function.New(&function.Spec{
+	Params: []function.Parameter{
+		{Name: "str", Type: cty.String},
+		{Name: "suffix", Type: cty.String},
+	},
+	Type:         function.StaticReturnType(cty.Bool),
+	RefineResult: refineNotNull,
+	Impl: func(args []cty.Value, retType cty.Type) (cty.Value, error) {
+		return cty.BoolVal(strings.HasSuffix(args[0].AsString(), args[1].AsString())), nil
+	},
+})
+
+// base64EncodeFunc encodes a string with Base64.
+var base64EncodeFunc = function.New(&function.Spec{
+	Params: []function.Parameter{
+		{Name: "str", Type: cty.String},
+	},
+	Type:         function.StaticReturnType(cty.String),
+	RefineResult: refineNotNull,
+	Impl: func(args []cty.Value, retType cty.Type) (cty.Value, error) {
+		return cty.StringVal(base64.StdEncoding.EncodeToString([]byte(args[0].AsString()))), nil
+	},
+})
+
+// base64DecodeFunc decodes a Base64 string, which must encode UTF-8 text.
+var base64DecodeFunc = function.New(&function.Spec{
+	Params: []function.Parameter{
+		{Name: "str", Type: cty.String},
+	},
+	Type:         function.StaticReturnType(cty.String),
+	RefineResult: refineNotNull,
+	Impl: func(args []cty.Value, retType cty.Type) (cty.Value, error) {
+		decoded, err := base64.StdEncoding.DecodeString(args[0].AsString())
+		if err != nil {
+			return cty.UnknownVal(cty.String), function.NewArgErrorf(0, "failed to decode base64 data %q", args[0].AsString())
+		}
+		if !utf8.Valid(decoded) {
+			return cty.UnknownVal(cty.String), function.NewArgErrorf(0, "the result of decoding the provided string is not valid UTF-8")
+		}
+		return cty.StringVal(string(decoded)), nil
+	},
+})
+
+// makeHashFunc returns a function that hashes a string with the given hash,
+// and returns it in hexadecimal.
+func makeHashFunc(newHash func() hash.Hash) function.Function {
+	return function.New(&function.Spec{
+		Params: []function.Parameter{
+			{Name: "str", Type: cty.String},
+		},
+		Type:         function.StaticReturnType(cty.String),
+		RefineResult: refineNotNull,
+		Impl: func(args []cty.Value, retType cty.Type) (cty.Value, error) {
+			h := newHash()
+			h.Write([]byte(args[0].AsString()))
+			return cty.StringVal(hex.EncodeToString(h.Sum(nil))), nil
+		},
+	})
+}
+
+// sensitivityFunc returns its argument. It stands in for sensitive and
+// nonsensitive, since the evaluator doesn't track which values are
+// sensitive.
+var sensitivityFunc = function.New(&function.Spec{
+	Params: []function.Parameter{
+		{Name: "value", Type: cty.DynamicPseudoType, AllowNull: true, AllowUnknown: true, AllowDynamicType: true},
+	},
+	Type: func(args []cty.Value) (cty.Type, error) {
+		return args[0].Type(), nil
+	},
+	Impl: func(args []cty.Value, retType cty.Type) (cty.Value, error) {
+		return args[0], nil
+	},
+})
+
+// transposeFunc swaps the keys and values of a map of lists of strings.
+var transposeFunc = function.New(&function.Spec{
+	Params: []function.Parameter{
+		{Name: "values", Type: cty.Map(cty.List(cty.String))},
+	},
+	Type:         function.StaticReturnType(cty.Map(cty.List(cty.String))),
+	RefineResult: refineNotNull,
+	Impl: func(args []cty.Value, retType cty.Type) (cty.Value, error) {
+		if !args[0].IsWhollyKnown() {
+			return cty.UnknownVal(retType), nil
+		}
+		transposed := make(map[string][]cty.Value)
+		for it := args[0].ElementIterator(); it.Next(); {
+			key, list := it.Element()
+			if list.IsNull() {
+				return cty.NilVal, function.NewArgErrorf(0, "lists must not be null")
+			}
+			for _, value := range list.AsValueSlice() {
+				if value.IsNull() {
+					return cty.NilVal, function.NewArgErrorf(0, "lists must not contain null values")
+				}
+				transposed[value.AsString()] = append(transposed[value.AsString()], key)
+			}
+		}
+		if len(transposed) == 0 {
+			return cty.MapValEmpty(cty.List(cty.String)), nil
+		}
+		result := make(map[string]cty.Value, len(transposed))
+		for key, values := range transposed {
+			result[key] = cty.ListVal(values)
+		}
+		return cty.MapVal(result), nil
+	},
+})
+
+// matchKeysFunc returns the elements of a list of values whose corresponding
+// elements in a list of keys are in a search set.
+var matchKeysFunc = function.New(&function.Spec{
+	Params: []function.Parameter{
+		{Name: "values", Type: cty.List(cty.DynamicPseudoType)},
+		{Name: "keys", Type: cty.List(cty.DynamicPseudoType)},
+		{Name: "searchset", Type: cty.List(cty.DynamicPseudoType)},
+	},
+	Type: func(args []cty.Value) (cty.Type, error) {
+		return args[0].Type(), nil
+	},
+	Impl: func(args []cty.Value, retType cty.Type) (cty.Value, error) {
+		values, keys, searchset := args[0], args[1], args[2]
+		if values.LengthInt() != keys.LengthInt() {
+			return cty.NilVal, function.NewArgErrorf(1, "length of keys and values should be equal")
+		}
+		if !keys.IsWhollyKnown() || !searchset.IsWhollyKnown() {
+			return cty.UnknownVal(retType), nil
+		}
+		matches := make([]cty.Value, 0)
+		searches := searchset.AsValueSlice()
+		for i, key := range keys.AsValueSlice() {
+			for _, search := range searches {
+				if key.Type().Equals(search.Type()) && key.Equals(search).True() {
+					matches = append(matches, values.Index(cty.NumberIntVal(int64(i))))
+					break
+				}
+			}
+		}
+		if len(matches) == 0 {
+			return cty.ListValEmpty(retType.ElementType()), nil
+		}
+		return cty.ListVal(matches), nil
+	},
+})
+
+func refineNotNull(b *cty.RefinementBuilder) *cty.RefinementBuilder {
+	return b.NotNull()
+}
+
+// ipNetwork is an IP network address prefix, with its first address as an
+// integer so that addresses within it can be calculated.
+type ipNetwork struct {
+	start *big.Int
+	// length is the length of the prefix, and bits the length of an address,
+	// in bits.
+	length int
+	bits   int
+}
+
+func parsePrefix(value cty.Value) (*ipNetwork, error) {
+	_, network, err := net.ParseCIDR(value.AsString())
+	if err != nil {
+		return nil, fmt.Errorf("invalid CIDR expression: %s", err)
+	}
+	length, bits := network.Mask.Size()
+	ip := network.IP
+	if bits == 32 {
+		ip = ip.To4()
+	}
+	return &ipNetwork{start: new(big.Int).SetBytes(ip), length: length, bits: bits}, nil
+}
+
+func intToIP(n *big.Int, bits int) net.IP {
+	ip := make(net.IP, bits/8)
+	return n.FillBytes(ip)
+}
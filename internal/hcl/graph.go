@@ -0,0 +1,317 @@
+package hcl
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/awalterschulze/gographviz"
+	"github.com/hashicorp/hcl/v2"
+)
+
+// Graph is a directed graph of the objects declared in a module. Each node is
+// keyed by its address (e.g. "var.qty" or "random_string.this"), and knows the
+// addresses of the nodes it depends on.
+type Graph struct {
+	dependencies map[string][]string
+	attributes   map[string]map[string]string
+	// map from node to dependency to the references that make up the edge
+	references map[string]map[string][]*Reference
+	infos      map[string]*NodeInfo
+	// map from node to the nodes that depend on it, the reverse of
+	// dependencies
+	dependents map[string]map[string]bool
+	// map from an expanded node to the instance nodes that replaced it
+	instances map[string][]string
+}
+
+// NodeInfo holds what we know about a node, beyond its dependencies.
+type NodeInfo struct {
+	// Iteration is the meta-argument that repeats the node ("count" or
+	// "for_each"), or empty if the node isn't repeated.
+	Iteration string
+	// IteratesOver are the nodes that the node's count or for_each
+	// expression depends on.
+	IteratesOver []string
+	// Builtins are the references the node makes to things Terraform
+	// provides, e.g. "count.index", "path.module" or "terraform.workspace".
+	Builtins []string
+}
+
+// Reference is a single place in a node's configuration that refers to
+// another node.
+type Reference struct {
+	// Address is the full address being referred to, e.g.
+	// "random_string.slug.result".
+	Address string
+	// Kind is the kind of thing being referred to, e.g. "variable",
+	// "resource" or (for built-in references) "count" or "path".
+	Kind string
+	// Attribute is the path of the attribute (within the referring block)
+	// whose expression holds the reference, e.g. "count" or
+	// "ingress.cidr_blocks". It is empty for locals.
+	Attribute string
+	// Range is the location of the reference in the source.
+	Range hcl.Range
+
+	traversal hcl.Traversal
+}
+
+func newGraph() *Graph {
+	return &Graph{
+		dependencies: make(map[string][]string),
+		dependents:   make(map[string]map[string]bool),
+		attributes:   make(map[string]map[string]string),
+		references:   make(map[string]map[string][]*Reference),
+		infos:        make(map[string]*NodeInfo),
+		instances:    make(map[string][]string),
+	}
+}
+
+// Info returns what the receiver knows about the given node, beyond its
+// dependencies.
+func (g *Graph) Info(name string) *NodeInfo {
+	if info, ok := g.infos[name]; ok {
+		return info
+	}
+	return &NodeInfo{}
+}
+
+func (g *Graph) info(name string) *NodeInfo {
+	if _, ok := g.infos[name]; !ok {
+		g.infos[name] = &NodeInfo{}
+	}
+	return g.infos[name]
+}
+
+// AddNode adds a node with the given dependencies to the receiver. If the
+// node already exists, its dependencies are replaced.
+func (g *Graph) AddNode(name string, deps []string) {
+	for _, dep := range g.dependencies[name] {
+		delete(g.dependents[dep], name)
+	}
+	g.dependencies[name] = deps
+	for _, dep := range deps {
+		if _, ok := g.dependents[dep]; !ok {
+			g.dependents[dep] = make(map[string]bool)
+		}
+		g.dependents[dep][name] = true
+	}
+}
+
+// removeNode removes the given node from the receiver, along with everything
+// recorded about it. Nodes that depend on it keep it as a dependency.
+func (g *Graph) removeNode(name string) {
+	for _, dep := range g.dependencies[name] {
+		delete(g.dependents[dep], name)
+	}
+	delete(g.dependencies, name)
+	delete(g.references, name)
+	delete(g.attributes, name)
+	delete(g.infos, name)
+}
+
+// addReference records a reference that makes up the edge between a node and
+// one of its dependencies.
+func (g *Graph) addReference(name, dep string, ref *Reference) {
+	if _, ok := g.references[name]; !ok {
+		g.references[name] = make(map[string][]*Reference)
+	}
+	g.references[name][dep] = append(g.references[name][dep], ref)
+}
+
+// References returns the references in the given node's configuration that
+// refer to the given dependency.
+func (g *Graph) References(name, dep string) []*Reference {
+	return g.references[name][dep]
+}
+
+// Has returns whether the receiver contains a node with the given name.
+func (g *Graph) Has(name string) bool {
+	_, ok := g.dependencies[name]
+	return ok
+}
+
+// Nodes returns the names of all the receiver's nodes, sorted.
+func (g *Graph) Nodes() []string {
+	names := make([]string, 0, len(g.dependencies))
+	for name := range g.dependencies {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Dependencies returns the names of the nodes that the given node depends on.
+func (g *Graph) Dependencies(name string) []string {
+	return g.dependencies[name]
+}
+
+// Dependents returns the names of the nodes that depend on the given node,
+// sorted.
+func (g *Graph) Dependents(name string) []string {
+	dependents := make([]string, 0, len(g.dependents[name]))
+	for other := range g.dependents[name] {
+		dependents = append(dependents, other)
+	}
+	sort.Strings(dependents)
+	return dependents
+}
+
+// Subgraph returns a new graph containing only the focus nodes (see Match),
+// the nodes they depend on up to `up` steps away, and the nodes that depend on
+// them up to `down` steps away. A negative number of steps is unlimited.
+func (g *Graph) Subgraph(focus string, up, down int) (*Graph, error) {
+	focusNodes := g.Match(focus)
+	if len(focusNodes) == 0 {
+		return nil, fmt.Errorf("%s is not in the graph", focus)
+	}
+
+	keep := make(map[string]bool)
+	for _, node := range focusNodes {
+		keep[node] = true
+		for name := range g.walk(node, up, g.Dependencies) {
+			keep[name] = true
+		}
+		for name := range g.walk(node, down, g.Dependents) {
+			keep[name] = true
+		}
+	}
+
+	sub := newGraph()
+	for name := range keep {
+		deps := make([]string, 0)
+		for _, dep := range g.dependencies[name] {
+			if keep[dep] {
+				deps = append(deps, dep)
+			}
+		}
+		sub.AddNode(name, deps)
+		for _, dep := range deps {
+			for _, ref := range g.references[name][dep] {
+				sub.addReference(name, dep, ref)
+			}
+		}
+		for k, v := range g.attributes[name] {
+			sub.SetNodeAttribute(name, k, v)
+		}
+		if info, ok := g.infos[name]; ok {
+			sub.infos[name] = info
+		}
+	}
+	for name, instances := range g.instances {
+		for _, instance := range instances {
+			if keep[instance] {
+				sub.instances[name] = append(sub.instances[name], instance)
+			}
+		}
+	}
+	return sub, nil
+}
+
+// Match returns the nodes of the receiver that the given address refers to.
+// That's the node with the address, if there is one, or else the instance
+// nodes it was expanded into (see ExpandedGraph). An instance address, e.g.
+// `random_string.this["a"]`, falls back to the node of its resource or module
+// call if the receiver wasn't expanded.
+func (g *Graph) Match(address string) []string {
+	if g.Has(address) {
+		return []string{address}
+	}
+	if instances, ok := g.instances[address]; ok {
+		return append([]string{}, instances...)
+	}
+	if base := ParseAddress(address).Base().String(); base != address && g.Has(base) {
+		return []string{base}
+	}
+	return nil
+}
+
+// walk returns the set of nodes reachable from the given node (in the
+// direction given by next), up to the given number of steps.
+func (g *Graph) walk(from string, steps int, next func(string) []string) map[string]bool {
+	visited := map[string]bool{from: true}
+	frontier := []string{from}
+	for step := 0; len(frontier) > 0 && (steps < 0 || step < steps); step++ {
+		nextFrontier := make([]string, 0)
+		for _, name := range frontier {
+			for _, other := range next(name) {
+				if visited[other] {
+					continue
+				}
+				visited[other] = true
+				nextFrontier = append(nextFrontier, other)
+			}
+		}
+		frontier = nextFrontier
+	}
+	delete(visited, from)
+	return visited
+}
+
+// SetNodeAttribute sets a DOT attribute (e.g. "fillcolor") on the given node.
+func (g *Graph) SetNodeAttribute(name, key, value string) {
+	if _, ok := g.attributes[name]; !ok {
+		g.attributes[name] = make(map[string]string)
+	}
+	g.attributes[name][key] = value
+}
+
+// DOT returns a DOT-format representation of the receiver. Edges point from
+// a dependency to the node that depends on it.
+func (g *Graph) DOT() (string, error) {
+	graphAst, _ := gographviz.ParseString(`digraph G {}`)
+	dotGraph := gographviz.NewGraph()
+	if err := gographviz.Analyse(graphAst, dotGraph); err != nil {
+		return "", err
+	}
+	if err := dotGraph.SetDir(true); err != nil {
+		return "", err
+	}
+
+	nodes := g.Nodes()
+	for _, name := range nodes {
+		var attrs map[string]string
+		if nodeAttrs := g.dotAttributes(name); len(nodeAttrs) > 0 {
+			attrs = make(map[string]string, len(nodeAttrs))
+			for k, v := range nodeAttrs {
+				attrs[k] = quote(v)
+			}
+		}
+		if err := dotGraph.AddNode("G", quote(name), attrs); err != nil {
+			return "", err
+		}
+	}
+
+	for _, name := range nodes {
+		deps := append([]string{}, g.dependencies[name]...)
+		sort.Strings(deps)
+		for _, dep := range deps {
+			if err := dotGraph.AddEdge(quote(dep), quote(name), true, nil); err != nil {
+				return "", err
+			}
+		}
+	}
+
+	return dotGraph.String(), nil
+}
+
+// dotAttributes returns the DOT attributes of the given node: any set with
+// SetNodeAttribute, plus an external label noting how the node is repeated.
+func (g *Graph) dotAttributes(name string) map[string]string {
+	attrs := make(map[string]string, len(g.attributes[name])+1)
+	if info := g.Info(name); len(info.Iteration) > 0 {
+		attrs["xlabel"] = info.Iteration
+		if len(info.IteratesOver) > 0 {
+			attrs["xlabel"] += ": " + strings.Join(info.IteratesOver, ", ")
+		}
+	}
+	for k, v := range g.attributes[name] {
+		attrs[k] = v
+	}
+	return attrs
+}
+
+func quote(s string) string {
+	return fmt.Sprintf("%q", s)
+}
@@ -0,0 +1,166 @@
+package hcl
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+)
+
+func TestSubgraph(t *testing.T) {
+	type test struct {
+		focus         string
+		up            int
+		down          int
+		expectedNodes []string
+	}
+
+	tests := []test{
+		{
+			focus:         "var.vpc_cidr",
+			up:            -1,
+			down:          -1,
+			expectedNodes: []string{"aws_subnet.this", "aws_vpc.this", "output.subnet_ids", "output.vpc_id", "var.vpc_cidr"},
+		},
+		{
+			focus:         "var.vpc_cidr",
+			up:            -1,
+			down:          1,
+			expectedNodes: []string{"aws_subnet.this", "aws_vpc.this", "var.vpc_cidr"},
+		},
+		{
+			focus:         "aws_vpc.this",
+			up:            -1,
+			down:          0,
+			expectedNodes: []string{"aws_vpc.this", "local.name", "local.tags", "var.env", "var.vpc_cidr"},
+		},
+		{
+			focus:         "aws_vpc.this",
+			up:            1,
+			down:          0,
+			expectedNodes: []string{"aws_vpc.this", "local.tags", "var.vpc_cidr"},
+		},
+	}
+
+	parser := NewModule(logrus.StandardLogger())
+	if err := parser.ParseModuleDirectory("../../fixtures/roots/network"); err != nil {
+		t.Fatal(err)
+	}
+	graph, err := parser.Graph()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.focus, func(t *testing.T) {
+			sub, err := graph.Subgraph(tc.focus, tc.up, tc.down)
+			if err != nil {
+				t.Fatal(err)
+			}
+			actualNodes := strings.Join(sub.Nodes(), ", ")
+			expectedNodes := strings.Join(tc.expectedNodes, ", ")
+			if actualNodes != expectedNodes {
+				t.Errorf("Expected nodes %s, got %s", expectedNodes, actualNodes)
+			}
+		})
+	}
+
+	if _, err := graph.Subgraph("var.nope", -1, -1); err == nil {
+		t.Error("Expected an error when focusing on a node that isn't in the graph")
+	}
+}
+
+func TestDependents(t *testing.T) {
+	graph := newGraph()
+	graph.AddNode("local.a", []string{"var.x"})
+	graph.AddNode("local.b", []string{"var.x", "local.a"})
+	graph.AddNode("var.x", []string{})
+	// replacing a node's dependencies replaces it as a dependent too
+	graph.AddNode("local.a", []string{"var.y"})
+	graph.expand("local.b", []string{"local.b[0]", "local.b[1]"})
+
+	expected := map[string][]string{
+		"var.x":   {"local.b[0]", "local.b[1]"},
+		"var.y":   {"local.a"},
+		"local.a": {"local.b[0]", "local.b[1]"},
+		"local.b": {},
+	}
+	for name, expectedDependents := range expected {
+		actual := graph.Dependents(name)
+		if strings.Join(actual, ",") != strings.Join(expectedDependents, ",") {
+			t.Errorf("Expected the dependents of %s to be %v, got %v", name, expectedDependents, actual)
+		}
+	}
+}
+
+func TestImpact(t *testing.T) {
+	parser := NewModule(logrus.StandardLogger())
+	if err := parser.ParseModuleDirectory("../../fixtures/roots/network"); err != nil {
+		t.Fatal(err)
+	}
+	graph, err := parser.Graph()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	impacts, err := graph.Impact("var.vpc_cidr")
+	if err != nil {
+		t.Fatal(err)
+	}
+	actual := make([]string, len(impacts))
+	for i, impact := range impacts {
+		actual[i] = impact.Kind + " " + strings.Join(impact.Path, " -> ")
+	}
+	expected := []string{
+		"resource var.vpc_cidr -> aws_subnet.this.cidr_block",
+		"resource var.vpc_cidr -> aws_vpc.this.cidr_block",
+		"output var.vpc_cidr -> aws_subnet.this.cidr_block -> output.subnet_ids.value",
+		"output var.vpc_cidr -> aws_vpc.this.cidr_block -> output.vpc_id.value",
+	}
+	if strings.Join(actual, "\n") != strings.Join(expected, "\n") {
+		t.Errorf("Expected impacts:\n%s\nActual impacts:\n%s", strings.Join(expected, "\n"), strings.Join(actual, "\n"))
+	}
+}
+
+func TestCycles(t *testing.T) {
+	parser := NewModule(logrus.StandardLogger())
+	if err := parser.ParseModuleDirectory("../../fixtures/roots/cycle"); err != nil {
+		t.Fatal(err)
+	}
+	graph, err := parser.Graph()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cycles := graph.Cycles()
+	actual := make([]string, len(cycles))
+	for i, cycle := range cycles {
+		chain := make([]string, 0, len(cycle.Edges))
+		for _, edge := range cycle.Edges {
+			for _, ref := range edge.References {
+				chain = append(chain, fmt.Sprintf("%s->%s@%d", edge.From, ref.Address, ref.Range.Start.Line))
+			}
+		}
+		actual[i] = strings.Join(chain, " ")
+	}
+	expected := []string{
+		"local.a->local.c@2 local.c->local.b@4 local.b->local.a@3",
+		"random_string.first->random_string.second.special@9 random_string.second->random_string.first.result@16",
+	}
+	if strings.Join(actual, "\n") != strings.Join(expected, "\n") {
+		t.Errorf("Expected cycles:\n%s\nActual cycles:\n%s", strings.Join(expected, "\n"), strings.Join(actual, "\n"))
+	}
+
+	parser = NewModule(logrus.StandardLogger())
+	if err := parser.ParseModuleDirectory("../../fixtures/roots/network"); err != nil {
+		t.Fatal(err)
+	}
+	graph, err = parser.Graph()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cycles := graph.Cycles(); len(cycles) > 0 {
+		t.Errorf("Expected no cycles, got %d", len(cycles))
+	}
+}
@@ -1,3 +1,51 @@
 // Package hcl provides some helper functions for various
 // github.com/hashicorp/hcl/v2 packages.
 package hcl
+
+import "sort"
+
+// contains returns whether the given list has the given value.
+func contains[T comparable](elems []T, v T) bool {
+	for _, s := range elems {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+// setSubtract returns the elements of super that aren't in sub, in order.
+func setSubtract[T comparable](super, sub []T) []T {
+	final := make([]T, 0)
+	for _, el := range super {
+		if !contains(sub, el) {
+			final = append(final, el)
+		}
+	}
+	return final
+}
+
+// unique returns the given list without its repeated elements, in the order
+// they first appear.
+func unique[T comparable](list []T) []T {
+	uniq := make([]T, 0, len(list))
+	truth := make(map[T]bool)
+
+	for _, val := range list {
+		if _, ok := truth[val]; !ok {
+			truth[val] = true
+			uniq = append(uniq, val)
+		}
+	}
+	return uniq
+}
+
+// sortedKeys returns the keys of the given map, sorted.
+func sortedKeys[T any](m map[string]T) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
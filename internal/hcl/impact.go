@@ -0,0 +1,99 @@
+package hcl
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Impact describes how a change to one node flows to another node that
+// depends on it.
+type Impact struct {
+	// Address is the address of the affected node.
+	Address string
+	// Kind is the kind of the affected node, e.g. "resource" or "output".
+	Kind string
+	// Path is the shortest chain of references from the changed node to the
+	// affected node. Each step is the address of a node, followed by the
+	// attribute(s) through which it refers to the previous step.
+	Path []string
+}
+
+// impactKinds are the kinds of node reported by Impact. Variables and locals
+// only pass changes along, so they show up in paths but not on their own.
+var impactKinds = []string{kindResource, kindData, kindModule, kindOutput}
+
+// Impact returns every resource, data source, module call and output that
+// depends on the given node, directly or transitively. Impacts are sorted by
+// kind, then address.
+func (g *Graph) Impact(from string) ([]*Impact, error) {
+	if !g.Has(from) {
+		return nil, fmt.Errorf("%s is not in the graph", from)
+	}
+
+	// breadth-first, so that the first path found to each node is the
+	// shortest.
+	previous := map[string]string{from: ""}
+	queue := []string{from}
+	for len(queue) > 0 {
+		next := queue[0]
+		queue = queue[1:]
+		for _, dependent := range g.Dependents(next) {
+			if _, ok := previous[dependent]; ok {
+				continue
+			}
+			previous[dependent] = next
+			queue = append(queue, dependent)
+		}
+	}
+
+	impacts := make([]*Impact, 0)
+	for name := range previous {
+		kind := nodeKind(name)
+		if name == from || !contains(impactKinds, kind) {
+			continue
+		}
+		path := make([]string, 0)
+		for step := name; step != from; step = previous[step] {
+			path = append([]string{g.describeEdge(step, previous[step])}, path...)
+		}
+		path = append([]string{from}, path...)
+		impacts = append(impacts, &Impact{
+			Address: name,
+			Kind:    kind,
+			Path:    path,
+		})
+	}
+
+	kindOrder := make(map[string]int, len(impactKinds))
+	for i, kind := range impactKinds {
+		kindOrder[kind] = i
+	}
+	sort.Slice(impacts, func(i, j int) bool {
+		if impacts[i].Kind != impacts[j].Kind {
+			return kindOrder[impacts[i].Kind] < kindOrder[impacts[j].Kind]
+		}
+		return impacts[i].Address < impacts[j].Address
+	})
+	return impacts, nil
+}
+
+// describeEdge returns the given node's address, followed by the attributes
+// through which it refers to the given dependency, e.g. "aws_vpc.this.tags"
+// or "aws_subnet.this.{cidr_block, count}".
+func (g *Graph) describeEdge(name, dep string) string {
+	attributes := make([]string, 0)
+	for _, ref := range g.References(name, dep) {
+		if len(ref.Attribute) > 0 && !contains(attributes, ref.Attribute) {
+			attributes = append(attributes, ref.Attribute)
+		}
+	}
+	sort.Strings(attributes)
+	switch len(attributes) {
+	case 0:
+		return name
+	case 1:
+		return name + separator + attributes[0]
+	}
+	return name + separator + "{" + strings.Join(attributes, ", ") + "}"
+}
@@ -0,0 +1,119 @@
+package hcl
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/hashicorp/hcl/v2"
+)
+
+// Finding is a problem found in a module's configuration.
+type Finding struct {
+	// Address is the address of the object the finding is about.
+	Address string
+	// Range is the location of the object in the source.
+	Range hcl.Range
+	// Message describes the problem.
+	Message string
+}
+
+func (f *Finding) String() string {
+	if f.Range.Start.Line == 0 {
+		return fmt.Sprintf("%s: %s", f.Range.Filename, f.Message)
+	}
+	return fmt.Sprintf("%s:%d: %s", f.Range.Filename, f.Range.Start.Line, f.Message)
+}
+
+// unusedKinds are the kinds of node that are only useful when something
+// depends on them. Outputs and resources are sinks, so they're never unused.
+var unusedKinds = []string{kindVariable, kindLocal, kindData, kindProvider}
+
+// Unused returns a finding for each variable, local, data source and
+// provider configuration in the receiver that nothing depends on. Things
+// that only `import` blocks refer to are used.
+func (m *module) Unused() ([]*Finding, error) {
+	graph, err := m.Graph()
+	if err != nil {
+		return nil, err
+	}
+	imported, err := m.importDependencies()
+	if err != nil {
+		return nil, err
+	}
+
+	hasModuleCalls := false
+	for _, name := range graph.Nodes() {
+		if nodeKind(name) == kindModule {
+			hasModuleCalls = true
+			break
+		}
+	}
+
+	findings := make([]*Finding, 0)
+	for _, name := range graph.Nodes() {
+		kind := nodeKind(name)
+		if !contains(unusedKinds, kind) {
+			continue
+		}
+		if len(graph.Dependents(name)) > 0 || contains(imported, name) {
+			continue
+		}
+		// child modules inherit the default provider configurations without
+		// referring to them, so we can't tell whether those are unused.
+		if kind == kindProvider && hasModuleCalls && len(ParseAddress(name).Name) == 0 {
+			continue
+		}
+		findings = append(findings, &Finding{
+			Address: name,
+			Range:   m.declarationRange(name),
+			Message: fmt.Sprintf("%s is declared but never used", name),
+		})
+	}
+
+	sort.Slice(findings, func(i, j int) bool {
+		if findings[i].Range.Filename != findings[j].Range.Filename {
+			return findings[i].Range.Filename < findings[j].Range.Filename
+		}
+		return findings[i].Range.Start.Line < findings[j].Range.Start.Line
+	})
+	return findings, nil
+}
+
+// importDependencies returns the nodes that the receiver's `import` blocks
+// refer to, e.g. in their `id` or `for_each`. Import blocks aren't part of
+// the graph, so nothing in it depends on these on their behalf.
+func (m *module) importDependencies() ([]string, error) {
+	nodes := make([]string, 0)
+	for _, block := range m.cfg.refactors {
+		if block.Type != "import" {
+			continue
+		}
+		refs, err := blockDependencies(block)
+		if err != nil {
+			return nil, err
+		}
+		for _, ref := range refs {
+			prefix := ""
+			if ref.Attribute == "provider" {
+				prefix = "provider" + separator
+			}
+			if node, _ := m.resolve(prefix, ref.traversal, false); len(node) > 0 && !contains(nodes, node) {
+				nodes = append(nodes, node)
+			}
+		}
+	}
+	return nodes, nil
+}
+
+// declarationRange returns the source range of the declaration of the given
+// local or block.
+func (m *module) declarationRange(name string) hcl.Range {
+	addr := ParseAddress(name)
+	if local, ok := m.cfg.locals[addr]; ok {
+		return local.Range
+	}
+	if block, ok := m.cfg.blocks[addr]; ok {
+		return block.DefRange
+	}
+	return hcl.Range{}
+}
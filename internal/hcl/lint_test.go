@@ -0,0 +1,34 @@
+package hcl
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+)
+
+func TestUnused(t *testing.T) {
+	parser := NewModule(logrus.StandardLogger())
+	if err := parser.ParseModuleDirectory("../../fixtures/roots/unused"); err != nil {
+		t.Fatal(err)
+	}
+
+	findings, err := parser.Unused()
+	if err != nil {
+		t.Fatal(err)
+	}
+	actual := make([]string, len(findings))
+	for i, f := range findings {
+		f.Range.Filename = strings.TrimPrefix(f.Range.Filename, "../../fixtures/roots/unused/")
+		actual[i] = f.String()
+	}
+	expected := []string{
+		"main.tf:18: provider.aws.east is declared but never used",
+		"main.tf:27: var.unused is declared but never used",
+		"main.tf:38: local.unused is declared but never used",
+		"main.tf:43: data.aws_region.unused is declared but never used",
+	}
+	if strings.Join(actual, "\n") != strings.Join(expected, "\n") {
+		t.Errorf("Expected findings:\n%s\nActual findings:\n%s", strings.Join(expected, "\n"), strings.Join(actual, "\n"))
+	}
+}
@@ -0,0 +1,379 @@
+package hcl
+
+import (
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/hclsyntax"
+	"github.com/hashicorp/hcl/v2/hclwrite"
+	"github.com/sirupsen/logrus"
+)
+
+// Move is a change to the address of a resource or module call, as declared
+// by (or proposed for) a `moved` block.
+type Move struct {
+	From string
+	To   string
+	// Similarity is how alike the configurations at the two addresses are,
+	// from 0 to 1. It is 1 for moves declared in the configuration.
+	Similarity float64
+}
+
+func (mv *Move) String() string {
+	return fmt.Sprintf("%s -> %s", mv.From, mv.To)
+}
+
+// Refactors are the changes to state that a module declares with `moved`,
+// `import` and `removed` blocks.
+type Refactors struct {
+	Moves []*Move
+	// Imports are the addresses that `import` blocks import into.
+	Imports []string
+	// Removals are the addresses that `removed` blocks remove from state.
+	Removals []string
+}
+
+// refactorSchema picks out the arguments of moved, import and removed blocks
+var refactorSchema = &hcl.BodySchema{
+	Attributes: []hcl.AttributeSchema{
+		{Name: "from"},
+		{Name: "to"},
+	},
+}
+
+// movesMetaArguments are the arguments that don't describe the object itself,
+// so they don't count towards its similarity with another object.
+var movesMetaArguments = []string{"count", "for_each", "provider", "providers", "depends_on", "lifecycle", "source", "version"}
+
+// Refactors returns the moves, imports and removals declared in the
+// receiver.
+func (m *module) Refactors() (*Refactors, error) {
+	refactors := &Refactors{
+		Moves:    make([]*Move, 0),
+		Imports:  make([]string, 0),
+		Removals: make([]string, 0),
+	}
+	var diags hcl.Diagnostics
+	for _, block := range m.cfg.refactors {
+		content, _, contentDiags := block.Body.PartialContent(refactorSchema)
+		diags = append(diags, contentDiags...)
+		addresses := make(map[string]string, len(content.Attributes))
+		for name, attr := range content.Attributes {
+			traversal, travDiags := hcl.AbsTraversalForExpr(attr.Expr)
+			diags = append(diags, travDiags...)
+			if !travDiags.HasErrors() {
+				addresses[name] = traversalAddress(traversal)
+			}
+		}
+
+		switch block.Type {
+		case "moved":
+			if len(addresses["from"]) > 0 && len(addresses["to"]) > 0 {
+				refactors.Moves = append(refactors.Moves, &Move{From: addresses["from"], To: addresses["to"], Similarity: 1})
+			}
+		case "import":
+			if len(addresses["to"]) > 0 {
+				refactors.Imports = append(refactors.Imports, addresses["to"])
+			}
+		case "removed":
+			if len(addresses["from"]) > 0 {
+				refactors.Removals = append(refactors.Removals, addresses["from"])
+			}
+		}
+	}
+	if err := handleDiags(diags, m.fundamental.Files(), m.Logger.WriterLevel(logrus.WarnLevel)); err != nil {
+		return nil, err
+	}
+	return refactors, nil
+}
+
+// statefulObject is a managed resource or module call, as far as telling it
+// apart from others is concerned.
+type statefulObject struct {
+	// key groups the objects that could be the same thing under different
+	// addresses: the type of a resource, or the source of a module call.
+	key string
+	// arguments maps the path of each of the object's arguments (e.g. "ami"
+	// or "root_block_device.volume_size") to its source, with whitespace
+	// collapsed.
+	arguments map[string]string
+}
+
+// statefulObjects returns the given module's managed resources and module
+// calls, plus those in the modules it calls from local directories (read with
+// the given logger), keyed by address.
+func statefulObjects(m Module, logger *logrus.Logger) (map[string]*statefulObject, error) {
+	return statefulObjectsIn(m, logger, "", []string{})
+}
+
+func statefulObjectsIn(m Module, logger *logrus.Logger, prefix string, ancestors []string) (map[string]*statefulObject, error) {
+	files := m.Parser().Files()
+	objects := make(map[string]*statefulObject)
+	for addr, block := range m.Configuration().blocks {
+		switch addr.Kind {
+		case kindResource:
+			objects[prefix+addr.String()] = &statefulObject{
+				key:       addr.Type,
+				arguments: argumentSources(files, block.Body, ""),
+			}
+		case kindModule:
+			arguments := argumentSources(files, block.Body, "")
+			source := strings.Trim(arguments["source"], `"`)
+			objects[prefix+addr.String()] = &statefulObject{
+				key:       kindModule + separator + source,
+				arguments: arguments,
+			}
+
+			if !isLocalSource(source) || m.Module() == nil {
+				continue
+			}
+			dir := path.Join(m.Module().Path, source)
+			if contains(ancestors, dir) {
+				continue
+			}
+			child := NewModule(logger)
+			if err := child.ParseModuleDirectory(dir); err != nil {
+				return nil, fmt.Errorf("could not read %s (called by %s): %w", dir, prefix+addr.String(), err)
+			}
+			childObjects, err := statefulObjectsIn(child, logger, prefix+addr.String()+separator, append(ancestors, m.Module().Path))
+			if err != nil {
+				return nil, err
+			}
+			for name, object := range childObjects {
+				objects[name] = object
+			}
+		}
+	}
+	return objects, nil
+}
+
+// isLocalSource returns whether the given module source is a local path.
+func isLocalSource(source string) bool {
+	return strings.HasPrefix(source, "./") || strings.HasPrefix(source, "../")
+}
+
+// argumentSources returns the source of each argument in the given body
+// (from the given files), including those of nested blocks, keyed by path.
+func argumentSources(files map[string]*hcl.File, body hcl.Body, prefix string) map[string]string {
+	source := func(rng hcl.Range) string {
+		file, ok := files[rng.Filename]
+		if !ok {
+			return ""
+		}
+		return strings.Join(strings.Fields(string(rng.SliceBytes(file.Bytes))), " ")
+	}
+	arguments := make(map[string]string)
+	if syntaxBody, ok := body.(*hclsyntax.Body); ok {
+		for name, attr := range syntaxBody.Attributes {
+			arguments[prefix+name] = source(attr.Expr.Range())
+		}
+		for _, nested := range syntaxBody.Blocks {
+			for name, value := range argumentSources(files, nested.Body, prefix+nested.Type+separator) {
+				arguments[name] = value
+			}
+		}
+		return arguments
+	}
+
+	attrs, _ := body.JustAttributes()
+	for name, attr := range attrs {
+		arguments[prefix+name] = source(attr.Expr.Range())
+	}
+	return arguments
+}
+
+// similarity returns how alike two objects' configurations are, from 0 to 1.
+// Half of the score is for having the same arguments, and half is for those
+// arguments having the same values.
+func similarity(a, b *statefulObject) float64 {
+	names := make([]string, 0)
+	for name := range a.arguments {
+		if !isMetaArgument(name) {
+			names = append(names, name)
+		}
+	}
+	for name := range b.arguments {
+		if !isMetaArgument(name) && !contains(names, name) {
+			names = append(names, name)
+		}
+	}
+	if len(names) == 0 {
+		return 1
+	}
+
+	score := 0
+	for _, name := range names {
+		aValue, aOK := a.arguments[name]
+		bValue, bOK := b.arguments[name]
+		if aOK && bOK {
+			score++
+			if aValue == bValue {
+				score++
+			}
+		}
+	}
+	return float64(score) / float64(2*len(names))
+}
+
+func isMetaArgument(path string) bool {
+	return contains(movesMetaArguments, strings.SplitN(path, separator, 2)[0])
+}
+
+// ProposeMoves matches the resources and module calls that are only in the
+// old version of a module with those that are only in the new version, and
+// returns a move for each match. Objects only match if they have the same
+// type (or module source), and their configurations are at least as similar
+// as the given threshold. Resources in local child modules are included, so
+// that moving a resource into (or out of) a module is found too. Those
+// modules are read with the given logger.
+//
+// Moves, imports and removals already declared in the new version are taken
+// into account, so the proposals don't duplicate them.
+func ProposeMoves(oldModule, newModule Module, threshold float64, logger *logrus.Logger) ([]*Move, error) {
+	oldObjects, err := statefulObjects(oldModule, logger)
+	if err != nil {
+		return nil, err
+	}
+	newObjects, err := statefulObjects(newModule, logger)
+	if err != nil {
+		return nil, err
+	}
+	refactors, err := newModule.Refactors()
+	if err != nil {
+		return nil, err
+	}
+
+	removed := make([]string, 0)
+	for address := range oldObjects {
+		if _, ok := newObjects[address]; ok || contains(refactors.Removals, address) {
+			continue
+		}
+		removed = append(removed, address)
+	}
+	added := make([]string, 0)
+	for address := range newObjects {
+		if _, ok := oldObjects[address]; ok || contains(refactors.Imports, address) {
+			continue
+		}
+		added = append(added, address)
+	}
+	for _, move := range refactors.Moves {
+		removed = setSubtract(removed, []string{move.From})
+		added = setSubtract(added, []string{move.To})
+	}
+
+	candidates := make([]*Move, 0)
+	for _, from := range removed {
+		for _, to := range added {
+			if oldObjects[from].key != newObjects[to].key {
+				continue
+			}
+			score := similarity(oldObjects[from], newObjects[to])
+			if score >= threshold {
+				candidates = append(candidates, &Move{From: from, To: to, Similarity: score})
+			}
+		}
+	}
+	// best matches first. Between equally good matches, prefer those that
+	// keep the object's name.
+	sort.Slice(candidates, func(i, j int) bool {
+		if candidates[i].Similarity != candidates[j].Similarity {
+			return candidates[i].Similarity > candidates[j].Similarity
+		}
+		iSameName := lastStep(candidates[i].From) == lastStep(candidates[i].To)
+		jSameName := lastStep(candidates[j].From) == lastStep(candidates[j].To)
+		if iSameName != jSameName {
+			return iSameName
+		}
+		if candidates[i].From != candidates[j].From {
+			return candidates[i].From < candidates[j].From
+		}
+		return candidates[i].To < candidates[j].To
+	})
+
+	moves := make([]*Move, 0)
+	matched := make(map[string]bool)
+	for _, candidate := range candidates {
+		if matched[candidate.From] || matched[candidate.To] {
+			continue
+		}
+		matched[candidate.From] = true
+		matched[candidate.To] = true
+		moves = append(moves, candidate)
+	}
+	// moving a module call moves everything in it, so the things in it
+	// don't need moves of their own.
+	moves = filterNestedMoves(moves, append(moves, refactors.Moves...))
+	sort.Slice(moves, func(i, j int) bool { return moves[i].From < moves[j].From })
+	return moves, nil
+}
+
+// filterNestedMoves returns the given moves, except those that are implied
+// by one of the given module call moves.
+func filterNestedMoves(moves, parents []*Move) []*Move {
+	filtered := make([]*Move, 0, len(moves))
+	for _, move := range moves {
+		implied := false
+		for _, parent := range parents {
+			if !isModuleCallAddress(parent.From) {
+				continue
+			}
+			fromPrefix := parent.From + separator
+			toPrefix := parent.To + separator
+			if strings.HasPrefix(move.From, fromPrefix) && strings.HasPrefix(move.To, toPrefix) &&
+				strings.TrimPrefix(move.From, fromPrefix) == strings.TrimPrefix(move.To, toPrefix) {
+				implied = true
+				break
+			}
+		}
+		if !implied {
+			filtered = append(filtered, move)
+		}
+	}
+	return filtered
+}
+
+// isModuleCallAddress returns whether the given address is that of a module
+// call, e.g. "module.network" or "module.network.module.subnets".
+func isModuleCallAddress(address string) bool {
+	parts := strings.Split(address, separator)
+	return len(parts) >= 2 && parts[len(parts)-2] == "module"
+}
+
+// lastStep returns the name of the object at the given address, e.g. "web"
+// for "module.compute.aws_instance.web".
+func lastStep(address string) string {
+	parts := strings.Split(address, separator)
+	return parts[len(parts)-1]
+}
+
+// WriteMoves appends a `moved` block for each of the given moves to the given
+// configuration file source (which may be empty), and returns the result.
+func WriteMoves(src []byte, filename string, moves []*Move) ([]byte, error) {
+	file, diags := hclwrite.ParseConfig(src, filename, hcl.InitialPos)
+	if diags.HasErrors() {
+		return nil, diags
+	}
+	body := file.Body()
+	for _, move := range moves {
+		from, diags := hclsyntax.ParseTraversalAbs([]byte(move.From), "", hcl.InitialPos)
+		if diags.HasErrors() {
+			return nil, fmt.Errorf("%s is not a valid address: %w", move.From, diags)
+		}
+		to, diags := hclsyntax.ParseTraversalAbs([]byte(move.To), "", hcl.InitialPos)
+		if diags.HasErrors() {
+			return nil, fmt.Errorf("%s is not a valid address: %w", move.To, diags)
+		}
+
+		if len(body.Blocks())+len(body.Attributes()) > 0 {
+			body.AppendNewline()
+		}
+		block := body.AppendNewBlock("moved", nil)
+		block.Body().SetAttributeTraversal("from", from)
+		block.Body().SetAttributeTraversal("to", to)
+	}
+	return hclwrite.Format(file.Bytes()), nil
+}
@@ -0,0 +1,88 @@
+package hcl
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+)
+
+func TestProposeMoves(t *testing.T) {
+	oldModule := NewModule(logrus.StandardLogger())
+	if err := oldModule.ParseModuleDirectory("../../fixtures/modules/refactor/v1"); err != nil {
+		t.Fatal(err)
+	}
+	newModule := NewModule(logrus.StandardLogger())
+	if err := newModule.ParseModuleDirectory("../../fixtures/modules/refactor/v2"); err != nil {
+		t.Fatal(err)
+	}
+
+	moves, err := ProposeMoves(oldModule, newModule, 0.5, logrus.StandardLogger())
+	if err != nil {
+		t.Fatal(err)
+	}
+	actual := make([]string, len(moves))
+	for i, move := range moves {
+		actual[i] = fmt.Sprintf("%s (%.2f)", move, move.Similarity)
+	}
+	expected := []string{
+		"aws_instance.web -> aws_instance.app (1.00)",
+		"aws_subnet.public -> module.network.aws_subnet.public (0.83)",
+		"aws_vpc.main -> module.network.aws_vpc.this (1.00)",
+	}
+	if strings.Join(actual, "\n") != strings.Join(expected, "\n") {
+		t.Errorf("Expected moves:\n%s\nActual moves:\n%s", strings.Join(expected, "\n"), strings.Join(actual, "\n"))
+	}
+}
+
+func TestFilterNestedMoves(t *testing.T) {
+	moves := []*Move{
+		{From: "module.network", To: "module.vpc"},
+		{From: "module.network.aws_vpc.this", To: "module.vpc.aws_vpc.this"},
+		{From: "module.network.aws_vpc.this", To: "module.vpc.aws_vpc.main"},
+	}
+	filtered := filterNestedMoves(moves, moves)
+	if len(filtered) != 2 || filtered[1].To != "module.vpc.aws_vpc.main" {
+		t.Errorf("Expected the implied move to be filtered out, got %v", filtered)
+	}
+}
+
+func TestWriteMoves(t *testing.T) {
+	src, err := os.ReadFile("../../fixtures/modules/refactor/v2/moved.tf")
+	if err != nil {
+		t.Fatal(err)
+	}
+	moves := []*Move{
+		{From: "aws_instance.web", To: "aws_instance.app"},
+		{From: "aws_vpc.main", To: "module.network.aws_vpc.this"},
+	}
+	actual, err := WriteMoves(src, "moved.tf", moves)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := string(src) + `
+moved {
+  from = aws_instance.web
+  to   = aws_instance.app
+}
+
+moved {
+  from = aws_vpc.main
+  to   = module.network.aws_vpc.this
+}
+`
+	if string(actual) != expected {
+		t.Errorf("Expected:\n%s\nActual:\n%s", expected, actual)
+	}
+
+	actual, err = WriteMoves(nil, "moved.tf", moves[:1])
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected = "moved {\n  from = aws_instance.web\n  to   = aws_instance.app\n}\n"
+	if string(actual) != expected {
+		t.Errorf("Expected:\n%s\nActual:\n%s", expected, actual)
+	}
+}
@@ -0,0 +1,245 @@
+package hcl
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/gohcl"
+	"github.com/hashicorp/hcl/v2/hclsyntax"
+	"github.com/sirupsen/logrus"
+)
+
+// mergedBlockType is the only nested block type that an override merges
+// argument-by-argument. Any other nested block type in an override replaces
+// all the nested blocks of that type in the original.
+const mergedBlockType = "lifecycle"
+
+// applyOverrides merges the contents of an override file into what the
+// receiver has already read, following terraform's rules
+// (https://developer.hashicorp.com/terraform/language/files/override):
+//   - each local in the override replaces the local of the same name.
+//   - each block in the override is merged into the block of the same
+//     address.
+//
+// It is an error for an override to refer to something that hasn't been
+// declared.
+func (m *module) applyOverrides(content *hcl.BodyContent) error {
+	var diags hcl.Diagnostics
+	for _, block := range content.Blocks {
+		if block.Type == "locals" {
+			locals, localDiags := block.Body.JustAttributes()
+			diags = append(diags, localDiags...)
+			for name, attr := range locals {
+				addr := Address{Kind: kindLocal, Name: name}
+				if _, ok := m.cfg.locals[addr]; !ok {
+					diags = append(diags, missingBaseDiagnostic(addr, attr.NameRange))
+					continue
+				}
+				m.cfg.locals[addr] = attr
+			}
+		} else if block.Type == "terraform" {
+			m.cfg.terraform = append(m.cfg.terraform, block)
+		} else if contains(refactorBlockTypes, block.Type) {
+			m.cfg.refactors = append(m.cfg.refactors, block)
+		} else {
+			addr := blockAddress(block)
+			base, ok := m.cfg.blocks[addr]
+			if !ok {
+				diags = append(diags, missingBaseDiagnostic(addr, block.DefRange))
+				continue
+			}
+			m.cfg.blocks[addr] = mergeBlock(base, block)
+		}
+	}
+	return handleDiags(diags, m.fundamental.Files(), m.Logger.WriterLevel(logrus.WarnLevel))
+}
+
+// missingBaseDiagnostic returns an error diagnostic for an override (at
+// subject) of something that was never declared.
+func missingBaseDiagnostic(addr Address, subject hcl.Range) *hcl.Diagnostic {
+	return &hcl.Diagnostic{
+		Severity: hcl.DiagError,
+		Summary:  fmt.Sprintf("Missing base %s declaration to override", addr.Kind),
+		Detail:   fmt.Sprintf("There is no %s declaration for %s. An override file can only override something declared in a primary file.", addr.Kind, addr),
+		Subject:  subject.Ptr(),
+	}
+}
+
+// mergeBlock returns a block that is the given base block with the given
+// override merged into it. The result keeps the base block's source
+// location.
+func mergeBlock(base, override *hcl.Block) *hcl.Block {
+	merged := *base
+	merged.Body = &overrideBody{base: base.Body, override: override.Body}
+	return &merged
+}
+
+// overrideBody is the body of a block that has been merged with an override.
+// Each argument in the override replaces the argument of the same name, and
+// each type of nested block in the override replaces the nested blocks of
+// that type (except lifecycle blocks, which are merged).
+type overrideBody struct {
+	base     hcl.Body
+	override hcl.Body
+}
+
+var _ hcl.Body = (*overrideBody)(nil)
+
+func (b *overrideBody) Content(schema *hcl.BodySchema) (*hcl.BodyContent, hcl.Diagnostics) {
+	baseContent, diags := b.base.Content(schema)
+	overrideContent, overrideDiags := b.override.Content(optionalSchema(schema))
+	diags = append(diags, overrideDiags...)
+	return mergeContent(baseContent, overrideContent), diags
+}
+
+func (b *overrideBody) PartialContent(schema *hcl.BodySchema) (*hcl.BodyContent, hcl.Body, hcl.Diagnostics) {
+	baseContent, baseRemain, diags := b.base.PartialContent(schema)
+	overrideContent, overrideRemain, overrideDiags := b.override.PartialContent(optionalSchema(schema))
+	diags = append(diags, overrideDiags...)
+	remain := &overrideBody{base: baseRemain, override: overrideRemain}
+	return mergeContent(baseContent, overrideContent), remain, diags
+}
+
+func (b *overrideBody) JustAttributes() (hcl.Attributes, hcl.Diagnostics) {
+	attrs, diags := b.base.JustAttributes()
+	overrideAttrs, overrideDiags := b.override.JustAttributes()
+	diags = append(diags, overrideDiags...)
+	if attrs == nil {
+		attrs = make(hcl.Attributes, len(overrideAttrs))
+	}
+	for name, attr := range overrideAttrs {
+		attrs[name] = attr
+	}
+	return attrs, diags
+}
+
+func (b *overrideBody) MissingItemRange() hcl.Range {
+	return b.base.MissingItemRange()
+}
+
+// dependencies returns the references in the receiver, leaving out those in
+// the base body that the override replaces.
+func (b *overrideBody) dependencies(prefix string) ([]*Reference, error) {
+	baseRefs, err := bodyDependencies(b.base, prefix)
+	if err != nil {
+		return nil, err
+	}
+	refs, err := bodyDependencies(b.override, prefix)
+	if err != nil {
+		return nil, err
+	}
+
+	overridden := overriddenNames(b.override)
+	for _, ref := range baseRefs {
+		path := strings.TrimPrefix(ref.Attribute, prefix)
+		replaced := false
+		for _, name := range overridden {
+			if path == name || strings.HasPrefix(path, name+separator) {
+				replaced = true
+				break
+			}
+		}
+		if !replaced {
+			refs = append(refs, ref)
+		}
+	}
+	return refs, nil
+}
+
+// overriddenNames returns the attribute paths that the given override body
+// replaces: the name of each argument and nested block type, except that the
+// arguments of a lifecycle block are named individually, e.g.
+// "lifecycle.ignore_changes".
+func overriddenNames(body hcl.Body) []string {
+	names := make([]string, 0)
+
+	if syntaxBody, ok := body.(*hclsyntax.Body); ok {
+		for name := range syntaxBody.Attributes {
+			names = append(names, name)
+		}
+		for _, nested := range syntaxBody.Blocks {
+			if nested.Type != mergedBlockType {
+				names = append(names, nested.Type)
+				continue
+			}
+			for name := range nested.Body.Attributes {
+				names = append(names, nested.Type+separator+name)
+			}
+		}
+		return names
+	}
+
+	// In JSON syntax, nested blocks look like arguments whose values are
+	// objects.
+	attrs, _ := body.JustAttributes()
+	for name, attr := range attrs {
+		if name != mergedBlockType {
+			names = append(names, name)
+			continue
+		}
+		pairs, diags := hcl.ExprMap(attr.Expr)
+		if diags.HasErrors() {
+			names = append(names, name)
+			continue
+		}
+		for _, pair := range pairs {
+			var key string
+			if diags := gohcl.DecodeExpression(pair.Key, nil, &key); !diags.HasErrors() {
+				names = append(names, name+separator+key)
+			}
+		}
+	}
+	return names
+}
+
+// mergeContent returns the given base content with the given override
+// content merged into it.
+func mergeContent(base, override *hcl.BodyContent) *hcl.BodyContent {
+	merged := &hcl.BodyContent{
+		Attributes:       make(hcl.Attributes, len(base.Attributes)+len(override.Attributes)),
+		Blocks:           make(hcl.Blocks, 0, len(base.Blocks)+len(override.Blocks)),
+		MissingItemRange: base.MissingItemRange,
+	}
+	for name, attr := range base.Attributes {
+		merged.Attributes[name] = attr
+	}
+	for name, attr := range override.Attributes {
+		merged.Attributes[name] = attr
+	}
+
+	overridden := make(map[string]bool)
+	for _, block := range override.Blocks {
+		overridden[block.Type] = true
+	}
+	var baseLifecycle *hcl.Block
+	for _, block := range base.Blocks {
+		if block.Type == mergedBlockType {
+			baseLifecycle = block
+		}
+		if !overridden[block.Type] {
+			merged.Blocks = append(merged.Blocks, block)
+		}
+	}
+	for _, block := range override.Blocks {
+		if block.Type == mergedBlockType && baseLifecycle != nil {
+			block = mergeBlock(baseLifecycle, block)
+		}
+		merged.Blocks = append(merged.Blocks, block)
+	}
+	return merged
+}
+
+// optionalSchema returns a copy of the given schema with nothing required,
+// since an override only has to mention what it changes.
+func optionalSchema(schema *hcl.BodySchema) *hcl.BodySchema {
+	optional := &hcl.BodySchema{
+		Attributes: make([]hcl.AttributeSchema, len(schema.Attributes)),
+		Blocks:     schema.Blocks,
+	}
+	for i, attr := range schema.Attributes {
+		attr.Required = false
+		optional.Attributes[i] = attr
+	}
+	return optional
+}
@@ -0,0 +1,174 @@
+package hcl
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+)
+
+// PlanAction is the action Terraform plans to take on a resource instance.
+type PlanAction string
+
+// These are the actions a plan may take on a resource instance. Terraform
+// expresses replacement as a pair of actions, which we collapse into one.
+const (
+	PlanActionNoOp    PlanAction = "no-op"
+	PlanActionRead    PlanAction = "read"
+	PlanActionCreate  PlanAction = "create"
+	PlanActionUpdate  PlanAction = "update"
+	PlanActionReplace PlanAction = "replace"
+	PlanActionDelete  PlanAction = "delete"
+)
+
+// planActionSeverity ranks actions, so that a node with several instances can
+// be colored by the most disruptive thing happening to any of them.
+var planActionSeverity = map[PlanAction]int{
+	PlanActionNoOp:    0,
+	PlanActionRead:    1,
+	PlanActionCreate:  2,
+	PlanActionUpdate:  3,
+	PlanActionReplace: 4,
+	PlanActionDelete:  5,
+}
+
+// planActionColors maps each action to the fill color of its graph node.
+var planActionColors = map[PlanAction]string{
+	PlanActionNoOp:    "lightgrey",
+	PlanActionRead:    "lightblue",
+	PlanActionCreate:  "palegreen",
+	PlanActionUpdate:  "khaki",
+	PlanActionReplace: "orange",
+	PlanActionDelete:  "salmon",
+}
+
+// Plan represents the parts of a `terraform show -json` plan that we use.
+type Plan struct {
+	FormatVersion    string                `json:"format_version"`
+	TerraformVersion string                `json:"terraform_version"`
+	ResourceChanges  []*PlanResourceChange `json:"resource_changes"`
+	Errored          bool                  `json:"errored"`
+}
+
+// PlanResourceChange represents one resource instance's planned change.
+type PlanResourceChange struct {
+	Address       string      `json:"address"`
+	ModuleAddress string      `json:"module_address"`
+	Mode          string      `json:"mode"`
+	Type          string      `json:"type"`
+	Name          string      `json:"name"`
+	Index         interface{} `json:"index"`
+	Change        struct {
+		Actions []string `json:"actions"`
+	} `json:"change"`
+}
+
+// ParsePlanFile reads a JSON plan, as produced by `terraform show -json`.
+func ParsePlanFile(filename string) (*Plan, error) {
+	b, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	plan := &Plan{}
+	if err := json.Unmarshal(b, plan); err != nil {
+		return nil, fmt.Errorf("could not read plan %s: %w", filename, err)
+	}
+	if len(plan.FormatVersion) == 0 {
+		return nil, fmt.Errorf("%s does not look like a JSON plan (no format_version). Did you run `terraform show -json`?", filename)
+	}
+	return plan, nil
+}
+
+// Action returns the receiver's action. Terraform's "delete, create" and
+// "create, delete" pairs are both reported as a replacement.
+func (rc *PlanResourceChange) Action() PlanAction {
+	if len(rc.Change.Actions) > 1 {
+		return PlanActionReplace
+	}
+	if len(rc.Change.Actions) == 0 {
+		return PlanActionNoOp
+	}
+	return PlanAction(rc.Change.Actions[0])
+}
+
+// NodeAddress returns the address of the graph node the receiver belongs to.
+// Instances of a counted or for_each'ed resource all belong to the node of
+// the resource itself, and anything inside a child module belongs to the
+// node of the module call.
+func (rc *PlanResourceChange) NodeAddress() string {
+	if len(rc.ModuleAddress) > 0 {
+		parts := strings.SplitN(rc.ModuleAddress, separator, 3)
+		return ParseAddress(parts[0] + separator + parts[1]).Base().String()
+	}
+	addr := Address{Kind: kindResource, Type: rc.Type, Name: rc.Name}
+	if rc.Mode == "data" {
+		addr.Kind = kindData
+	}
+	return addr.String()
+}
+
+// InstanceNodeAddress returns the address of the graph node the receiver
+// belongs to once the graph is expanded (see ExpandedGraph): the instance of
+// the resource itself, or the instance of the module call that it's inside.
+func (rc *PlanResourceChange) InstanceNodeAddress() string {
+	if len(rc.ModuleAddress) > 0 {
+		parts := strings.SplitN(rc.ModuleAddress, separator, 3)
+		return parts[0] + separator + parts[1]
+	}
+	return rc.Address
+}
+
+// ApplyPlan colors the receiver's nodes by the actions the given plan would
+// take on them. A node with several instances is colored by its most
+// disruptive action, and labelled with a summary of all of them.
+// Each change is matched to its instance node, if the receiver is expanded,
+// or else to the node of its resource or module call. ApplyPlan returns the
+// addresses of any planned changes that have no node in the receiver.
+func (g *Graph) ApplyPlan(plan *Plan) []string {
+	// map from node address to action to instance addresses
+	actions := make(map[string]map[PlanAction][]string)
+	unmatched := make([]string, 0)
+	for _, rc := range plan.ResourceChanges {
+		node := rc.InstanceNodeAddress()
+		if !g.Has(node) {
+			node = rc.NodeAddress()
+		}
+		if !g.Has(node) {
+			unmatched = append(unmatched, rc.Address)
+			continue
+		}
+		if _, ok := actions[node]; !ok {
+			actions[node] = make(map[PlanAction][]string)
+		}
+		actions[node][rc.Action()] = append(actions[node][rc.Action()], rc.Address)
+	}
+
+	for node, nodeActions := range actions {
+		var worst PlanAction = PlanActionNoOp
+		summary := make([]string, 0, len(nodeActions))
+		tooltip := make([]string, 0)
+		for action, instances := range nodeActions {
+			if planActionSeverity[action] > planActionSeverity[worst] {
+				worst = action
+			}
+			summary = append(summary, fmt.Sprintf("%s %d", action, len(instances)))
+			for _, instance := range instances {
+				tooltip = append(tooltip, fmt.Sprintf("%s: %s", instance, action))
+			}
+		}
+		sort.Strings(summary)
+		sort.Strings(tooltip)
+
+		color, ok := planActionColors[worst]
+		if !ok {
+			color = "white"
+		}
+		g.SetNodeAttribute(node, "style", "filled")
+		g.SetNodeAttribute(node, "fillcolor", color)
+		g.SetNodeAttribute(node, "label", node+"\n"+strings.Join(summary, ", "))
+		g.SetNodeAttribute(node, "tooltip", strings.Join(tooltip, "\n"))
+	}
+
+	return unmatched
+}
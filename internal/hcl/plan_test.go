@@ -0,0 +1,84 @@
+package hcl
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+)
+
+func TestApplyPlan(t *testing.T) {
+	parser := NewModule(logrus.StandardLogger())
+	if err := parser.ParseModuleDirectory("../../fixtures/roots/mapped-resource"); err != nil {
+		t.Fatal(err)
+	}
+	graph, err := parser.Graph()
+	if err != nil {
+		t.Fatal(err)
+	}
+	plan, err := ParsePlanFile("../../fixtures/plans/mapped-resource.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	unmatched := graph.ApplyPlan(plan)
+	if len(unmatched) != 1 || unmatched[0] != "module.other.random_string.this" {
+		t.Errorf("Expected only the child module's change to be unmatched, got %v", unmatched)
+	}
+
+	attrs := graph.attributes["random_string.this"]
+	if attrs["fillcolor"] != planActionColors[PlanActionReplace] {
+		t.Errorf("Expected random_string.this to be colored as a replacement, got %q", attrs["fillcolor"])
+	}
+	expectedLabel := "random_string.this\ncreate 1, replace 1"
+	if attrs["label"] != expectedLabel {
+		t.Errorf("Expected label %q, got %q", expectedLabel, attrs["label"])
+	}
+	if len(graph.attributes["var.keys"]) != 0 {
+		t.Errorf("Expected var.keys to have no attributes, got %v", graph.attributes["var.keys"])
+	}
+}
+
+func TestApplyPlanExpanded(t *testing.T) {
+	parser := NewModule(logrus.StandardLogger())
+	if err := parser.ParseModuleDirectory("../../fixtures/roots/mapped-resource"); err != nil {
+		t.Fatal(err)
+	}
+	inputs, err := ParseVariableFlag(`keys=["a","b"]`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	graph, err := parser.ExpandedGraph(inputs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	plan, err := ParsePlanFile("../../fixtures/plans/mapped-resource.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// focusing on the resource keeps both of its instances
+	focus := graph.Match("random_string.this")
+	expectedFocus := []string{`random_string.this["a"]`, `random_string.this["b"]`}
+	if strings.Join(focus, ",") != strings.Join(expectedFocus, ",") {
+		t.Errorf("Expected random_string.this to match %v, got %v", expectedFocus, focus)
+	}
+	graph, err = graph.Subgraph("random_string.this", 0, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	unmatched := graph.ApplyPlan(plan)
+	if len(unmatched) != 1 || unmatched[0] != "module.other.random_string.this" {
+		t.Errorf("Expected only the child module's change to be unmatched, got %v", unmatched)
+	}
+	expectedColors := map[string]PlanAction{
+		`random_string.this["a"]`: PlanActionCreate,
+		`random_string.this["b"]`: PlanActionReplace,
+	}
+	for node, action := range expectedColors {
+		if actual := graph.attributes[node]["fillcolor"]; actual != planActionColors[action] {
+			t.Errorf("Expected %s to be colored for %s, got %q", node, action, actual)
+		}
+	}
+}
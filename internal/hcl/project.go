@@ -0,0 +1,352 @@
+package hcl
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/gohcl"
+	"github.com/hashicorp/hcl/v2/hclparse"
+	"github.com/hashicorp/hcl/v2/hclsyntax"
+)
+
+// ProjectFilename is the name of the file that configures a terrascope
+// project, and each root in it.
+const ProjectFilename = "terrascope.hcl"
+
+const defaultRootsDir = "roots"
+const defaultBuildDir = ".terrascope"
+const defaultScopeDataFilename = "data.hcl"
+
+// projectFileSchema is the schema of a terrascope.hcl file. A project's file
+// has a project block, and a root's has a root block.
+var projectFileSchema = &hcl.BodySchema{
+	Blocks: []hcl.BlockHeaderSchema{
+		{Type: "project", LabelNames: []string{"name"}},
+		{Type: "root", LabelNames: []string{"name"}},
+	},
+}
+
+// projectBlock is the body of a project block.
+type projectBlock struct {
+	RootsDir  string          `hcl:"roots_dir,optional"`
+	BuildDir  string          `hcl:"build_dir,optional"`
+	ScopeData []string        `hcl:"scope_data,optional"`
+	Scopes    []*ScopeType    `hcl:"scope,block"`
+	Backends  []*backendBlock `hcl:"backend,block"`
+}
+
+// backendBlock is a project's template for the backend block of its roots.
+type backendBlock struct {
+	Type   string   `hcl:"type,label"`
+	Config hcl.Body `hcl:",remain"`
+}
+
+// rootBlock is the body of a root block.
+type rootBlock struct {
+	Scopes []string `hcl:"scopes,optional"`
+}
+
+// ScopeType is one layer of a project's infrastructure, like an organization
+// or an environment. Each value of a scope type is inside a value of the type
+// before it.
+type ScopeType struct {
+	Name        string `hcl:"name,label"`
+	Description string `hcl:"description,optional"`
+}
+
+// Project is a terrascope project: a set of terraform roots, each of which is
+// built once for each value of a hierarchy of scopes.
+type Project struct {
+	Name string
+	// Filename is the absolute path of the project's terrascope.hcl.
+	Filename string
+	// RootsDir is the absolute path of the directory the project's roots are
+	// in.
+	RootsDir string
+	// BuildDir is the absolute path of the directory that roots are built
+	// into.
+	BuildDir string
+	// ScopeDataFiles are the absolute paths of the files that the project's
+	// scope values are read from.
+	ScopeDataFiles []string
+	// ScopeTypes are the project's scope types, from the outermost in.
+	ScopeTypes []*ScopeType
+	// Backend is the template for the backend block of each built root, or
+	// nil if the project has none.
+	Backend *BackendTemplate
+
+	parser *hclparse.Parser
+}
+
+// BackendTemplate is the backend block that a project gives each of its
+// built roots. Its arguments are expressions of the root's name and scope
+// (see Project.BackendContext).
+type BackendTemplate struct {
+	Type string
+	Body *hclsyntax.Body
+}
+
+// Root is a terraform root configuration in a project, which is built once for
+// each value of its scope types.
+type Root struct {
+	Name string
+	// Dir is the absolute path of the root's directory.
+	Dir string
+	// Scopes are the names of the root's scope types, from the outermost in.
+	Scopes []string
+}
+
+// LoadProject reads the project configured by the given file.
+func LoadProject(filename string) (*Project, error) {
+	filename, err := filepath.Abs(filename)
+	if err != nil {
+		return nil, err
+	}
+	parser := hclparse.NewParser()
+	f, diags := parser.ParseHCLFile(filename)
+	if err := handleDiags(diags, parser.Files(), nil); err != nil {
+		return nil, err
+	}
+	content, diags := f.Body.Content(projectFileSchema)
+	if err := handleDiags(diags, parser.Files(), nil); err != nil {
+		return nil, err
+	}
+	var block *hcl.Block
+	for _, b := range content.Blocks {
+		if b.Type != "project" {
+			continue
+		}
+		if block != nil {
+			return nil, fmt.Errorf("%s: a project is already declared at line %d", b.DefRange, block.DefRange.Start.Line)
+		}
+		block = b
+	}
+	if block == nil {
+		return nil, fmt.Errorf("%s has no project block", filename)
+	}
+
+	decoded := &projectBlock{}
+	diags = gohcl.DecodeBody(block.Body, nil, decoded)
+	if err := handleDiags(diags, parser.Files(), nil); err != nil {
+		return nil, err
+	}
+
+	dir := filepath.Dir(filename)
+	project := &Project{
+		Name:           block.Labels[0],
+		Filename:       filename,
+		RootsDir:       projectPath(dir, decoded.RootsDir, defaultRootsDir),
+		BuildDir:       projectPath(dir, decoded.BuildDir, defaultBuildDir),
+		ScopeDataFiles: make([]string, 0, len(decoded.ScopeData)),
+		ScopeTypes:     decoded.Scopes,
+		parser:         parser,
+	}
+	for _, name := range decoded.ScopeData {
+		project.ScopeDataFiles = append(project.ScopeDataFiles, projectPath(dir, name, ""))
+	}
+	if len(project.ScopeDataFiles) == 0 {
+		project.ScopeDataFiles = append(project.ScopeDataFiles, filepath.Join(dir, defaultScopeDataFilename))
+	}
+
+	names := make([]string, 0, len(project.ScopeTypes))
+	for _, scopeType := range project.ScopeTypes {
+		// the names become variable names, and attributes of `scope`
+		if !hclsyntax.ValidIdentifier(scopeType.Name) {
+			return nil, fmt.Errorf("%s: %q is not a valid scope type name: it must start with a letter, and have only letters, digits, underscores and dashes", block.DefRange, scopeType.Name)
+		}
+		if contains(names, scopeType.Name) {
+			return nil, fmt.Errorf("%s: scope type %q is declared more than once", block.DefRange, scopeType.Name)
+		}
+		names = append(names, scopeType.Name)
+	}
+
+	if len(decoded.Backends) > 1 {
+		return nil, fmt.Errorf("%s: a project can only have one backend", block.DefRange)
+	}
+	if len(decoded.Backends) == 1 {
+		body, ok := decoded.Backends[0].Config.(*hclsyntax.Body)
+		if !ok {
+			return nil, fmt.Errorf("%s: the backend must be written in HCL native syntax", block.DefRange)
+		}
+		project.Backend = &BackendTemplate{Type: decoded.Backends[0].Type, Body: body}
+	}
+	return project, nil
+}
+
+// projectPath returns the given path relative to the given project directory,
+// or the given default if it's empty.
+func projectPath(dir, path, defaultPath string) string {
+	if len(path) == 0 {
+		path = defaultPath
+	}
+	if filepath.IsAbs(path) {
+		return filepath.Clean(path)
+	}
+	return filepath.Join(dir, path)
+}
+
+// FindProjectFile returns the path of the nearest terrascope.hcl that declares
+// a project, in the given directory or any of its parents.
+func FindProjectFile(dir string) (string, error) {
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return "", err
+	}
+	for {
+		filename := filepath.Join(dir, ProjectFilename)
+		if isProjectFile(filename) {
+			return filename, nil
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return "", fmt.Errorf("no %s with a project block found in this directory or any of its parents", ProjectFilename)
+		}
+		dir = parent
+	}
+}
+
+// isProjectFile returns whether the given file exists and has a project
+// block. Roots have files of the same name, with root blocks instead.
+func isProjectFile(filename string) bool {
+	if _, err := os.Stat(filename); err != nil {
+		return false
+	}
+	// a file with errors is still parsed as far as it can be, and
+	// LoadProject reports the errors
+	f, _ := hclparse.NewParser().ParseHCLFile(filename)
+	if f == nil {
+		return false
+	}
+	content, _, _ := f.Body.PartialContent(projectFileSchema)
+	for _, block := range content.Blocks {
+		if block.Type == "project" {
+			return true
+		}
+	}
+	return false
+}
+
+// ScopeTypeNames returns the names of the receiver's scope types, from the
+// outermost in.
+func (p *Project) ScopeTypeNames() []string {
+	names := make([]string, len(p.ScopeTypes))
+	for i, scopeType := range p.ScopeTypes {
+		names[i] = scopeType.Name
+	}
+	return names
+}
+
+// LoadRoot reads the root configured by the terrascope.hcl in the given
+// directory. Its scopes must be the first of the receiver's scope types, in
+// order.
+func (p *Project) LoadRoot(dir string) (*Root, error) {
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return nil, err
+	}
+	parser := hclparse.NewParser()
+	f, diags := parser.ParseHCLFile(filepath.Join(dir, ProjectFilename))
+	if err := handleDiags(diags, parser.Files(), nil); err != nil {
+		return nil, err
+	}
+	content, diags := f.Body.Content(projectFileSchema)
+	if err := handleDiags(diags, parser.Files(), nil); err != nil {
+		return nil, err
+	}
+	var block *hcl.Block
+	for _, b := range content.Blocks {
+		if b.Type == "root" {
+			block = b
+			break
+		}
+	}
+	if block == nil {
+		return nil, fmt.Errorf("%s has no root block", filepath.Join(dir, ProjectFilename))
+	}
+
+	decoded := &rootBlock{}
+	diags = gohcl.DecodeBody(block.Body, nil, decoded)
+	if err := handleDiags(diags, parser.Files(), nil); err != nil {
+		return nil, err
+	}
+	root := &Root{Name: block.Labels[0], Dir: dir, Scopes: decoded.Scopes}
+	if root.Scopes == nil {
+		root.Scopes = []string{}
+	}
+
+	if err := p.checkRootScopes(root); err != nil {
+		return nil, fmt.Errorf("%s: %w", block.DefRange, err)
+	}
+	return root, nil
+}
+
+// checkRootScopes returns an error if the given root's scopes aren't the
+// first of the receiver's scope types, in order.
+func (p *Project) checkRootScopes(root *Root) error {
+	typeNames := p.ScopeTypeNames()
+	if len(root.Scopes) > len(typeNames) || strings.Join(root.Scopes, separator) != strings.Join(typeNames[:len(root.Scopes)], separator) {
+		return fmt.Errorf("the scopes of root %s must be the first of the project's scope types, in order (%s)", root.Name, strings.Join(typeNames, ", "))
+	}
+	return nil
+}
+
+// Roots returns the roots in the receiver's roots directory, sorted by name.
+// A root is a directory with a terrascope.hcl that has a root block.
+func (p *Project) Roots() ([]*Root, error) {
+	roots := make([]*Root, 0)
+	err := filepath.WalkDir(p.RootsDir, func(fullpath string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if entry.IsDir() {
+			if entry.Name() == ".terraform" || fullpath == p.BuildDir {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if entry.Name() != ProjectFilename || isProjectFile(fullpath) {
+			return nil
+		}
+		root, err := p.LoadRoot(filepath.Dir(fullpath))
+		if err != nil {
+			return err
+		}
+		roots = append(roots, root)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(roots, func(i, j int) bool {
+		return roots[i].Name < roots[j].Name
+	})
+	for i := 1; i < len(roots); i++ {
+		if roots[i].Name == roots[i-1].Name {
+			return nil, fmt.Errorf("root %s is declared in both %s and %s", roots[i].Name, roots[i-1].Dir, roots[i].Dir)
+		}
+	}
+	return roots, nil
+}
+
+// FindRoot returns the receiver's root with the given name, or the root in
+// the given directory.
+func (p *Project) FindRoot(name string) (*Root, error) {
+	if _, err := os.Stat(filepath.Join(name, ProjectFilename)); err == nil {
+		return p.LoadRoot(name)
+	}
+	roots, err := p.Roots()
+	if err != nil {
+		return nil, err
+	}
+	for _, root := range roots {
+		if root.Name == name {
+			return root, nil
+		}
+	}
+	return nil, fmt.Errorf("no root named %s in %s", name, p.RootsDir)
+}
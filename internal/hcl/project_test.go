@@ -0,0 +1,256 @@
+package hcl
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+)
+
+const acmeProject = "../../fixtures/projects/acme/terrascope.hcl"
+
+func TestLoadProject(t *testing.T) {
+	project, err := LoadProject(acmeProject)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if project.Name != "acme" {
+		t.Errorf("Expected name acme, got %s", project.Name)
+	}
+	if types := strings.Join(project.ScopeTypeNames(), ", "); types != "org, platform, domain, environment, region" {
+		t.Errorf("Expected scope types org, platform, domain, environment, region, got %s", types)
+	}
+	if filepath.Base(project.RootsDir) != "roots" || filepath.Base(project.BuildDir) != ".terrascope" {
+		t.Errorf("Expected the roots and build directories to be roots and .terrascope, got %s and %s", project.RootsDir, project.BuildDir)
+	}
+	if project.Backend == nil || project.Backend.Type != "s3" {
+		t.Errorf("Expected an s3 backend, got %+v", project.Backend)
+	}
+
+	roots, err := project.Roots()
+	if err != nil {
+		t.Fatal(err)
+	}
+	names := make([]string, len(roots))
+	for i, root := range roots {
+		names[i] = root.Name
+	}
+	if strings.Join(names, ", ") != "app, legacy, network" {
+		t.Errorf("Expected roots app, legacy, network, got %v", names)
+	}
+
+	for _, name := range []string{"my.org", "2fa", "org name"} {
+		filename := filepath.Join(t.TempDir(), ProjectFilename)
+		if err := os.WriteFile(filename, []byte(fmt.Sprintf("project \"acme\" {\n  scope %q {}\n}\n", name)), 0644); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := LoadProject(filename); err == nil || !strings.Contains(err.Error(), "not a valid scope type name") {
+			t.Errorf("Expected scope type %q to be invalid, got %v", name, err)
+		}
+	}
+
+	found, err := FindProjectFile("../../fixtures/projects/acme/roots/network")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected, _ := filepath.Abs(acmeProject); found != expected {
+		t.Errorf("Expected to find %s, got %s", expected, found)
+	}
+}
+
+func TestLoadRootScopes(t *testing.T) {
+	project, err := LoadProject(acmeProject)
+	if err != nil {
+		t.Fatal(err)
+	}
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, ProjectFilename), []byte(`root "odd" {
+  scopes = ["org", "domain"]
+}
+`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	_, err = project.LoadRoot(dir)
+	if err == nil || !strings.Contains(err.Error(), "must be the first of the project's scope types") {
+		t.Errorf("Expected an error about the root's scopes, got %v", err)
+	}
+}
+
+func TestScopeData(t *testing.T) {
+	project, err := LoadProject(acmeProject)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := project.LoadScopeData()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	addresses := func(values []*ScopeValue) string {
+		s := make([]string, len(values))
+		for i, value := range values {
+			s[i] = value.Address()
+		}
+		return strings.Join(s, ", ")
+	}
+	if actual := addresses(data.ValuesAt(2)); actual != "acme.gold, acme.silver" {
+		t.Errorf("Expected the platforms acme.gold, acme.silver, got %s", actual)
+	}
+
+	type test struct {
+		filters  []string
+		expected string
+	}
+	tests := []test{
+		{filters: []string{}, expected: "acme.gold.product.dev, acme.gold.product.prod, acme.silver.product.dev"},
+		{filters: []string{"acme.gold.*.*"}, expected: "acme.gold.product.dev, acme.gold.product.prod"},
+		{filters: []string{"acme.silver"}, expected: "acme.silver.product.dev"},
+		{filters: []string{"*.*.*.prod", "*.silver"}, expected: "acme.gold.product.prod, acme.silver.product.dev"},
+		{filters: []string{"acme.bronze.*.*"}, expected: ""},
+	}
+	for _, tc := range tests {
+		t.Run(strings.Join(tc.filters, " "), func(t *testing.T) {
+			filters := make([]ScopeFilter, len(tc.filters))
+			for i, arg := range tc.filters {
+				filters[i], err = ParseScopeFilter(arg)
+				if err != nil {
+					t.Fatal(err)
+				}
+			}
+			if actual := addresses(data.Match(4, filters)); actual != tc.expected {
+				t.Errorf("Expected %s, got %s", tc.expected, actual)
+			}
+		})
+	}
+
+	prod := data.Find("acme.gold.product.prod")
+	if prod == nil {
+		t.Fatal("Expected to find acme.gold.product.prod")
+	}
+	attrs := prod.AllAttributes()
+	if attrs["region"].AsString() != "us-east-1" || attrs["cidr"].AsString() != "10.0.0.0/16" {
+		t.Errorf("Expected prod to override region and inherit cidr, got %s and %s", FormatValue(attrs["region"]), FormatValue(attrs["cidr"]))
+	}
+
+	if _, err := ParseScopeFilter("acme.[gold"); err == nil {
+		t.Error("Expected an error for a malformed filter")
+	}
+}
+
+func TestScopeDataTypes(t *testing.T) {
+	project, err := LoadProject(acmeProject)
+	if err != nil {
+		t.Fatal(err)
+	}
+	dir := t.TempDir()
+	project.ScopeDataFiles = []string{filepath.Join(dir, "data.hcl")}
+	if err := os.WriteFile(project.ScopeDataFiles[0], []byte(`scope "org" "acme" {
+  scope "domain" "product" {}
+}
+`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := project.LoadScopeData(); err == nil {
+		t.Error("Expected an error for a domain inside an org")
+	}
+}
+
+func TestBuildRoot(t *testing.T) {
+	project, err := LoadProject(acmeProject)
+	if err != nil {
+		t.Fatal(err)
+	}
+	project.BuildDir = t.TempDir()
+	data, err := project.LoadScopeData()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	type test struct {
+		root          string
+		scope         string
+		expectedFiles map[string][]string
+		missingFiles  []string
+	}
+	tests := []test{
+		{
+			root:  "network",
+			scope: "acme.silver",
+			expectedFiles: map[string][]string{
+				"main.tf":              {`source = "./subnets"`},
+				"subnets/main.tf":      {"cidrsubnets"},
+				BuildVariablesFilename: {`cidr     = "10.1.0.0/16"`, `org      = "acme"`, `platform = "silver"`},
+				BuildBackendFilename:   {`backend "s3"`, `key    = "network/acme/silver/terraform.tfstate"`},
+			},
+			missingFiles: []string{ProjectFilename},
+		},
+		{
+			root:  "app",
+			scope: "acme.gold.product.prod",
+			expectedFiles: map[string][]string{
+				BuildVariablesFilename: {`account_id  = "222222222222"`, `environment = "prod"`, `region      = "us-east-1"`},
+			},
+		},
+		{
+			root:         "legacy",
+			scope:        "",
+			missingFiles: []string{BuildVariablesFilename, BuildBackendFilename},
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.root, func(t *testing.T) {
+			root, err := project.FindRoot(tc.root)
+			if err != nil {
+				t.Fatal(err)
+			}
+			module := NewModule(logrus.StandardLogger())
+			if err := module.ParseModuleDirectory(root.Dir); err != nil {
+				t.Fatal(err)
+			}
+			dir, err := project.BuildRoot(root, module, data.Find(tc.scope))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if expected := filepath.Join(append([]string{project.BuildDir, tc.root}, strings.Split(tc.scope, separator)...)...); dir != expected {
+				t.Errorf("Expected the build in %s, got %s", expected, dir)
+			}
+			for name, expected := range tc.expectedFiles {
+				contents, err := os.ReadFile(filepath.Join(dir, name))
+				if err != nil {
+					t.Fatal(err)
+				}
+				for _, line := range expected {
+					if !strings.Contains(string(contents), line) {
+						t.Errorf("Expected %s to have %q, got\n%s", name, line, contents)
+					}
+				}
+			}
+			if tc.root == "network" {
+				// the copy is elsewhere, so the module outside the root is
+				// found from there
+				labels, _ := filepath.Abs("../../fixtures/projects/acme/modules/labels")
+				source, _ := filepath.Rel(dir, labels)
+				contents, _ := os.ReadFile(filepath.Join(dir, "main.tf"))
+				if !strings.Contains(string(contents), fmt.Sprintf("source = %q", source)) {
+					t.Errorf("Expected main.tf to call %s, got\n%s", source, contents)
+				}
+			}
+			for _, name := range tc.missingFiles {
+				if _, err := os.Stat(filepath.Join(dir, name)); err == nil {
+					t.Errorf("Expected no %s", name)
+				}
+			}
+		})
+	}
+
+	root, err := project.FindRoot("app")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := project.BuildRoot(root, NewModule(logrus.StandardLogger()), data.Find("acme.gold")); err == nil {
+		t.Error("Expected an error building app for a platform")
+	}
+}
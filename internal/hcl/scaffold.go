@@ -0,0 +1,252 @@
+package hcl
+
+import (
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/hclsyntax"
+	"github.com/hashicorp/hcl/v2/hclwrite"
+	"github.com/zclconf/go-cty/cty"
+)
+
+// RootBackendFilename is the name of the file that a new root's backend is
+// written to.
+const RootBackendFilename = "backend.tf"
+
+// NewProjectFile returns a terrascope.hcl for a new project with the given
+// name, roots directory, scope data file and scope types. It has an example
+// backend, commented out.
+func NewProjectFile(name, rootsDir, scopeData string, scopes []string) ([]byte, error) {
+	if len(name) == 0 {
+		return nil, fmt.Errorf("a project must have a name")
+	}
+	for i, scope := range scopes {
+		if !hclsyntax.ValidIdentifier(scope) {
+			return nil, fmt.Errorf("%q is not a valid scope type name: it must start with a letter, and have only letters, digits, underscores and dashes", scope)
+		}
+		if contains(scopes[:i], scope) {
+			return nil, fmt.Errorf("scope type %q is given more than once", scope)
+		}
+	}
+
+	file := hclwrite.NewEmptyFile()
+	body := file.Body().AppendNewBlock("project", []string{name}).Body()
+	body.SetAttributeValue("roots_dir", cty.StringVal(filepath.ToSlash(rootsDir)))
+	body.SetAttributeValue("scope_data", cty.ListVal([]cty.Value{cty.StringVal(filepath.ToSlash(scopeData))}))
+	body.AppendNewline()
+	for _, scope := range scopes {
+		body.AppendBlock(emptyBlock("scope", scope))
+	}
+	body.AppendNewline()
+	appendComment(body, "The backend of each built root. Its arguments can use `root` (the root's",
+		"name), `path` (the root's directory), `scope` (e.g. `scope."+firstOr(scopes, "org")+"`) and",
+		"`scope_path` (the names of the root's scope value, joined by slashes).",
+		"",
+		`backend "s3" {`,
+		`  bucket = "terraform-state"`,
+		`  key    = "${root}/${scope_path}/terraform.tfstate"`,
+		`}`)
+	return hclwrite.Format(file.Bytes()), nil
+}
+
+// NewScopeDataFile returns a scope data file for a new project with the given
+// scope types. It has no values, just a comment with an example of them.
+func NewScopeDataFile(scopes []string) []byte {
+	file := hclwrite.NewEmptyFile()
+	lines := []string{"Each scope value is a block inside a value of the scope type before it:", ""}
+	for i, scope := range scopes {
+		indent := strings.Repeat("  ", i)
+		if i == len(scopes)-1 {
+			lines = append(lines, fmt.Sprintf("%sscope %q \"NAME\" {}", indent, scope))
+			break
+		}
+		lines = append(lines, fmt.Sprintf("%sscope %q \"NAME\" {", indent, scope))
+	}
+	for i := len(scopes) - 2; i >= 0; i-- {
+		lines = append(lines, strings.Repeat("  ", i)+"}")
+	}
+	appendComment(file.Body(), lines...)
+	return file.Bytes()
+}
+
+// appendComment appends a comment with the given lines to the given body.
+func appendComment(body *hclwrite.Body, lines ...string) {
+	tokens := make(hclwrite.Tokens, len(lines))
+	for i, line := range lines {
+		tokens[i] = &hclwrite.Token{Type: hclsyntax.TokenComment, Bytes: []byte(strings.TrimRight("# "+line, " ") + "\n")}
+	}
+	body.AppendUnstructuredTokens(tokens)
+}
+
+func firstOr(elems []string, fallback string) string {
+	if len(elems) == 0 {
+		return fallback
+	}
+	return elems[0]
+}
+
+// RequiredProvider is a provider that a new root requires.
+type RequiredProvider struct {
+	// Name is the provider's local name, e.g. "aws".
+	Name string
+	// Source is the provider's source address, e.g. "hashicorp/aws".
+	Source string
+	// Version is the exact version the root requires.
+	Version string
+}
+
+// MostCommonProviderVersions returns each provider in the given lockfiles,
+// with the version that the most of them lock it to. Ties go to the newest
+// version. A provider is named for its type, or for its namespace and type if
+// another provider of the same type is locked more. The providers are sorted
+// by name.
+func MostCommonProviderVersions(lockfiles []*Lockfile) []*RequiredProvider {
+	counts := make(map[string]map[string]int)
+	for _, lockfile := range lockfiles {
+		counted := make([]string, 0, len(lockfile.Providers))
+		for _, provider := range lockfile.Providers {
+			if contains(counted, provider.ID) {
+				continue
+			}
+			counted = append(counted, provider.ID)
+			if counts[provider.ID] == nil {
+				counts[provider.ID] = make(map[string]int)
+			}
+			counts[provider.ID][provider.Version]++
+		}
+	}
+
+	// when two providers have the same type, the one that more lockfiles lock
+	// gets the type as its name
+	ids := sortedKeys(counts)
+	totals := make(map[string]int, len(ids))
+	for _, id := range ids {
+		for _, count := range counts[id] {
+			totals[id] += count
+		}
+	}
+	sort.SliceStable(ids, func(i, j int) bool {
+		return totals[ids[i]] > totals[ids[j]]
+	})
+
+	providers := make([]*RequiredProvider, 0, len(counts))
+	names := make([]string, 0, len(counts))
+	for _, id := range ids {
+		var best string
+		for _, version := range sortedKeys(counts[id]) {
+			if len(best) == 0 || counts[id][version] > counts[id][best] || (counts[id][version] == counts[id][best] && newerVersion(version, best)) {
+				best = version
+			}
+		}
+		source := strings.TrimPrefix(id, defaultRegistryHost+"/")
+		parts := strings.Split(source, "/")
+		name := parts[len(parts)-1]
+		if contains(names, name) {
+			name = parts[len(parts)-2] + "-" + name
+		}
+		names = append(names, name)
+		providers = append(providers, &RequiredProvider{Name: name, Source: source, Version: best})
+	}
+	sort.Slice(providers, func(i, j int) bool {
+		return providers[i].Name < providers[j].Name
+	})
+	return providers
+}
+
+// newerVersion returns whether version a is newer than version b. Versions
+// that can't be parsed are compared as strings.
+func newerVersion(a, b string) bool {
+	va, errA := parseSemver(a)
+	vb, errB := parseSemver(b)
+	if errA != nil || errB != nil {
+		return a > b
+	}
+	return va.compare(vb) > 0
+}
+
+// NewRoot returns a new root in the receiver's roots directory with the
+// given name and scopes, after checking that it can be made: the scopes must
+// be the first of the receiver's scope types, in order, and no other root can
+// have the name.
+func (p *Project) NewRoot(name string, scopes []string) (*Root, error) {
+	if len(name) == 0 || name == "." || name == ".." || strings.ContainsAny(name, `/\`) {
+		return nil, fmt.Errorf("%q is not a valid root name", name)
+	}
+	root := &Root{Name: name, Dir: filepath.Join(p.RootsDir, name), Scopes: scopes}
+	if err := p.checkRootScopes(root); err != nil {
+		return nil, err
+	}
+	if isDirectory(root.Dir) {
+		return nil, fmt.Errorf("%s already exists", root.Dir)
+	}
+	roots, err := p.Roots()
+	if err != nil {
+		return nil, err
+	}
+	for _, other := range roots {
+		if other.Name == name {
+			return nil, fmt.Errorf("root %s already exists, in %s", name, other.Dir)
+		}
+	}
+	return root, nil
+}
+
+// ScaffoldRoot returns the files of the given new root (see NewRoot), keyed
+// by name:
+//   - terrascope.hcl, with the root's scopes.
+//   - main.tf, with a variable for each scope type.
+//   - versions.tf, which requires the given providers, if there are any.
+//   - backend.tf, with the receiver's backend, if it has one. Arguments that
+//     depend on the root's scope value are left for `root build` to fill in.
+func (p *Project) ScaffoldRoot(root *Root, providers []*RequiredProvider) (map[string][]byte, error) {
+	files := make(map[string][]byte)
+
+	config := hclwrite.NewEmptyFile()
+	scopes := make([]cty.Value, len(root.Scopes))
+	for i, scope := range root.Scopes {
+		scopes[i] = cty.StringVal(scope)
+	}
+	scopesValue := cty.ListValEmpty(cty.String)
+	if len(scopes) > 0 {
+		scopesValue = cty.ListVal(scopes)
+	}
+	config.Body().AppendNewBlock("root", []string{root.Name}).Body().SetAttributeValue("scopes", scopesValue)
+	files[ProjectFilename] = config.Bytes()
+
+	main := hclwrite.NewEmptyFile()
+	for i, name := range root.Scopes {
+		if i > 0 {
+			main.Body().AppendNewline()
+		}
+		variable := main.Body().AppendNewBlock("variable", []string{name}).Body()
+		if description := p.ScopeTypes[i].Description; len(description) > 0 {
+			variable.SetAttributeValue("description", cty.StringVal(description))
+		}
+		variable.SetAttributeTraversal("type", hcl.Traversal{hcl.TraverseRoot{Name: "string"}})
+	}
+	files["main.tf"] = hclwrite.Format(main.Bytes())
+
+	if len(providers) > 0 {
+		versions := hclwrite.NewEmptyFile()
+		required := versions.Body().AppendNewBlock("terraform", nil).Body().AppendNewBlock("required_providers", nil).Body()
+		for _, provider := range providers {
+			required.SetAttributeValue(provider.Name, cty.ObjectVal(map[string]cty.Value{
+				"source":  cty.StringVal(provider.Source),
+				"version": cty.StringVal(provider.Version),
+			}))
+		}
+		files["versions.tf"] = hclwrite.Format(versions.Bytes())
+	}
+
+	if p.Backend != nil {
+		backend, err := p.newRootBackend(root)
+		if err != nil {
+			return nil, err
+		}
+		files[RootBackendFilename] = backend
+	}
+	return files, nil
+}
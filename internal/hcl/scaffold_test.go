@@ -0,0 +1,156 @@
+package hcl
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/hcl/v2/hclparse"
+	"github.com/sirupsen/logrus"
+)
+
+func TestNewProjectFile(t *testing.T) {
+	src, err := NewProjectFile("acme", "roots", "data.hcl", []string{"org", "platform"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	filename := filepath.Join(t.TempDir(), ProjectFilename)
+	if err := os.WriteFile(filename, src, 0644); err != nil {
+		t.Fatal(err)
+	}
+	project, err := LoadProject(filename)
+	if err != nil {
+		t.Fatalf("%v, in\n%s", err, src)
+	}
+	if project.Name != "acme" || strings.Join(project.ScopeTypeNames(), ",") != "org,platform" || project.Backend != nil {
+		t.Errorf("Expected project acme with scopes org,platform and no backend, got\n%s", src)
+	}
+
+	for _, scopes := range [][]string{{"org", "org"}, {"org", "2fa"}} {
+		if _, err := NewProjectFile("acme", "roots", "data.hcl", scopes); err == nil {
+			t.Errorf("Expected an error for scopes %v", scopes)
+		}
+	}
+
+	data := NewScopeDataFile([]string{"org", "platform"})
+	if _, diags := hclparse.NewParser().ParseHCL(data, "data.hcl"); diags.HasErrors() {
+		t.Errorf("Expected a valid scope data file, got %v in\n%s", diags, data)
+	}
+}
+
+func TestMostCommonProviderVersions(t *testing.T) {
+	lockfile := func(providers ...string) *Lockfile {
+		lf := &Lockfile{}
+		for _, provider := range providers {
+			parts := strings.Split(provider, "@")
+			lf.Providers = append(lf.Providers, &LockfileProvider{ID: parts[0], Version: parts[1]})
+		}
+		return lf
+	}
+	lockfiles := []*Lockfile{
+		lockfile("registry.terraform.io/hashicorp/aws@5.1.0", "registry.terraform.io/hashicorp/random@3.5.0"),
+		lockfile("registry.terraform.io/hashicorp/aws@5.1.0", "registry.terraform.io/hashicorp/random@3.6.0"),
+		// a lockfile only counts once for each provider
+		lockfile("registry.terraform.io/hashicorp/aws@5.2.0", "registry.terraform.io/hashicorp/aws@5.2.0", "registry.terraform.io/hashicorp/aws@5.2.0"),
+		lockfile("registry.terraform.io/acme/aws@0.1.0"),
+	}
+
+	actual := MostCommonProviderVersions(lockfiles)
+	expected := []RequiredProvider{
+		{Name: "acme-aws", Source: "acme/aws", Version: "0.1.0"},
+		{Name: "aws", Source: "hashicorp/aws", Version: "5.1.0"},
+		{Name: "random", Source: "hashicorp/random", Version: "3.6.0"},
+	}
+	if len(actual) != len(expected) {
+		t.Fatalf("Expected %d providers, got %d", len(expected), len(actual))
+	}
+	for i := range expected {
+		if *actual[i] != expected[i] {
+			t.Errorf("Expected %+v, got %+v", expected[i], *actual[i])
+		}
+	}
+}
+
+func TestScaffoldRoot(t *testing.T) {
+	project, err := LoadProject(acmeProject)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, tc := range []struct {
+		name          string
+		scopes        []string
+		expectedError string
+	}{
+		{name: "network", scopes: []string{"org"}, expectedError: "already exists"},
+		{name: "dns/zones", scopes: []string{"org"}, expectedError: "not a valid root name"},
+		{name: "dns", scopes: []string{"platform"}, expectedError: "scopes"},
+	} {
+		if _, err := project.NewRoot(tc.name, tc.scopes); err == nil || !strings.Contains(err.Error(), tc.expectedError) {
+			t.Errorf("Expected an error with %q for %s, got %v", tc.expectedError, tc.name, err)
+		}
+	}
+
+	project.RootsDir = t.TempDir()
+	project.BuildDir = t.TempDir()
+	root, err := project.NewRoot("dns", []string{"org", "platform"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	files, err := project.ScaffoldRoot(root, []*RequiredProvider{{Name: "aws", Source: "hashicorp/aws", Version: "5.1.0"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expectedFiles := map[string][]string{
+		ProjectFilename:     {`root "dns"`, `scopes = ["org", "platform"]`},
+		"main.tf":           {`variable "platform"`, `description = "the company, which has the Organization account"`, `type        = string`},
+		"versions.tf":       {`source  = "hashicorp/aws"`, `version = "5.1.0"`},
+		RootBackendFilename: {`backend "s3"`, `region = "us-west-2"`},
+	}
+	for name, expected := range expectedFiles {
+		for _, line := range expected {
+			if !strings.Contains(string(files[name]), line) {
+				t.Errorf("Expected %s to have %q, got\n%s", name, line, files[name])
+			}
+		}
+	}
+	if strings.Contains(string(files[RootBackendFilename]), "bucket") {
+		t.Errorf("Expected the bucket to be left for the build, got\n%s", files[RootBackendFilename])
+	}
+
+	// the new root can be built, and the build overrides its backend
+	if err := os.MkdirAll(root.Dir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	for name, contents := range files {
+		if err := os.WriteFile(filepath.Join(root.Dir, name), contents, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if root, err = project.LoadRoot(root.Dir); err != nil {
+		t.Fatal(err)
+	}
+	data, err := project.LoadScopeData()
+	if err != nil {
+		t.Fatal(err)
+	}
+	module := NewModule(logrus.StandardLogger())
+	if err := module.ParseModuleDirectory(root.Dir); err != nil {
+		t.Fatal(err)
+	}
+	dir, err := project.BuildRoot(root, module, data.Find("acme.gold"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	override, err := os.ReadFile(filepath.Join(dir, BuildBackendOverrideFilename))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(override), `bucket = "acme-terraform-state"`) {
+		t.Errorf("Expected the override to have the bucket, got\n%s", override)
+	}
+	if _, err := os.Stat(filepath.Join(dir, BuildBackendFilename)); err == nil {
+		t.Errorf("Expected no %s", BuildBackendFilename)
+	}
+}
@@ -0,0 +1,333 @@
+package hcl
+
+import (
+	"fmt"
+	"path"
+	"strings"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/hclparse"
+	"github.com/hashicorp/hcl/v2/hclsyntax"
+	"github.com/zclconf/go-cty/cty"
+)
+
+// scopeDataSchema is the schema of a scope data file, and of the body of each
+// scope value in it.
+var scopeDataSchema = &hcl.BodySchema{
+	Blocks: []hcl.BlockHeaderSchema{
+		{Type: "scope", LabelNames: []string{"type", "name"}},
+	},
+}
+
+// scopeNameReservedChars are the characters a scope value's name can't have,
+// because they separate the names in an address or a build path, or match
+// them in a filter.
+const scopeNameReservedChars = ".*?[]/\\"
+
+// ScopeValue is one value of a scope type, like the "acme" organization or
+// the "dev" environment. It is inside a value of the scope type before its
+// own, and has values of the scope type after its own inside it.
+type ScopeValue struct {
+	Type string
+	Name string
+	// Parent is the value this one is inside. The outermost values' parent is
+	// the top of the hierarchy, which has no type or name.
+	Parent   *ScopeValue
+	Children []*ScopeValue
+	// Attributes are the arguments of the value's block. See AllAttributes
+	// for the ones it inherits.
+	Attributes map[string]cty.Value
+	// DefRanges are the places the value's block is declared. A value can be
+	// declared more than once, for instance in different files.
+	DefRanges []hcl.Range
+}
+
+// ScopeData is the hierarchy of a project's scope values.
+type ScopeData struct {
+	// Types are the names of the project's scope types.
+	Types []string
+	top   *ScopeValue
+}
+
+// LoadScopeData reads the receiver's scope data files. Each value must be of
+// the scope type after its parent's, and its name must not have any of
+// `.*?[]/\`.
+func (p *Project) LoadScopeData() (*ScopeData, error) {
+	data := &ScopeData{
+		Types: p.ScopeTypeNames(),
+		top:   &ScopeValue{Attributes: make(map[string]cty.Value)},
+	}
+	parser := hclparse.NewParser()
+	for _, filename := range p.ScopeDataFiles {
+		f, diags := parser.ParseHCLFile(filename)
+		if err := handleDiags(diags, parser.Files(), nil); err != nil {
+			return nil, err
+		}
+		content, diags := f.Body.Content(scopeDataSchema)
+		if err := handleDiags(diags, parser.Files(), nil); err != nil {
+			return nil, err
+		}
+		for _, block := range content.Blocks {
+			diags = diags.Extend(data.decode(data.top, block))
+		}
+		if err := handleDiags(diags, parser.Files(), nil); err != nil {
+			return nil, err
+		}
+	}
+	return data, nil
+}
+
+// decode adds the scope value declared by the given block (and the values
+// inside it) to the given parent.
+func (d *ScopeData) decode(parent *ScopeValue, block *hcl.Block) hcl.Diagnostics {
+	depth := parent.Depth()
+	scopeType, name := block.Labels[0], block.Labels[1]
+	if depth >= len(d.Types) {
+		return hcl.Diagnostics{{
+			Severity: hcl.DiagError,
+			Summary:  "Unexpected scope",
+			Detail:   fmt.Sprintf("%s %q can't be inside %s, which is of the innermost scope type", scopeType, name, parent.Address()),
+			Subject:  block.DefRange.Ptr(),
+		}}
+	}
+	if scopeType != d.Types[depth] {
+		detail := fmt.Sprintf("the outermost scope values must be of type %q, not %q", d.Types[depth], scopeType)
+		if depth > 0 {
+			detail = fmt.Sprintf("the scope values inside %s must be of type %q, not %q", parent.Address(), d.Types[depth], scopeType)
+		}
+		return hcl.Diagnostics{{
+			Severity: hcl.DiagError,
+			Summary:  "Unexpected scope type",
+			Detail:   detail,
+			Subject:  block.LabelRanges[0].Ptr(),
+		}}
+	}
+	if len(name) == 0 || strings.ContainsAny(name, scopeNameReservedChars) {
+		return hcl.Diagnostics{{
+			Severity: hcl.DiagError,
+			Summary:  "Invalid scope name",
+			Detail:   fmt.Sprintf("a scope value's name must not be empty, or have any of %q", scopeNameReservedChars),
+			Subject:  block.LabelRanges[1].Ptr(),
+		}}
+	}
+
+	value := parent.child(name)
+	if value == nil {
+		value = &ScopeValue{
+			Type:       scopeType,
+			Name:       name,
+			Parent:     parent,
+			Attributes: make(map[string]cty.Value),
+		}
+		parent.Children = append(parent.Children, value)
+	}
+	value.DefRanges = append(value.DefRanges, block.DefRange)
+
+	// the body has both attributes and blocks, which the schema-based API
+	// can't read together without knowing the attributes' names
+	body := block.Body.(*hclsyntax.Body)
+	var diags hcl.Diagnostics
+	ctx := &hcl.EvalContext{Functions: functions()}
+	for _, name := range sortedKeys(body.Attributes) {
+		attr := body.Attributes[name]
+		if _, ok := value.Attributes[name]; ok {
+			diags = diags.Append(&hcl.Diagnostic{
+				Severity: hcl.DiagError,
+				Summary:  "Duplicate attribute",
+				Detail:   fmt.Sprintf("%s already has a value for %s", value.Address(), name),
+				Subject:  attr.NameRange.Ptr(),
+			})
+			continue
+		}
+		attrValue, valueDiags := attr.Expr.Value(ctx)
+		diags = diags.Extend(valueDiags)
+		value.Attributes[name] = attrValue
+	}
+	for _, nested := range body.Blocks {
+		if nested.Type != "scope" || len(nested.Labels) != 2 {
+			diags = diags.Append(&hcl.Diagnostic{
+				Severity: hcl.DiagError,
+				Summary:  "Unexpected block",
+				Detail:   `only scope blocks, with a type and a name (like scope "environment" "dev"), can be inside a scope value`,
+				Subject:  nested.DefRange().Ptr(),
+			})
+			continue
+		}
+		diags = diags.Extend(d.decode(value, nested.AsHCLBlock()))
+	}
+	return diags
+}
+
+// child returns the value inside the receiver with the given name, or nil if
+// there isn't one.
+func (v *ScopeValue) child(name string) *ScopeValue {
+	for _, child := range v.Children {
+		if child.Name == name {
+			return child
+		}
+	}
+	return nil
+}
+
+// Depth returns how many values the receiver is inside of, including itself:
+// 1 for the outermost values.
+func (v *ScopeValue) Depth() int {
+	depth := 0
+	for value := v; value.Parent != nil; value = value.Parent {
+		depth++
+	}
+	return depth
+}
+
+// Path returns the values the receiver is inside of, from the outermost in,
+// ending with the receiver.
+func (v *ScopeValue) Path() []*ScopeValue {
+	values := make([]*ScopeValue, v.Depth())
+	value := v
+	for i := len(values) - 1; i >= 0; i-- {
+		values[i] = value
+		value = value.Parent
+	}
+	return values
+}
+
+// Names returns the names of the receiver's path.
+func (v *ScopeValue) Names() []string {
+	path := v.Path()
+	names := make([]string, len(path))
+	for i, value := range path {
+		names[i] = value.Name
+	}
+	return names
+}
+
+// Address returns the names of the receiver's path, joined by dots, e.g.
+// "acme.gold.product".
+func (v *ScopeValue) Address() string {
+	return strings.Join(v.Names(), separator)
+}
+
+// AllAttributes returns the receiver's attributes, including the ones it
+// inherits from the values it is inside of. A value's own attributes replace
+// the ones it would inherit.
+func (v *ScopeValue) AllAttributes() map[string]cty.Value {
+	attrs := make(map[string]cty.Value)
+	for _, value := range v.Path() {
+		for name, attr := range value.Attributes {
+			attrs[name] = attr
+		}
+	}
+	return attrs
+}
+
+// Values returns the object that each of the receiver's path's types has
+// its value's name in, e.g. `{ org = "acme", platform = "gold" }`.
+func (v *ScopeValue) Values() cty.Value {
+	values := make(map[string]cty.Value)
+	for _, value := range v.Path() {
+		values[value.Type] = cty.StringVal(value.Name)
+	}
+	return cty.ObjectVal(values)
+}
+
+// Find returns the value with the given address, or nil if there isn't one.
+func (d *ScopeData) Find(address string) *ScopeValue {
+	value := d.top
+	if len(address) == 0 {
+		return value
+	}
+	for _, name := range strings.Split(address, separator) {
+		value = value.child(name)
+		if value == nil {
+			return nil
+		}
+	}
+	return value
+}
+
+// All returns every value in the hierarchy, each followed by the values
+// inside it, in the order they are declared.
+func (d *ScopeData) All() []*ScopeValue {
+	values := make([]*ScopeValue, 0)
+	var walk func(*ScopeValue)
+	walk = func(value *ScopeValue) {
+		for _, child := range value.Children {
+			values = append(values, child)
+			walk(child)
+		}
+	}
+	walk(d.top)
+	return values
+}
+
+// ValuesAt returns the values of the given number of scope types deep, in
+// the order they are declared. The values of depth 0 are just the top of
+// the hierarchy, which has no type or name.
+func (d *ScopeData) ValuesAt(depth int) []*ScopeValue {
+	values := []*ScopeValue{d.top}
+	for i := 0; i < depth; i++ {
+		children := make([]*ScopeValue, 0)
+		for _, value := range values {
+			children = append(children, value.Children...)
+		}
+		values = children
+	}
+	return values
+}
+
+// Match returns the values of the given depth that match any of the given
+// filters, in the order they are declared. With no filters, every value of
+// the depth matches.
+func (d *ScopeData) Match(depth int, filters []ScopeFilter) []*ScopeValue {
+	values := d.ValuesAt(depth)
+	if len(filters) == 0 {
+		return values
+	}
+	matches := make([]*ScopeValue, 0, len(values))
+	for _, value := range values {
+		for _, filter := range filters {
+			if filter.Match(value) {
+				matches = append(matches, value)
+				break
+			}
+		}
+	}
+	return matches
+}
+
+// ScopeFilter matches scope values by their address. Each of its parts is a
+// pattern (see path.Match) for the name of the value of the same depth, e.g.
+// "acme.gold.*.*" matches every value inside the gold platform.
+type ScopeFilter []string
+
+// ParseScopeFilter parses the given dot-separated filter. An empty filter
+// matches every value.
+func ParseScopeFilter(s string) (ScopeFilter, error) {
+	if len(s) == 0 {
+		return ScopeFilter{}, nil
+	}
+	filter := ScopeFilter(strings.Split(s, separator))
+	for _, pattern := range filter {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return nil, fmt.Errorf("%q is not a valid scope filter: %w", s, err)
+		}
+	}
+	return filter, nil
+}
+
+// Match returns whether the given value matches the receiver. Only the parts
+// of the filter that the value has names for are compared, so "acme.gold.*"
+// matches both "acme.gold" and "acme.gold.product.dev".
+func (f ScopeFilter) Match(v *ScopeValue) bool {
+	names := v.Names()
+	for i := 0; i < len(f) && i < len(names); i++ {
+		if ok, _ := path.Match(f[i], names[i]); !ok {
+			return false
+		}
+	}
+	return true
+}
+
+func (f ScopeFilter) String() string {
+	return strings.Join(f, separator)
+}
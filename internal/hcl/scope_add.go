@@ -0,0 +1,111 @@
+package hcl
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/hclwrite"
+	"github.com/zclconf/go-cty/cty"
+)
+
+// NewValue returns a new scope value with the given address, inside the
+// value it names as its parent, after checking that it can be added: its
+// parent must exist and it must not, it can't be deeper than the receiver's
+// scope types, and its name must be valid. The new value isn't added to the
+// receiver.
+func (d *ScopeData) NewValue(address string) (*ScopeValue, error) {
+	names := strings.Split(address, separator)
+	if len(names) > len(d.Types) {
+		return nil, fmt.Errorf("%q has %d parts, but there are only %d scope types (%s)", address, len(names), len(d.Types), strings.Join(d.Types, ", "))
+	}
+	name := names[len(names)-1]
+	if len(name) == 0 || strings.ContainsAny(name, scopeNameReservedChars) {
+		return nil, fmt.Errorf("%q is not a valid name: a scope value's name must not be empty, or have any of %q", name, scopeNameReservedChars)
+	}
+	parentAddress := strings.Join(names[:len(names)-1], separator)
+	parent := d.Find(parentAddress)
+	if parent == nil {
+		return nil, fmt.Errorf("%s %q doesn't exist, so there is nothing to add %q to", d.Types[len(names)-2], parentAddress, name)
+	}
+	if existing := parent.child(name); existing != nil {
+		return nil, fmt.Errorf("%s %q already exists, at %s", existing.Type, address, existing.DefRanges[0])
+	}
+	return &ScopeValue{
+		Type:       d.Types[len(names)-1],
+		Name:       name,
+		Parent:     parent,
+		Attributes: make(map[string]cty.Value),
+	}, nil
+}
+
+// Sibling returns the value with the given name that is inside the same
+// value as the receiver, or nil if there isn't one.
+func (v *ScopeValue) Sibling(name string) *ScopeValue {
+	return v.Parent.child(name)
+}
+
+// ScopeDataFile returns the data file that a new value inside the given
+// parent belongs in: the first file the parent is declared in, or the
+// receiver's first data file for an outermost value.
+func (p *Project) ScopeDataFile(parent *ScopeValue) string {
+	if len(parent.DefRanges) > 0 {
+		return parent.DefRanges[0].Filename
+	}
+	return p.ScopeDataFiles[0]
+}
+
+// AddScopeValue adds a block for the given new value (see NewValue), with its
+// own attributes, to the given data file source, and returns the result. The
+// block goes at the end of the first block of the value's parent in the
+// file. The rest of the file keeps its comments, and its formatting if it is
+// formatted like `terraform fmt` would.
+func AddScopeValue(src []byte, filename string, value *ScopeValue) ([]byte, error) {
+	file, diags := hclwrite.ParseConfig(src, filename, hcl.InitialPos)
+	if diags.HasErrors() {
+		return nil, diags
+	}
+
+	body := file.Body()
+	for _, parent := range value.Parent.Path() {
+		var found *hclwrite.Block
+		for _, block := range body.Blocks() {
+			labels := block.Labels()
+			if block.Type() == "scope" && len(labels) == 2 && labels[0] == parent.Type && labels[1] == parent.Name {
+				found = block
+				break
+			}
+		}
+		if found == nil {
+			return nil, fmt.Errorf("%s doesn't declare %s %q", filename, parent.Type, parent.Address())
+		}
+		body = found.Body()
+	}
+
+	// a body written as `{}` needs a newline before the block too, or the
+	// block would be on the same line as the body's brace
+	if len(body.Attributes())+len(body.Blocks()) > 0 || !strings.Contains(string(body.BuildTokens(nil).Bytes()), "\n") {
+		body.AppendNewline()
+	}
+	if len(value.Attributes) == 0 {
+		body.AppendBlock(emptyBlock("scope", value.Type, value.Name))
+		return file.Bytes(), nil
+	}
+	block := body.AppendNewBlock("scope", []string{value.Type, value.Name})
+	for _, name := range sortedKeys(value.Attributes) {
+		block.Body().SetAttributeValue(name, value.Attributes[name])
+	}
+	return file.Bytes(), nil
+}
+
+// emptyBlock returns a block of the given type and labels with an empty body,
+// written as `{}`. A block made with hclwrite.NewBlock always has a newline
+// in its body instead.
+func emptyBlock(typeName string, labels ...string) *hclwrite.Block {
+	header := typeName
+	for _, label := range labels {
+		header += fmt.Sprintf(" %q", label)
+	}
+	file, _ := hclwrite.ParseConfig([]byte(header+" {}\n"), "", hcl.InitialPos)
+	return file.Body().Blocks()[0]
+}
@@ -0,0 +1,148 @@
+package hcl
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/zclconf/go-cty/cty"
+)
+
+func TestNewScopeValue(t *testing.T) {
+	project, err := LoadProject(acmeProject)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := project.LoadScopeData()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	type test struct {
+		address       string
+		expectedType  string
+		expectedError string
+	}
+	tests := []test{
+		{address: "acme.gold.product.qa", expectedType: "environment"},
+		{address: "beta", expectedType: "org"},
+		{address: "acme.gold.product.dev.us-west-2", expectedType: "region"},
+		{address: "acme.gold.product.dev.us-west-2.a", expectedError: "only 5 scope types"},
+		{address: "acme.bronze.product", expectedError: `platform "acme.bronze" doesn't exist`},
+		{address: "acme.gold.product.dev", expectedError: "already exists"},
+		{address: "acme.gold.*", expectedError: "not a valid name"},
+		{address: "acme.gold.", expectedError: "not a valid name"},
+		{address: "acme.gold.product/dev", expectedError: "not a valid name"},
+	}
+	for _, tc := range tests {
+		t.Run(tc.address, func(t *testing.T) {
+			value, err := data.NewValue(tc.address)
+			if len(tc.expectedError) > 0 {
+				if err == nil || !strings.Contains(err.Error(), tc.expectedError) {
+					t.Errorf("Expected an error with %q, got %v", tc.expectedError, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if value.Type != tc.expectedType || value.Address() != tc.address {
+				t.Errorf("Expected %s %s, got %s %s", tc.expectedType, tc.address, value.Type, value.Address())
+			}
+		})
+	}
+}
+
+func TestAddScopeValue(t *testing.T) {
+	project, err := LoadProject(acmeProject)
+	if err != nil {
+		t.Fatal(err)
+	}
+	filename := filepath.Join(t.TempDir(), "data.hcl")
+	project.ScopeDataFiles = []string{filename}
+	src := `# the whole company
+scope "org" "acme" {
+  scope "platform" "gold" {
+    scope "domain" "product" {
+      # the developers' account
+      scope "environment" "dev" {
+        account_id = "111111111111" # not prod!
+      }
+    }
+  }
+}
+`
+	if err := os.WriteFile(filename, []byte(src), 0644); err != nil {
+		t.Fatal(err)
+	}
+	data, err := project.LoadScopeData()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	value, err := data.NewValue("acme.gold.product.qa")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for name, attr := range value.Sibling("dev").Attributes {
+		value.Attributes[name] = attr
+	}
+	value.Attributes["region"] = cty.StringVal("us-east-1")
+	if actual := project.ScopeDataFile(value.Parent); actual != filename {
+		t.Errorf("Expected the value to go in %s, got %s", filename, actual)
+	}
+	result, err := AddScopeValue([]byte(src), filename, value)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := strings.Replace(src, `        account_id = "111111111111" # not prod!
+      }
+`, `        account_id = "111111111111" # not prod!
+      }
+
+      scope "environment" "qa" {
+        account_id = "111111111111"
+        region     = "us-east-1"
+      }
+`, 1)
+	if string(result) != expected {
+		t.Errorf("Expected\n%s\ngot\n%s", expected, result)
+	}
+
+	value, err = data.NewValue("acme.silver")
+	if err != nil {
+		t.Fatal(err)
+	}
+	result, err = AddScopeValue([]byte(src), filename, value)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasSuffix(string(result), "  }\n\n  scope \"platform\" \"silver\" {}\n}\n") {
+		t.Errorf("Expected an empty platform at the end of acme, got\n%s", result)
+	}
+	if err := os.WriteFile(filename, result, 0644); err != nil {
+		t.Fatal(err)
+	}
+	data, err = project.LoadScopeData()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if data.Find("acme.silver") == nil {
+		t.Error("Expected to read acme.silver back")
+	}
+
+	// a parent written as `{}` gets its own lines for the new value
+	src = "scope \"org\" \"acme\" {}\n"
+	value, err = data.NewValue("acme.bronze")
+	if err != nil {
+		t.Fatal(err)
+	}
+	result, err = AddScopeValue([]byte(src), filename, value)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := "scope \"org\" \"acme\" {\n  scope \"platform\" \"bronze\" {}\n}\n"; string(result) != expected {
+		t.Errorf("Expected\n%s\ngot\n%s", expected, result)
+	}
+}
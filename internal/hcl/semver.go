@@ -0,0 +1,161 @@
+package hcl
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// versionPattern matches a semantic version, with an optional "v" prefix and
+// optional minor and patch numbers, e.g. "1.2.0", "v1.2" or "1.2.0-beta.1".
+var versionPattern = regexp.MustCompile(`^v?(\d+)(?:\.(\d+))?(?:\.(\d+))?(?:-([0-9A-Za-z.-]+))?(?:\+[0-9A-Za-z.-]+)?$`)
+
+// constraintPattern matches a single version constraint, e.g. "~> 1.2"
+var constraintPattern = regexp.MustCompile(`^(=|!=|>=|<=|>|<|~>)?\s*(\S+)$`)
+
+// semver is a parsed semantic version
+type semver struct {
+	numbers [3]int
+	// given is how many of the numbers were written, which matters for the
+	// pessimistic operator: "~> 1.2" allows 1.3, but "~> 1.2.0" doesn't.
+	given      int
+	prerelease string
+}
+
+func parseSemver(s string) (*semver, error) {
+	m := versionPattern.FindStringSubmatch(strings.TrimSpace(s))
+	if m == nil {
+		return nil, fmt.Errorf("%q is not a version", s)
+	}
+	v := &semver{prerelease: m[4]}
+	for i := 0; i < 3; i++ {
+		if len(m[i+1]) == 0 {
+			break
+		}
+		n, err := strconv.Atoi(m[i+1])
+		if err != nil {
+			return nil, err
+		}
+		v.numbers[i] = n
+		v.given++
+	}
+	return v, nil
+}
+
+// compare returns -1, 0 or 1 if the receiver is less than, equal to, or
+// greater than the other version. A prerelease is less than its release.
+func (v *semver) compare(other *semver) int {
+	for i := range v.numbers {
+		if v.numbers[i] != other.numbers[i] {
+			if v.numbers[i] < other.numbers[i] {
+				return -1
+			}
+			return 1
+		}
+	}
+	switch {
+	case v.prerelease == other.prerelease:
+		return 0
+	case len(v.prerelease) == 0:
+		return 1
+	case len(other.prerelease) == 0:
+		return -1
+	case v.prerelease < other.prerelease:
+		return -1
+	}
+	return 1
+}
+
+// isExactVersion returns whether the given version constraint allows only a
+// single version, e.g. "1.2.0" or "= 1.2.0".
+func isExactVersion(constraint string) bool {
+	m := constraintPattern.FindStringSubmatch(strings.TrimSpace(constraint))
+	if m == nil || (len(m[1]) > 0 && m[1] != "=") {
+		return false
+	}
+	v, err := parseSemver(m[2])
+	return err == nil && v.given == 3
+}
+
+// constraintAllows returns whether the given version constraint (a
+// comma-separated list, like in a `version` argument) allows the given
+// version.
+func constraintAllows(constraint, version string) (bool, error) {
+	v, err := parseSemver(version)
+	if err != nil {
+		return false, err
+	}
+	for _, part := range strings.Split(constraint, ",") {
+		m := constraintPattern.FindStringSubmatch(strings.TrimSpace(part))
+		if m == nil {
+			return false, fmt.Errorf("%q is not a version constraint", part)
+		}
+		bound, err := parseSemver(m[2])
+		if err != nil {
+			return false, err
+		}
+		cmp := v.compare(bound)
+
+		allowed := false
+		switch m[1] {
+		case "", "=":
+			allowed = cmp == 0
+		case "!=":
+			allowed = cmp != 0
+		case ">":
+			allowed = cmp > 0
+		case ">=":
+			allowed = cmp >= 0
+		case "<":
+			allowed = cmp < 0
+		case "<=":
+			allowed = cmp <= 0
+		case "~>":
+			// only the rightmost given number may increase
+			upper := &semver{numbers: bound.numbers}
+			if bound.given <= 1 {
+				upper.numbers = [3]int{bound.numbers[0] + 1, 0, 0}
+			} else {
+				upper.numbers[bound.given-2]++
+				for i := bound.given - 1; i < 3; i++ {
+					upper.numbers[i] = 0
+				}
+			}
+			allowed = cmp >= 0 && v.compare(upper) < 0
+		}
+		if !allowed {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+// SortVersions sorts the given versions, or version constraints, e.g.
+// "1.9.0", "v1.10.0" or "~> 1.2", by the versions they name, oldest first.
+// Those that don't name a single version come last, sorted as strings.
+func SortVersions(versions []string) {
+	parsed := make(map[string]*semver, len(versions))
+	for _, version := range versions {
+		m := constraintPattern.FindStringSubmatch(strings.TrimSpace(version))
+		if m == nil {
+			continue
+		}
+		if v, err := parseSemver(m[2]); err == nil {
+			parsed[version] = v
+		}
+	}
+	sort.SliceStable(versions, func(i, j int) bool {
+		vi, vj := parsed[versions[i]], parsed[versions[j]]
+		switch {
+		case vi != nil && vj != nil:
+			if cmp := vi.compare(vj); cmp != 0 {
+				return cmp < 0
+			}
+		case vi != nil || vj != nil:
+			return vi != nil
+		}
+		return versions[i] < versions[j]
+	})
+}
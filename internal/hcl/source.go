@@ -0,0 +1,196 @@
+package hcl
+
+import (
+	"errors"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/hashicorp/terraform-config-inspect/tfconfig"
+)
+
+// These are the kinds of module source.
+const (
+	SourceLocal    = "local"
+	SourceRegistry = "registry"
+	SourceGit      = "git"
+	SourceOther    = "other"
+)
+
+// defaultRegistryHost is the host of registry sources that don't name one
+const defaultRegistryHost = "registry.terraform.io"
+
+// registrySourcePattern matches registry sources, with or without a host,
+// e.g. "hashicorp/consul/aws" or "app.terraform.io/acme/vpc/aws//modules/x".
+var registrySourcePattern = regexp.MustCompile(`^(([a-zA-Z0-9.-]+\.[a-zA-Z]+)/)?([a-zA-Z0-9_-]+)/([a-zA-Z0-9_-]+)/([a-zA-Z0-9]+)(//.*)?$`)
+
+// scpGitPattern matches git sources in the scp-like syntax, e.g.
+// "git@github.com:acme/modules.git".
+var scpGitPattern = regexp.MustCompile(`^[a-zA-Z0-9_.-]+@([a-zA-Z0-9.-]+):(.+)$`)
+
+// ModuleSource is a module call's source, normalized so that calls of the
+// same module can be compared no matter how they wrote the source.
+type ModuleSource struct {
+	// Address is the normalized source:
+	//   - local paths are relative to the base directory, and start with
+	//     "./" (or "../"), e.g. "./modules/vpc".
+	//   - registry sources include their host, e.g.
+	//     "registry.terraform.io/hashicorp/consul/aws".
+	//   - git sources have no scheme, user, ".git" suffix or query, e.g.
+	//     "github.com/acme/modules//vpc".
+	Address string `json:"address"`
+	// Kind is the kind of source, e.g. "registry" or "git".
+	Kind string `json:"kind"`
+	// Version is the version constraint of a registry source, or the ref of a
+	// git source.
+	Version string `json:"version,omitempty"`
+}
+
+func (s *ModuleSource) String() string {
+	if len(s.Version) == 0 {
+		return s.Address
+	}
+	return s.Address + "@" + s.Version
+}
+
+// localModuleAddress returns the normalized address of the module at the
+// given directory: its path relative to baseDir, joined by slashes, starting
+// with "./" or "../". baseDir itself is ".". A directory that can't be made
+// relative to baseDir keeps its own (cleaned) path.
+func localModuleAddress(dir, baseDir string) string {
+	rel, err := filepath.Rel(baseDir, dir)
+	if err != nil {
+		return filepath.ToSlash(filepath.Clean(dir))
+	}
+	address := filepath.ToSlash(rel)
+	if address == "." || address == ".." || strings.HasPrefix(address, "../") {
+		return address
+	}
+	return "./" + address
+}
+
+// NormalizeModuleSource returns the normalized form of a module call's
+// source and version. Local paths are resolved against callerDir, and made
+// relative to baseDir.
+func NormalizeModuleSource(source, version, callerDir, baseDir string) *ModuleSource {
+	if isLocalSource(source) {
+		return &ModuleSource{Address: localModuleAddress(filepath.Join(callerDir, source), baseDir), Kind: SourceLocal}
+	}
+
+	if gitSource, ref, ok := normalizeGitSource(source); ok {
+		return &ModuleSource{Address: gitSource, Kind: SourceGit, Version: ref}
+	}
+
+	if m := registrySourcePattern.FindStringSubmatch(source); m != nil {
+		host := strings.ToLower(m[2])
+		if len(host) == 0 {
+			host = defaultRegistryHost
+		}
+		address := strings.ToLower(strings.Join([]string{host, m[3], m[4], m[5]}, "/")) + m[6]
+		return &ModuleSource{Address: address, Kind: SourceRegistry, Version: version}
+	}
+
+	return &ModuleSource{Address: source, Kind: SourceOther, Version: version}
+}
+
+// normalizeGitSource returns the normalized address of a git source, and
+// its ref. If the source isn't a git source, it returns false.
+func normalizeGitSource(source string) (string, string, bool) {
+	isGit := false
+	if strings.HasPrefix(source, "git::") {
+		source = strings.TrimPrefix(source, "git::")
+		isGit = true
+	}
+	for _, host := range []string{"github.com/", "bitbucket.org/"} {
+		if strings.HasPrefix(source, host) {
+			source = "https://" + source
+			isGit = true
+		}
+	}
+	if m := scpGitPattern.FindStringSubmatch(source); m != nil && !strings.Contains(source, "://") {
+		source = "ssh://" + m[1] + "/" + m[2]
+		isGit = true
+	}
+	if !isGit {
+		return "", "", false
+	}
+
+	u, err := url.Parse(source)
+	if err != nil {
+		return "", "", false
+	}
+	ref := u.Query().Get("ref")
+
+	repo, subdir, _ := strings.Cut(u.Path, "//")
+	repo = strings.TrimSuffix(strings.Trim(repo, "/"), ".git")
+	address := strings.ToLower(u.Hostname()) + "/" + repo
+	if len(subdir) > 0 {
+		address += "//" + strings.Trim(subdir, "/")
+	}
+	return address, ref, true
+}
+
+// ParseModuleSourceArg reads a module source given on the command line, with
+// an optional version after an "@", e.g. "hashicorp/consul/aws@0.1.0". Local
+// paths are resolved against the working directory, and made relative to
+// baseDir.
+func ParseModuleSourceArg(arg, baseDir string) (*ModuleSource, error) {
+	source, version := arg, ""
+	if i := strings.LastIndex(arg, "@"); i >= 0 && !strings.ContainsAny(arg[i+1:], "/:") {
+		source, version = arg[:i], arg[i+1:]
+	}
+	if len(source) == 0 {
+		return nil, errors.New("no module source given")
+	}
+
+	callerDir, err := filepath.Abs(".")
+	if err != nil {
+		return nil, err
+	}
+	// a path without "./", like "modules/vpc"
+	if !isLocalSource(source) && !filepath.IsAbs(source) && isDirectory(source) {
+		source = "./" + source
+	}
+	normalized := NormalizeModuleSource(source, "", callerDir, baseDir)
+	normalized.Version = version
+	return normalized, nil
+}
+
+func isDirectory(path string) bool {
+	info, err := os.Stat(path)
+	return err == nil && info.IsDir()
+}
+
+// ModuleCallSite is a module call in a particular directory.
+type ModuleCallSite struct {
+	// Dir is the directory of the calling module.
+	Dir    string               `json:"dir"`
+	Name   string               `json:"name"`
+	Source *ModuleSource        `json:"source"`
+	Pos    tfconfig.SourcePos   `json:"pos"`
+	Call   *tfconfig.ModuleCall `json:"-"`
+}
+
+// LoadModuleCalls returns the module calls in the module at the given
+// directory, sorted by name, with their sources normalized against baseDir.
+func LoadModuleCalls(dir, baseDir string) ([]*ModuleCallSite, error) {
+	module, diags := tfconfig.LoadModule(dir)
+	if diags.HasErrors() {
+		return nil, errors.New(diags.Error())
+	}
+	sites := make([]*ModuleCallSite, 0, len(module.ModuleCalls))
+	for _, call := range module.ModuleCalls {
+		sites = append(sites, &ModuleCallSite{
+			Dir:    dir,
+			Name:   call.Name,
+			Source: NormalizeModuleSource(call.Source, call.Version, dir, baseDir),
+			Pos:    call.Pos,
+			Call:   call,
+		})
+	}
+	sort.Slice(sites, func(i, j int) bool { return sites[i].Name < sites[j].Name })
+	return sites, nil
+}
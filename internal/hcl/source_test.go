@@ -0,0 +1,68 @@
+package hcl
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestNormalizeModuleSource(t *testing.T) {
+	type test struct {
+		source   string
+		version  string
+		expected string
+		kind     string
+	}
+	tests := []test{
+		{source: "../../modules/vpc", expected: "./modules/vpc", kind: SourceLocal},
+		{source: "./child", expected: "./roots/prod/child", kind: SourceLocal},
+		{source: "./child/../child/", expected: "./roots/prod/child", kind: SourceLocal},
+		{source: "./.", expected: "./roots/prod", kind: SourceLocal},
+		{source: "../..", expected: ".", kind: SourceLocal},
+		{source: "../../../shared/vpc", expected: "../shared/vpc", kind: SourceLocal},
+		{source: "../../..", expected: "..", kind: SourceLocal},
+		{source: "hashicorp/consul/aws", version: "0.1.0", expected: "registry.terraform.io/hashicorp/consul/aws@0.1.0", kind: SourceRegistry},
+		{source: "Registry.Terraform.io/HashiCorp/consul/aws", expected: "registry.terraform.io/hashicorp/consul/aws", kind: SourceRegistry},
+		{source: "app.terraform.io/acme/vpc/aws//modules/subnets", version: "~> 1.0", expected: "app.terraform.io/acme/vpc/aws//modules/subnets@~> 1.0", kind: SourceRegistry},
+		{source: "git::https://github.com/acme/modules.git//labels?ref=v1.2.0", expected: "github.com/acme/modules//labels@v1.2.0", kind: SourceGit},
+		{source: "github.com/acme/modules//labels?ref=v1.2.0", expected: "github.com/acme/modules//labels@v1.2.0", kind: SourceGit},
+		{source: "git@github.com:acme/modules.git//labels", expected: "github.com/acme/modules//labels", kind: SourceGit},
+		{source: "git::ssh://git@github.com/acme/modules.git?ref=main", expected: "github.com/acme/modules@main", kind: SourceGit},
+		{source: "s3::https://s3.amazonaws.com/acme/vpc.zip", expected: "s3::https://s3.amazonaws.com/acme/vpc.zip", kind: SourceOther},
+	}
+	for _, tc := range tests {
+		t.Run(tc.source, func(t *testing.T) {
+			actual := NormalizeModuleSource(tc.source, tc.version, "/repo/roots/prod", "/repo")
+			if actual.String() != tc.expected {
+				t.Errorf("Expected %s, got %s", tc.expected, actual)
+			}
+			if actual.Kind != tc.kind {
+				t.Errorf("Expected kind %s, got %s", tc.kind, actual.Kind)
+			}
+		})
+	}
+}
+
+func TestLoadModuleCalls(t *testing.T) {
+	baseDir, err := filepath.Abs("../../fixtures/monorepo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	sites, err := LoadModuleCalls(filepath.Join(baseDir, "roots/staging"), baseDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []string{
+		"app: ./modules/app",
+		"consul: registry.terraform.io/hashicorp/consul/aws@~> 0.2.0",
+		"labels: github.com/acme/terraform-modules//labels@v1.3.0",
+	}
+	if len(sites) != len(expected) {
+		t.Fatalf("Expected %d module calls, got %d", len(expected), len(sites))
+	}
+	for i, site := range sites {
+		if actual := site.Name + ": " + site.Source.String(); actual != expected[i] {
+			t.Errorf("Expected %s, got %s", expected[i], actual)
+		}
+	}
+}
@@ -0,0 +1,235 @@
+package hcl
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+)
+
+// State represents the parts of a Terraform state file (format version 4)
+// that we use.
+type State struct {
+	Version          int              `json:"version"`
+	TerraformVersion string           `json:"terraform_version"`
+	Resources        []*StateResource `json:"resources"`
+}
+
+// StateResource represents one resource recorded in a State, and its
+// instances.
+type StateResource struct {
+	Module    string           `json:"module"`
+	Mode      string           `json:"mode"`
+	Type      string           `json:"type"`
+	Name      string           `json:"name"`
+	Provider  string           `json:"provider"`
+	Instances []*StateInstance `json:"instances"`
+}
+
+// StateInstance represents one instance of a StateResource.
+type StateInstance struct {
+	IndexKey     interface{} `json:"index_key"`
+	Dependencies []string    `json:"dependencies"`
+}
+
+// StateDrift describes the differences between a module's configuration and
+// the resources recorded in its state.
+type StateDrift struct {
+	// Orphans are resources recorded in the state that are not in the
+	// configuration.
+	Orphans []string
+	// Uncreated are resources in the configuration that are not recorded in
+	// the state.
+	Uncreated []string
+	// MissingDependencies maps a resource to the dependencies it has in the
+	// configuration, but not in the state.
+	MissingDependencies map[string][]string
+	// ExtraDependencies maps a resource to the dependencies it has in the
+	// state, but not in the configuration.
+	ExtraDependencies map[string][]string
+}
+
+// ParseStateFile reads a local Terraform state file.
+func ParseStateFile(filename string) (*State, error) {
+	b, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	state := &State{}
+	if err := json.Unmarshal(b, state); err != nil {
+		return nil, fmt.Errorf("could not read state %s: %w", filename, err)
+	}
+	if state.Version != 4 {
+		return nil, fmt.Errorf("%s has state version %d, but only version 4 is supported", filename, state.Version)
+	}
+	return state, nil
+}
+
+// Address returns the receiver's full address, including its module path.
+func (sr *StateResource) Address() string {
+	addr := Address{Kind: kindResource, Type: sr.Type, Name: sr.Name}
+	if sr.Mode == "data" {
+		addr.Kind = kindData
+	}
+	address := addr.String()
+	if len(sr.Module) > 0 {
+		address = sr.Module + separator + address
+	}
+	return address
+}
+
+// NodeAddress returns the address of the configuration graph node the
+// receiver belongs to. Anything inside a child module belongs to the node of
+// the module call.
+func (sr *StateResource) NodeAddress() string {
+	return stateNodeAddress(sr.Address())
+}
+
+// InstanceAddress returns the full address of the given instance of the
+// receiver, e.g. `random_string.this["a"]`.
+func (sr *StateResource) InstanceAddress(instance *StateInstance) string {
+	switch key := instance.IndexKey.(type) {
+	case string:
+		return fmt.Sprintf("%s%s%q%s", sr.Address(), indexLeft, key, indexRight)
+	case float64:
+		return fmt.Sprintf("%s%s%d%s", sr.Address(), indexLeft, int(key), indexRight)
+	}
+	return sr.Address()
+}
+
+// stateNodeAddress trims a resource address (as found in a state's
+// dependency list) down to the address of its configuration graph node.
+func stateNodeAddress(address string) string {
+	if !strings.HasPrefix(address, "module"+separator) {
+		return address
+	}
+	parts := strings.SplitN(address, separator, 3)
+	return ParseAddress(parts[0] + separator + parts[1]).Base().String()
+}
+
+// Graph returns a graph of the resource instances recorded in the receiver.
+// Each instance depends on every instance of the resources in its
+// dependency list.
+func (s *State) Graph() *Graph {
+	instances := make(map[string][]string)
+	for _, sr := range s.Resources {
+		for _, instance := range sr.Instances {
+			instances[sr.Address()] = append(instances[sr.Address()], sr.InstanceAddress(instance))
+		}
+	}
+
+	graph := newGraph()
+	for _, sr := range s.Resources {
+		for _, instance := range sr.Instances {
+			deps := make([]string, 0, len(instance.Dependencies))
+			for _, dep := range instance.Dependencies {
+				if depInstances, ok := instances[dep]; ok {
+					deps = append(deps, depInstances...)
+				} else {
+					deps = append(deps, dep)
+				}
+			}
+			graph.AddNode(sr.InstanceAddress(instance), unique(deps))
+		}
+	}
+	// a dependency on something that has no instances still deserves a node
+	for _, name := range graph.Nodes() {
+		for _, dep := range graph.Dependencies(name) {
+			if !graph.Has(dep) {
+				graph.AddNode(dep, []string{})
+			}
+		}
+	}
+	return graph
+}
+
+// Drift compares the receiver to the given configuration graph. Only managed
+// resources and module calls are compared, since those are what Terraform
+// records dependencies between.
+func (s *State) Drift(config *Graph) *StateDrift {
+	drift := &StateDrift{
+		Orphans:             make([]string, 0),
+		Uncreated:           make([]string, 0),
+		MissingDependencies: make(map[string][]string),
+		ExtraDependencies:   make(map[string][]string),
+	}
+
+	// map from config node to the dependencies recorded in state
+	recorded := make(map[string][]string)
+	for _, sr := range s.Resources {
+		if sr.Mode != "managed" {
+			continue
+		}
+		node := sr.NodeAddress()
+		if !config.Has(node) {
+			drift.Orphans = append(drift.Orphans, sr.Address())
+			continue
+		}
+		// resources inside a child module depend on things inside that
+		// module, which the configuration graph can't see.
+		if len(sr.Module) > 0 {
+			if _, ok := recorded[node]; !ok {
+				recorded[node] = make([]string, 0)
+			}
+			continue
+		}
+		for _, instance := range sr.Instances {
+			for _, dep := range instance.Dependencies {
+				dep = stateNodeAddress(dep)
+				if isManagedResource(dep) || strings.HasPrefix(dep, "module"+separator) {
+					recorded[node] = append(recorded[node], dep)
+				}
+			}
+		}
+		if _, ok := recorded[node]; !ok {
+			recorded[node] = make([]string, 0)
+		}
+	}
+
+	for _, name := range config.Nodes() {
+		if !isManagedResource(name) {
+			continue
+		}
+		stateDeps, ok := recorded[name]
+		if !ok {
+			drift.Uncreated = append(drift.Uncreated, name)
+			continue
+		}
+		stateDeps = unique(stateDeps)
+		configDeps := resourceDependencies(config, name)
+		if missing := setSubtract(configDeps, stateDeps); len(missing) > 0 {
+			drift.MissingDependencies[name] = missing
+		}
+		if extra := setSubtract(stateDeps, configDeps); len(extra) > 0 {
+			drift.ExtraDependencies[name] = extra
+		}
+	}
+
+	sort.Strings(drift.Orphans)
+	drift.Orphans = unique(drift.Orphans)
+	return drift
+}
+
+// resourceDependencies returns the managed resources and module calls that
+// the given node depends on, directly or transitively. This mirrors the
+// dependency list Terraform records in state.
+func resourceDependencies(g *Graph, name string) []string {
+	found := make([]string, 0)
+	visited := map[string]bool{name: true}
+	queue := append([]string{}, g.Dependencies(name)...)
+	for len(queue) > 0 {
+		next := queue[0]
+		queue = queue[1:]
+		if visited[next] {
+			continue
+		}
+		visited[next] = true
+		if isManagedResource(next) || strings.HasPrefix(next, "module"+separator) {
+			found = append(found, next)
+		}
+		queue = append(queue, g.Dependencies(next)...)
+	}
+	sort.Strings(found)
+	return found
+}
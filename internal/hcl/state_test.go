@@ -0,0 +1,59 @@
+package hcl
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+)
+
+func TestStateGraph(t *testing.T) {
+	state, err := ParseStateFile("../../fixtures/states/mapped-resource.tfstate")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	actualGraph, err := state.Graph().DOT()
+	if err != nil {
+		t.Fatal(err)
+	}
+	expectedGraph := `digraph G {
+	"random_pet.old"->"random_string.this[\"a\"]";
+	"random_pet.old";
+	"random_string.this[\"a\"]";
+	"random_string.this[\"b\"]";
+
+}`
+	if strings.TrimSpace(actualGraph) != strings.TrimSpace(expectedGraph) {
+		t.Logf("Expected: %s", expectedGraph)
+		t.Logf("Actual:   %s", actualGraph)
+		t.Error("Actual graph did not match expected graph.")
+	}
+}
+
+func TestStateDrift(t *testing.T) {
+	parser := NewModule(logrus.StandardLogger())
+	if err := parser.ParseModuleDirectory("../../fixtures/roots/mapped-resource"); err != nil {
+		t.Fatal(err)
+	}
+	config, err := parser.Graph()
+	if err != nil {
+		t.Fatal(err)
+	}
+	state, err := ParseStateFile("../../fixtures/states/mapped-resource.tfstate")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	drift := state.Drift(config)
+	expected := &StateDrift{
+		Orphans:             []string{"random_pet.old"},
+		Uncreated:           []string{},
+		MissingDependencies: map[string][]string{},
+		ExtraDependencies:   map[string][]string{"random_string.this": {"random_pet.old"}},
+	}
+	if !reflect.DeepEqual(drift, expected) {
+		t.Errorf("Expected drift %+v, got %+v", expected, drift)
+	}
+}
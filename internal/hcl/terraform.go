@@ -4,17 +4,19 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
-	"math/big"
 	"os"
 	"path"
+	"strconv"
 	"strings"
 
-	"github.com/awalterschulze/gographviz"
 	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/gohcl"
 	"github.com/hashicorp/hcl/v2/hclparse"
+	"github.com/hashicorp/hcl/v2/hclsyntax"
 	"github.com/hashicorp/terraform-config-inspect/tfconfig"
 	"github.com/sirupsen/logrus"
-	"github.com/zclconf/go-cty/cty/gocty"
+	"github.com/zclconf/go-cty/cty"
+	"github.com/zclconf/go-cty/cty/convert"
 )
 
 const separator = "."
@@ -35,18 +37,32 @@ var configFileSchema = &hcl.BodySchema{
 		{Type: "module", LabelNames: []string{"name"}},
 		{Type: "resource", LabelNames: []string{"id", "name"}},
 		{Type: "data", LabelNames: []string{"type", "name"}},
+		{Type: "moved"},
+		{Type: "import"},
+		{Type: "removed"},
 	},
 }
 
+// refactorBlockTypes are the types of block that describe changes to the
+// state, rather than objects in the module.
+var refactorBlockTypes = []string{"moved", "import", "removed"}
+
 type configuration struct {
-	locals map[string]*hcl.Attribute
-	blocks map[string]*hcl.Block
+	locals map[Address]*hcl.Attribute
+	blocks map[Address]*hcl.Block
+	// terraform blocks configure terraform itself, so they aren't part of
+	// the graph.
+	terraform []*hcl.Block
+	// moved, import and removed blocks aren't part of the graph either.
+	refactors []*hcl.Block
 }
 
 func newConfiguration() *configuration {
 	return &configuration{
-		locals: make(map[string]*hcl.Attribute, 0),
-		blocks: make(map[string]*hcl.Block, 0),
+		locals:    make(map[Address]*hcl.Attribute, 0),
+		blocks:    make(map[Address]*hcl.Block, 0),
+		terraform: make([]*hcl.Block, 0),
+		refactors: make([]*hcl.Block, 0),
 	}
 }
 
@@ -59,6 +75,15 @@ type Module interface {
 	Parser() *hclparse.Parser
 	ParseTerraformFile(string) error
 	DependencyGraph() (string, error)
+	Graph() (*Graph, error)
+	InstanceGraph() (*Graph, error)
+	ExpandedGraph(InputValues) (*Graph, error)
+	Unused() ([]*Finding, error)
+	Describe() (*ModuleDescription, error)
+	Refactors() (*Refactors, error)
+	ParseVariableFile(string) (InputValues, error)
+	Evaluator(InputValues) (*Evaluator, error)
+	CheckVariables(InputValues) ([]*Finding, error)
 }
 
 type module struct {
@@ -88,23 +113,53 @@ func (m *module) ParseModuleDirectory(dirname string) error {
 	if err != nil {
 		return err
 	}
+	// like terraform, read every primary file before any override file, so
+	// that the overrides have something to apply to.
+	primary := make([]string, 0, len(files))
+	override := make([]string, 0)
 	for _, f := range files {
-		if f.IsDir() {
+		if f.IsDir() || !isConfigFile(f.Name()) {
 			continue
 		}
-		if strings.HasSuffix(f.Name(), ".tf") || strings.HasSuffix(f.Name(), ".hcl") {
-			fullname := path.Join(dirname, f.Name())
-			if err := m.ParseTerraformFile(fullname); err != nil {
-				return err
-			}
+		fullname := path.Join(dirname, f.Name())
+		if isOverrideFile(f.Name()) {
+			override = append(override, fullname)
+		} else {
+			primary = append(primary, fullname)
+		}
+	}
+	for _, filename := range append(primary, override...) {
+		if err := m.ParseTerraformFile(filename); err != nil {
+			return err
 		}
 	}
 	return nil
 }
 
-// ParseTerraformFile parses a single file
+// isConfigFile returns whether the given file name is a Terraform
+// configuration file, in either native (`.tf`) or JSON (`.tf.json`) syntax.
+func isConfigFile(name string) bool {
+	return strings.HasSuffix(name, ".tf") || strings.HasSuffix(name, ".tf.json")
+}
+
+// isOverrideFile returns whether the given configuration file name is an
+// override file, e.g. `override.tf` or `dev_override.tf.json`.
+func isOverrideFile(name string) bool {
+	base := strings.TrimSuffix(strings.TrimSuffix(path.Base(name), ".json"), ".tf")
+	return base == "override" || strings.HasSuffix(base, "_override")
+}
+
+// ParseTerraformFile parses a single file. JSON syntax is used for files
+// ending in `.tf.json`, and native syntax for everything else. The contents
+// of an override file are merged into what the receiver has already read.
 func (m *module) ParseTerraformFile(filename string) error {
-	file, diags := m.fundamental.ParseHCLFile(filename)
+	var file *hcl.File
+	var diags hcl.Diagnostics
+	if strings.HasSuffix(filename, ".json") {
+		file, diags = m.fundamental.ParseJSONFile(filename)
+	} else {
+		file, diags = m.fundamental.ParseHCLFile(filename)
+	}
 	if err := handleDiags(diags, m.fundamental.Files(), m.Logger.WriterLevel(logrus.WarnLevel)); err != nil {
 		return err
 	}
@@ -114,211 +169,452 @@ func (m *module) ParseTerraformFile(filename string) error {
 		return err
 	}
 
-	// read the contents into the receiver
+	if isOverrideFile(filename) {
+		return m.applyOverrides(content)
+	}
+
+	// read the contents into the receiver, reporting every duplicate
+	// declaration at once.
+	var duplicates hcl.Diagnostics
 	for _, block := range content.Blocks {
-		var diags hcl.Diagnostics
 		if block.Type == "locals" {
-			var locals hcl.Attributes
-			locals, diags = block.Body.JustAttributes()
+			locals, diags := block.Body.JustAttributes()
 			if err := handleDiags(diags, m.fundamental.Files(), m.Logger.WriterLevel(logrus.WarnLevel)); err != nil {
 				return err
 			}
 			for name, attr := range locals {
-				m.cfg.locals["local"+separator+name] = attr
+				addr := Address{Kind: kindLocal, Name: name}
+				if previous, ok := m.cfg.locals[addr]; ok {
+					duplicates = append(duplicates, duplicateDiagnostic(addr, attr.NameRange, previous.NameRange))
+					continue
+				}
+				m.cfg.locals[addr] = attr
 			}
+		} else if block.Type == "terraform" {
+			m.cfg.terraform = append(m.cfg.terraform, block)
+		} else if contains(refactorBlockTypes, block.Type) {
+			m.cfg.refactors = append(m.cfg.refactors, block)
 		} else {
-			var blockName string
-			if block.Type == "variable" {
-				blockName = "var" + separator
-			}
-			if block.Type == "module" {
-				blockName = "module" + separator
-			}
-			if block.Type == "output" {
-				blockName = "output" + separator
-			}
-			if block.Type == "data" {
-				blockName = "data" + separator
+			addr := blockAddress(block)
+			if previous, ok := m.cfg.blocks[addr]; ok {
+				duplicates = append(duplicates, duplicateDiagnostic(addr, block.DefRange, previous.DefRange))
+				continue
 			}
-			blockName += strings.Join(block.Labels, separator)
-			m.cfg.blocks[blockName] = block
-		}
-		if err := handleDiags(diags, m.fundamental.Files(), m.Logger.WriterLevel(logrus.WarnLevel)); err != nil {
-			return err
+			m.cfg.blocks[addr] = block
 		}
 	}
 
-	return nil
+	return handleDiags(duplicates, m.fundamental.Files(), m.Logger.WriterLevel(logrus.WarnLevel))
+}
+
+// duplicateDiagnostic returns an error diagnostic for an object declared at
+// subject, which was already declared at previous.
+func duplicateDiagnostic(addr Address, subject, previous hcl.Range) *hcl.Diagnostic {
+	return &hcl.Diagnostic{
+		Severity: hcl.DiagError,
+		Summary:  fmt.Sprintf("Duplicate %s declaration", addr.Kind),
+		Detail:   fmt.Sprintf("%s was already declared at %s. Each %s in a module must have a unique address.", addr, previous, addr.Kind),
+		Subject:  subject.Ptr(),
+	}
+}
+
+// providerSchema picks out the parts of a provider block we need to name it
+var providerSchema = &hcl.BodySchema{
+	Attributes: []hcl.AttributeSchema{
+		{Name: "alias"},
+	},
+}
+
+// providerAddress returns the address of the provider configuration declared
+// by the given block, e.g. "provider.aws" or (if it has an alias)
+// "provider.aws.west".
+func providerAddress(block *hcl.Block) Address {
+	addr := Address{Kind: kindProvider, Type: block.Labels[0]}
+	content, _, _ := block.Body.PartialContent(providerSchema)
+	if attr, ok := content.Attributes["alias"]; ok {
+		var alias string
+		if diags := gohcl.DecodeExpression(attr.Expr, nil, &alias); !diags.HasErrors() {
+			addr.Name = alias
+		}
+	}
+	return addr
 }
 
 // DependencyGraph returns a DOT-format graph of the receiver
 func (m *module) DependencyGraph() (string, error) {
-	graph := make(map[string][]string, 0)
-	var err error
+	graph, err := m.Graph()
+	if err != nil {
+		return "", err
+	}
+	return graph.DOT()
+}
 
-	for name, local := range m.cfg.locals {
-		graph[name], err = attributeDependencies(local)
+// Graph returns a graph of the receiver's locals and blocks, and the
+// dependencies between them.
+func (m *module) Graph() (*Graph, error) {
+	return m.graph(false)
+}
+
+// InstanceGraph returns a graph like Graph does, except that references to a
+// particular instance of a resource or module call (e.g.
+// `aws_instance.web["a"]`) get a node of their own, which depends on the node
+// of the whole resource or module call.
+func (m *module) InstanceGraph() (*Graph, error) {
+	return m.graph(true)
+}
+
+func (m *module) graph(instances bool) (*Graph, error) {
+	graph := newGraph()
+
+	for addr, local := range m.cfg.locals {
+		refs, err := attributeDependencies(local, "")
 		if err != nil {
-			return "", err
+			return nil, err
 		}
+		m.addNode(graph, addr.String(), refs, instances)
 	}
 
-	for name, block := range m.cfg.blocks {
-		deps, err := blockDependencies(block)
+	for addr, block := range m.cfg.blocks {
+		refs, err := blockDependencies(block)
 		if err != nil {
-			return "", err
+			return nil, err
 		}
-		graph[name] = deps
+		refs = append(refs, implicitProviderDependency(block, refs)...)
+		m.addNode(graph, addr.String(), refs, instances)
+		graph.info(addr.String()).Iteration = blockIteration(block)
 	}
 
-	// dependencies go all the way through attribute names. For instance, an
-	// output.stack_name might depend on random_string.slug.result, but the
-	// m.cfg.blocks map only includes a "random_string.slug".
-	// So for each dependency we need to check: is it in our cfg? Or do we need
-	// to truncate it?
-	// Also, the downstreams here include things like "string" (the raw type
-	// used by a variable's "type" attribute) or "path.module", which is a
-	// Terraform builtin. So we only want to include things we recognize
-	for upstream, deps := range graph {
-		keepers := make([]string, 0, len(deps))
-		for _, downstream := range deps {
-			parts := strings.Split(downstream, separator)
-			for limit := len(parts); limit > 0; limit-- {
-				trial := strings.Join(parts[0:limit], separator)
-				if !m.Has(trial) {
-					continue
+	graphJSON, err := json.MarshalIndent(graph.dependencies, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	m.Debugf("dependencies:\n%s", string(graphJSON))
+
+	return graph, nil
+}
+
+// addNode adds a node to the graph, with an edge for each of the given
+// references that the receiver recognizes. If instances is true, references
+// to a particular instance of a resource or module call get a node of their
+// own.
+func (m *module) addNode(graph *Graph, name string, refs []*Reference, instances bool) {
+	info := graph.info(name)
+
+	keepers := make([]string, 0, len(refs))
+	for _, ref := range refs {
+		var prefix string
+		// provider configurations are referred to without a prefix, but only
+		// in these meta-arguments.
+		if ref.Attribute == "provider" || ref.Attribute == "providers" {
+			ref.Kind = kindProvider
+			prefix = "provider" + separator
+		}
+
+		switch ref.Kind {
+		case kindSelf, kindCount, kindEach, kindPath:
+			// these refer to the node itself or to facts about the
+			// configuration, so they aren't dependencies.
+			info.Builtins = append(info.Builtins, ref.Address)
+			continue
+		case kindTerraform:
+			info.Builtins = append(info.Builtins, ref.Address)
+			// the workspace is a node of its own, because so much can depend
+			// on it.
+			if strings.HasPrefix(ref.Address, workspaceAddress) {
+				if !graph.Has(workspaceAddress) {
+					graph.AddNode(workspaceAddress, []string{})
 				}
-				keepers = append(keepers, trial)
-				break
+				keepers = append(keepers, workspaceAddress)
+				graph.addReference(name, workspaceAddress, ref)
 			}
+			continue
+		case "":
+			continue
 		}
 
-		// and the resulting map might have dupes in the values, so uniq them.
-		graph[upstream] = unique(keepers)
+		node, base := m.resolve(prefix, ref.traversal, instances)
+		// a variable's validation refers to the variable itself, which isn't
+		// a dependency.
+		if len(node) == 0 || node == name {
+			continue
+		}
+		if node != base && !graph.Has(node) {
+			graph.AddNode(node, []string{base})
+		}
+		keepers = append(keepers, node)
+		graph.addReference(name, node, ref)
+		if ref.Attribute == "count" || ref.Attribute == "for_each" {
+			info.IteratesOver = append(info.IteratesOver, node)
+		}
 	}
 
-	graphJSON, err := json.MarshalIndent(graph, "", "  ")
-	if err != nil {
-		return "", err
+	info.Builtins = unique(info.Builtins)
+	info.IteratesOver = unique(info.IteratesOver)
+	if contains(info.Builtins, workspaceAddress) {
+		m.Debugf("%s uses %s", name, workspaceAddress)
 	}
-	m.Debugf("dependencies:\n%s", string(graphJSON))
 
-	graphAst, _ := gographviz.ParseString(`digraph G {}`)
-	dotGraph := gographviz.NewGraph()
-	if err := gographviz.Analyse(graphAst, dotGraph); err != nil {
-		return "", err
+	// and the resulting list might have dupes, so uniq them.
+	graph.AddNode(name, unique(keepers))
+}
+
+// repeatableKinds are the kinds of node (and, conveniently, the block types)
+// that can have count or for_each, and so can have instances.
+var repeatableKinds = []string{kindResource, kindData, kindModule}
+
+// iterationSchema picks out the meta-arguments that repeat a block
+var iterationSchema = &hcl.BodySchema{
+	Attributes: []hcl.AttributeSchema{
+		{Name: "count"},
+		{Name: "for_each"},
+	},
+}
+
+// blockIteration returns the name of the meta-argument that repeats the given
+// block ("count" or "for_each"), or an empty string if it isn't repeated.
+func blockIteration(block *hcl.Block) string {
+	if !contains(repeatableKinds, block.Type) {
+		return ""
 	}
-	if err := dotGraph.SetDir(true); err != nil {
-		return "", err
+	content, _, _ := block.Body.PartialContent(iterationSchema)
+	for _, name := range []string{"count", "for_each"} {
+		if _, ok := content.Attributes[name]; ok {
+			return name
+		}
 	}
+	return ""
+}
 
-	for name := range graph {
-		if err := dotGraph.AddNode("G", fmt.Sprintf("\"%s\"", name), nil); err != nil {
-			return "", err
+// resolve returns the node that the given traversal refers to (or an empty
+// string if it doesn't refer to anything the receiver recognizes). If
+// instances is true and the traversal refers to a particular instance, the
+// node is that instance, and base is the node of the whole resource or module
+// call. Otherwise base is the same as node.
+func (m *module) resolve(prefix string, traversal hcl.Traversal, instances bool) (node, base string) {
+	// dependencies go all the way through attribute names. For instance, an
+	// output.stack_name might depend on random_string.slug.result, but the
+	// m.cfg.blocks map only includes a "random_string.slug".
+	// So for each dependency we need to check: is it in our cfg? Or do we need
+	// to truncate it?
+	// Also, the downstreams here include things like "string" (the raw type
+	// used by a variable's "type" attribute), which we don't recognize.
+	for limit := len(traversal); limit > 0; limit-- {
+		trial := prefix + traversalAddress(traversal[:limit])
+		if m.Has(trial) {
+			return trial, trial
+		}
+		if !instances || limit < 2 {
+			continue
+		}
+		if _, ok := traversal[limit-1].(hcl.TraverseIndex); !ok {
+			continue
+		}
+		parent := prefix + traversalAddress(traversal[:limit-1])
+		if !m.Has(parent) || !contains(repeatableKinds, nodeKind(parent)) {
+			continue
+		}
+		if trial != parent {
+			return trial, parent
 		}
 	}
+	return "", ""
+}
 
-	for name, deps := range graph {
-		for _, dep := range deps {
-			src := fmt.Sprintf("\"%s\"", dep)
-			dst := fmt.Sprintf("\"%s\"", name)
-			if err := dotGraph.AddEdge(src, dst, true, nil); err != nil {
-				return "", err
-			}
+// implicitProviderDependency returns a reference to the default
+// configuration of a resource's provider, if the resource doesn't name a
+// provider configuration itself.
+func implicitProviderDependency(block *hcl.Block, refs []*Reference) []*Reference {
+	if block.Type != "resource" && block.Type != "data" {
+		return nil
+	}
+	for _, ref := range refs {
+		if ref.Attribute == "provider" {
+			return nil
 		}
 	}
-
-	return dotGraph.String(), err
+	localName := strings.SplitN(block.Labels[0], "_", 2)[0]
+	return []*Reference{{
+		Address:   localName,
+		Kind:      kindProvider,
+		Attribute: "provider",
+		Range:     block.DefRange,
+		traversal: hcl.Traversal{hcl.TraverseRoot{Name: localName, SrcRange: block.DefRange}},
+	}}
 }
 
-func unique[T comparable](list []T) []T {
-	uniq := make([]T, 0, len(list))
-	truth := make(map[T]bool)
+// These are the kinds of reference to things that Terraform provides, rather
+// than things declared in the module.
+const (
+	kindSelf  = "self"
+	kindCount = "count"
+	kindEach  = "each"
+	kindPath  = "path"
+)
 
-	for _, val := range list {
-		if _, ok := truth[val]; !ok {
-			truth[val] = true
-			uniq = append(uniq, val)
-		}
+// workspaceAddress is the only built-in reference that gets a graph node.
+const workspaceAddress = "terraform.workspace"
+
+// referenceKind returns the kind of thing a traversal refers to, judging by
+// its root name. If the traversal can't refer to anything (e.g. the `string`
+// in a variable's type constraint), referenceKind returns an empty string.
+func referenceKind(traversal hcl.Traversal) string {
+	switch traversal.RootName() {
+	case "var":
+		return kindVariable
+	case "local":
+		return kindLocal
+	case "module":
+		return kindModule
+	case "data":
+		return kindData
+	case "self":
+		return kindSelf
+	case "count":
+		return kindCount
+	case "each":
+		return kindEach
+	case "path":
+		return kindPath
+	case "terraform":
+		return kindTerraform
+	}
+	if len(traversal) < 2 {
+		return ""
 	}
-	return uniq
+	return kindResource
+}
+
+// nodeKind returns the kind of the node with the given name.
+func nodeKind(name string) string {
+	return ParseAddress(name).Kind
+}
+
+// isManagedResource returns whether the given node name is a managed
+// resource.
+func isManagedResource(name string) bool {
+	return nodeKind(name) == kindResource
 }
 
 func (m *module) Has(path string) bool {
-	if m.cfg.locals[path] != nil {
+	addr := ParseAddress(path)
+	if m.cfg.locals[addr] != nil {
 		return true
 	}
-	if m.cfg.blocks[path] != nil {
+	if m.cfg.blocks[addr] != nil {
 		return true
 	}
 	return false
 }
 
-// attributeDependencies examines an hcl Attribute for its dependencies,
-// and returns...what, exactly?
-func attributeDependencies(attr *hcl.Attribute) ([]string, error) {
-	return expressionDependencies(attr.Expr)
+// attributeDependencies examines an hcl Attribute for its dependencies, and
+// returns a reference for each one. The references are marked with the given
+// attribute path.
+func attributeDependencies(attr *hcl.Attribute, path string) ([]*Reference, error) {
+	refs, err := expressionDependencies(attr.Expr)
+	if err != nil {
+		return nil, err
+	}
+	for _, ref := range refs {
+		ref.Attribute = path
+	}
+	return refs, nil
+}
+
+// blockDependencies examines all the attributes of an hcl Block, including
+// those of its nested blocks, for their dependencies.
+func blockDependencies(block *hcl.Block) ([]*Reference, error) {
+	return bodyDependencies(block.Body, "")
 }
 
-func blockDependencies(block *hcl.Block) ([]string, error) {
-	blockDeps := make([]string, 0)
+func bodyDependencies(body hcl.Body, prefix string) ([]*Reference, error) {
+	bodyDeps := make([]*Reference, 0)
+
+	if merged, ok := body.(*overrideBody); ok {
+		return merged.dependencies(prefix)
+	}
 
-	attrs, _ := block.Body.JustAttributes()
-	for _, attr := range attrs {
-		attrDeps, err := attributeDependencies(attr)
+	// Only native syntax bodies let us see their nested blocks without a
+	// schema.
+	if syntaxBody, ok := body.(*hclsyntax.Body); ok {
+		for name, attr := range syntaxBody.Attributes {
+			attrDeps, err := attributeDependencies(attr.AsHCLAttribute(), prefix+name)
+			if err != nil {
+				return nil, err
+			}
+			bodyDeps = append(bodyDeps, attrDeps...)
+		}
+		for _, nested := range syntaxBody.Blocks {
+			nestedDeps, err := bodyDependencies(nested.Body, prefix+nested.Type+separator)
+			if err != nil {
+				return nil, err
+			}
+			bodyDeps = append(bodyDeps, nestedDeps...)
+		}
+		return bodyDeps, nil
+	}
+
+	attrs, _ := body.JustAttributes()
+	for name, attr := range attrs {
+		attrDeps, err := attributeDependencies(attr, prefix+name)
 		if err != nil {
 			return nil, err
 		}
-		blockDeps = append(blockDeps, attrDeps...)
+		bodyDeps = append(bodyDeps, attrDeps...)
 	}
-
-	// TODO how can we take into account exprs of nested blocks?
-	return blockDeps, nil
+	return bodyDeps, nil
 }
 
-func expressionDependencies(expr hcl.Expression) ([]string, error) {
-	deps := make([]string, 0)
+func expressionDependencies(expr hcl.Expression) ([]*Reference, error) {
+	deps := make([]*Reference, 0)
 	for _, traversal := range expr.Variables() {
-		var varName string
-		for i, step := range traversal {
-			if i == 0 {
-				varName = step.(hcl.TraverseRoot).Name
-				continue
-			}
-			stepAsAttr, ok := step.(hcl.TraverseAttr)
-			if ok {
-				varName += separator + stepAsAttr.Name
-				continue
-			}
-			stepAsIndex, ok := step.(hcl.TraverseIndex)
-			// TraverseIndex can be a big.Float or a string
-			if ok {
-				var indexString string
-				errString := gocty.FromCtyValue(stepAsIndex.Key, &indexString)
-				if errString != nil {
-					varName += indexLeft + indexString + indexRight
-					continue
-				}
-				var indexFloat *big.Float
-				errFloat := gocty.FromCtyValue(stepAsIndex.Key, &indexFloat)
-				if errFloat != nil {
-					varName += indexLeft + indexFloat.String() + indexRight
-					continue
-				}
-				return nil, fmt.Errorf("could not read dependencies of %v, because index was neither string nor float. Error when converting to string: %v. Error when converting to float: %v", varName, errString.Error(), errFloat.Error())
-			}
-			_, ok = step.(hcl.TraverseSplat)
-			if ok {
-				varName += splat
-				continue
-			}
-		}
-		deps = append(deps, varName)
+		deps = append(deps, &Reference{
+			Address:   traversalAddress(traversal),
+			Kind:      referenceKind(traversal),
+			Range:     traversal.SourceRange(),
+			traversal: traversal,
+		})
 	}
 
 	return deps, nil
 }
 
+// traversalAddress renders a traversal the way Terraform writes addresses,
+// e.g. `aws_instance.web["a"].id` or `module.x[0].name`.
+func traversalAddress(traversal hcl.Traversal) string {
+	var address string
+	for _, step := range traversal {
+		switch step := step.(type) {
+		case hcl.TraverseRoot:
+			address = step.Name
+		case hcl.TraverseAttr:
+			address += separator + step.Name
+		case hcl.TraverseIndex:
+			address += indexAddress(step.Key)
+		case hcl.TraverseSplat:
+			address += splat
+		}
+	}
+	return address
+}
+
+// indexAddress renders an index key the way Terraform writes it in an
+// address: strings are quoted, and numbers are not. A key that isn't known,
+// or can't be a string or number, can't name a particular instance, so it
+// renders as nothing at all (as if the whole collection was referred to).
+func indexAddress(key cty.Value) string {
+	if !key.IsWhollyKnown() || key.IsNull() {
+		return ""
+	}
+	if key.Type() == cty.Number {
+		return indexLeft + key.AsBigFloat().Text('f', -1) + indexRight
+	}
+	str, err := convert.Convert(key, cty.String)
+	if err != nil {
+		return ""
+	}
+	return indexLeft + strconv.Quote(str.AsString()) + indexRight
+}
+
 func (m *module) Parser() *hclparse.Parser {
 	return m.fundamental
 }
@@ -1,10 +1,16 @@
 package hcl
 
 import (
+	"io"
+	"reflect"
+	"sort"
 	"strings"
 	"testing"
 
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/hclsyntax"
 	"github.com/sirupsen/logrus"
+	"github.com/zclconf/go-cty/cty"
 )
 
 func TestDependencyGraph(t *testing.T) {
@@ -18,7 +24,7 @@ func TestDependencyGraph(t *testing.T) {
 			moduleDir: "../../fixtures/roots/listed-resource",
 			expectedGraph: `digraph G {
 	"var.qty"->"random_string.this";
-	"random_string.this";
+	"random_string.this" [ xlabel="count: var.qty" ];
 	"var.qty";
 
 }`,
@@ -27,7 +33,7 @@ func TestDependencyGraph(t *testing.T) {
 			moduleDir: "../../fixtures/roots/mapped-resource",
 			expectedGraph: `digraph G {
 	"var.keys"->"random_string.this";
-	"random_string.this";
+	"random_string.this" [ xlabel="for_each: var.keys" ];
 	"var.keys";
 
 }`,
@@ -54,3 +60,271 @@ func TestDependencyGraph(t *testing.T) {
 		})
 	}
 }
+
+func TestBuiltinReferences(t *testing.T) {
+	parser := NewModule(logrus.StandardLogger())
+	if err := parser.ParseModuleDirectory("../../fixtures/roots/builtins"); err != nil {
+		t.Fatal(err)
+	}
+	graph, err := parser.Graph()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	type test struct {
+		name                 string
+		expectedDependencies []string
+		expectedInfo         NodeInfo
+	}
+	tests := []test{
+		{
+			name:                 "local.prefix",
+			expectedDependencies: []string{"terraform.workspace"},
+			expectedInfo:         NodeInfo{Builtins: []string{"terraform.workspace"}},
+		},
+		{
+			name:                 "local_file.counted",
+			expectedDependencies: []string{"local.prefix", "var.files"},
+			expectedInfo: NodeInfo{
+				Iteration:    "count",
+				IteratesOver: []string{"var.files"},
+				Builtins:     []string{"count.index", "path.module", "self.filename"},
+			},
+		},
+		{
+			name:                 "local_file.each",
+			expectedDependencies: []string{"var.contents"},
+			expectedInfo: NodeInfo{
+				Iteration:    "for_each",
+				IteratesOver: []string{"var.contents"},
+				Builtins:     []string{"each.key", "each.value", "path.root"},
+			},
+		},
+		{
+			name:                 "terraform.workspace",
+			expectedDependencies: []string{},
+			expectedInfo:         NodeInfo{},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			deps := append([]string{}, graph.Dependencies(tc.name)...)
+			sort.Strings(deps)
+			if !reflect.DeepEqual(deps, tc.expectedDependencies) {
+				t.Errorf("Expected dependencies %v, got %v", tc.expectedDependencies, deps)
+			}
+			info := *graph.Info(tc.name)
+			sort.Strings(info.Builtins)
+			if info.Iteration != tc.expectedInfo.Iteration ||
+				strings.Join(info.IteratesOver, ",") != strings.Join(tc.expectedInfo.IteratesOver, ",") ||
+				strings.Join(info.Builtins, ",") != strings.Join(tc.expectedInfo.Builtins, ",") {
+				t.Errorf("Expected info %+v, got %+v", tc.expectedInfo, info)
+			}
+		})
+	}
+}
+
+func TestTraversalAddress(t *testing.T) {
+	type test struct {
+		expr            string
+		expectedAddress string
+	}
+
+	tests := []test{
+		{expr: `aws_instance.web["a"].id`, expectedAddress: `aws_instance.web["a"].id`},
+		{expr: `module.x[0].name`, expectedAddress: `module.x[0].name`},
+		{expr: `local.map["a.b"]`, expectedAddress: `local.map["a.b"]`},
+		{expr: `local.map[true]`, expectedAddress: `local.map["true"]`},
+		{expr: `local.list[1.5]`, expectedAddress: `local.list[1.5]`},
+		{expr: `aws_instance.web.*.id`, expectedAddress: `aws_instance.web`},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.expr, func(t *testing.T) {
+			expr, diags := hclsyntax.ParseExpression([]byte(tc.expr), "test.tf", hcl.InitialPos)
+			if diags.HasErrors() {
+				t.Fatal(diags.Error())
+			}
+			actual := traversalAddress(expr.Variables()[0])
+			if actual != tc.expectedAddress {
+				t.Errorf("Expected %s, got %s", tc.expectedAddress, actual)
+			}
+		})
+	}
+
+	if actual := indexAddress(cty.UnknownVal(cty.String)); actual != "" {
+		t.Errorf("Expected an unknown key to render as nothing, got %s", actual)
+	}
+}
+
+func TestInstanceGraph(t *testing.T) {
+	parser := NewModule(logrus.StandardLogger())
+	if err := parser.ParseModuleDirectory("../../fixtures/roots/indexed-references"); err != nil {
+		t.Fatal(err)
+	}
+
+	type test struct {
+		name                 string
+		expectedDependencies []string
+		expectedInstances    []string
+	}
+	tests := []test{
+		{name: "local.string_key", expectedDependencies: []string{"random_string.by_key"}, expectedInstances: []string{`random_string.by_key["a"]`}},
+		{name: "local.number_key", expectedDependencies: []string{"random_string.by_index"}, expectedInstances: []string{`random_string.by_index[0]`}},
+		{name: "local.module_key", expectedDependencies: []string{"module.child"}, expectedInstances: []string{`module.child[0]`}},
+		{name: "local.dotted_key", expectedDependencies: []string{"local.map"}, expectedInstances: []string{"local.map"}},
+		{name: "local.bool_key", expectedDependencies: []string{"local.map"}, expectedInstances: []string{"local.map"}},
+		{name: "local.splat", expectedDependencies: []string{"random_string.by_index"}, expectedInstances: []string{"random_string.by_index"}},
+	}
+
+	graph, err := parser.Graph()
+	if err != nil {
+		t.Fatal(err)
+	}
+	instanceGraph, err := parser.InstanceGraph()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			if deps := graph.Dependencies(tc.name); !reflect.DeepEqual(deps, tc.expectedDependencies) {
+				t.Errorf("Expected dependencies %v, got %v", tc.expectedDependencies, deps)
+			}
+			if deps := instanceGraph.Dependencies(tc.name); !reflect.DeepEqual(deps, tc.expectedInstances) {
+				t.Errorf("Expected instance dependencies %v, got %v", tc.expectedInstances, deps)
+			}
+		})
+	}
+
+	if deps := instanceGraph.Dependencies(`random_string.by_key["a"]`); !reflect.DeepEqual(deps, []string{"random_string.by_key"}) {
+		t.Errorf("Expected the instance to depend on its resource, got %v", deps)
+	}
+}
+
+func TestDuplicateDeclarations(t *testing.T) {
+	logger := logrus.New()
+	logger.Out = io.Discard
+
+	parser := NewModule(logger)
+	if err := parser.ParseModuleDirectory("../../fixtures/roots/duplicates"); err == nil {
+		t.Fatal("Expected duplicate declarations to be an error")
+	}
+
+	// the first declaration wins
+	cfg := parser.Configuration()
+	for _, name := range []string{"local.prefix", "random_string.this"} {
+		var filename string
+		if local, ok := cfg.locals[ParseAddress(name)]; ok {
+			filename = local.Range.Filename
+		} else if block, ok := cfg.blocks[ParseAddress(name)]; ok {
+			filename = block.DefRange.Filename
+		}
+		if !strings.HasSuffix(filename, "main.tf") {
+			t.Errorf("Expected %s to be declared in main.tf, got %q", name, filename)
+		}
+	}
+	if _, ok := cfg.blocks[ParseAddress("module.this")]; !ok {
+		t.Error("Expected module.this not to collide with random_string.this")
+	}
+
+	previous := hcl.Range{Filename: "main.tf", Start: hcl.Pos{Line: 9, Column: 1}, End: hcl.Pos{Line: 9, Column: 33}}
+	diag := duplicateDiagnostic(ParseAddress("random_string.this"), hcl.Range{Filename: "other.tf"}, previous)
+	if diag.Summary != "Duplicate resource declaration" {
+		t.Errorf("Expected summary %q, got %q", "Duplicate resource declaration", diag.Summary)
+	}
+	if !strings.HasPrefix(diag.Detail, "random_string.this was already declared at main.tf:9,1-33.") {
+		t.Errorf("Expected detail to name the previous declaration, got %q", diag.Detail)
+	}
+	if diag.Subject.Filename != "other.tf" {
+		t.Errorf("Expected subject to be the duplicate declaration, got %s", diag.Subject)
+	}
+}
+
+func TestOverrides(t *testing.T) {
+	parser := NewModule(logrus.StandardLogger())
+	if err := parser.ParseModuleDirectory("../../fixtures/roots/overrides"); err != nil {
+		t.Fatal(err)
+	}
+	graph, err := parser.Graph()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := map[string][]string{
+		"var.name":           {},
+		"var.length":         {},
+		"var.suffix":         {},
+		"local.prefix":       {"var.name"},
+		"local.full_name":    {"local.prefix", "var.suffix"},
+		"random_string.this": {"random_pet.this", "var.length"},
+		"random_pet.this":    {"local.full_name"},
+		"output.name":        {"random_string.this"},
+		"output.pet":         {"random_pet.this"},
+	}
+	expectedNodes := make([]string, 0, len(expected))
+	for name := range expected {
+		expectedNodes = append(expectedNodes, name)
+	}
+	sort.Strings(expectedNodes)
+	if nodes := graph.Nodes(); !reflect.DeepEqual(nodes, expectedNodes) {
+		t.Errorf("Expected nodes %v, got %v", expectedNodes, nodes)
+	}
+	for name, expectedDeps := range expected {
+		deps := append([]string{}, graph.Dependencies(name)...)
+		sort.Strings(deps)
+		if !reflect.DeepEqual(deps, expectedDeps) {
+			t.Errorf("Expected %s to depend on %v, got %v", name, expectedDeps, deps)
+		}
+	}
+
+	// lifecycle blocks are merged, rather than replaced
+	block := parser.Configuration().blocks[ParseAddress("random_string.this")]
+	content, _, _ := block.Body.PartialContent(&hcl.BodySchema{
+		Attributes: []hcl.AttributeSchema{{Name: "length"}, {Name: "keepers"}},
+		Blocks:     []hcl.BlockHeaderSchema{{Type: "lifecycle"}},
+	})
+	if len(content.Attributes) != 2 || len(content.Blocks) != 1 {
+		t.Fatalf("Expected 2 attributes and 1 lifecycle block, got %d and %d", len(content.Attributes), len(content.Blocks))
+	}
+	if filename := content.Attributes["keepers"].Range.Filename; !strings.HasSuffix(filename, "override.tf") {
+		t.Errorf("Expected keepers to come from override.tf, got %s", filename)
+	}
+	lifecycle, _ := content.Blocks[0].Body.JustAttributes()
+	if _, ok := lifecycle["create_before_destroy"]; !ok {
+		t.Error("Expected lifecycle to keep create_before_destroy")
+	}
+	if filename := lifecycle["ignore_changes"].Range.Filename; !strings.HasSuffix(filename, "override.tf") {
+		t.Errorf("Expected ignore_changes to come from override.tf, got %s", filename)
+	}
+}
+
+func TestParseAddress(t *testing.T) {
+	cases := []struct {
+		s        string
+		expected Address
+	}{
+		{"var.qty", Address{Kind: kindVariable, Name: "qty"}},
+		{"local.prefix", Address{Kind: kindLocal, Name: "prefix"}},
+		{"module.network", Address{Kind: kindModule, Name: "network"}},
+		{"data.aws_ami.ubuntu", Address{Kind: kindData, Type: "aws_ami", Name: "ubuntu"}},
+		{"aws_instance.web", Address{Kind: kindResource, Type: "aws_instance", Name: "web"}},
+		{`aws_instance.web["a.b"]`, Address{Kind: kindResource, Type: "aws_instance", Name: "web", Key: `["a.b"]`}},
+		{"provider.aws", Address{Kind: kindProvider, Type: "aws"}},
+		{"provider.aws.west", Address{Kind: kindProvider, Type: "aws", Name: "west"}},
+		{"terraform.workspace", Address{Kind: kindTerraform, Name: "workspace"}},
+		{"aws_instance", Address{}},
+		{"data.aws_ami", Address{}},
+	}
+	for _, c := range cases {
+		t.Run(c.s, func(t *testing.T) {
+			actual := ParseAddress(c.s)
+			if actual != c.expected {
+				t.Errorf("Expected %#v, got %#v", c.expected, actual)
+			}
+			if len(actual.Kind) > 0 && actual.String() != c.s {
+				t.Errorf("Expected %#v to print as %s, got %s", actual, c.s, actual.String())
+			}
+		})
+	}
+}
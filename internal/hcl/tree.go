@@ -0,0 +1,185 @@
+package hcl
+
+import (
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/hashicorp/hcl/v2"
+)
+
+// ModuleTree is the set of modules that a group of modules call from local
+// paths, directly or transitively. Each module is named by its normalized
+// local source, e.g. "./modules/vpc".
+type ModuleTree struct {
+	// Graph has a node for each module, which depends on the modules it
+	// calls. Each edge's reference is the call's `source` argument.
+	Graph *Graph
+	// Roots are the modules that no other module in the tree calls, sorted.
+	Roots []string
+	// Calls are the local module calls each module makes, sorted by name.
+	Calls map[string][]*ModuleCallSite
+	// Missing are the local module calls whose directories don't exist.
+	Missing []*ModuleCallSite
+	// Unreadable maps each module that couldn't be read to the reason. They
+	// are in the tree, but it doesn't know what they call.
+	Unreadable map[string]error
+}
+
+// LoadModuleTree reads the modules at the given directories, and the modules
+// they call from local paths, recursively. Module names are relative to
+// baseDir. Modules that can't be read are left without calls, and recorded
+// in the tree's Unreadable.
+func LoadModuleTree(dirs []string, baseDir string) (*ModuleTree, error) {
+	tree := &ModuleTree{
+		Graph:      newGraph(),
+		Roots:      make([]string, 0),
+		Calls:      make(map[string][]*ModuleCallSite),
+		Missing:    make([]*ModuleCallSite, 0),
+		Unreadable: make(map[string]error),
+	}
+
+	queue := make([]string, 0, len(dirs))
+	for _, dir := range dirs {
+		queue = append(queue, localModuleAddress(dir, baseDir))
+	}
+	for len(queue) > 0 {
+		name := queue[0]
+		queue = queue[1:]
+		if tree.Graph.Has(name) {
+			continue
+		}
+
+		dir := filepath.Join(baseDir, name)
+		deps := make([]string, 0)
+		tree.Calls[name] = make([]*ModuleCallSite, 0)
+		sites, err := LoadModuleCalls(dir, baseDir)
+		if err != nil {
+			tree.Unreadable[name] = err
+			tree.Graph.AddNode(name, deps)
+			continue
+		}
+		for _, site := range sites {
+			if site.Source.Kind != SourceLocal {
+				continue
+			}
+			tree.Calls[name] = append(tree.Calls[name], site)
+			callee := site.Source.Address
+			if !isDirectory(filepath.Join(baseDir, callee)) {
+				tree.Missing = append(tree.Missing, site)
+				continue
+			}
+			if !contains(deps, callee) {
+				deps = append(deps, callee)
+			}
+			tree.Graph.addReference(name, callee, &Reference{
+				Address:   Address{Kind: kindModule, Name: site.Name}.String(),
+				Kind:      kindModule,
+				Attribute: "source",
+				Range: hcl.Range{
+					Filename: site.Pos.Filename,
+					Start:    hcl.Pos{Line: site.Pos.Line},
+					End:      hcl.Pos{Line: site.Pos.Line},
+				},
+			})
+			queue = append(queue, callee)
+		}
+		tree.Graph.AddNode(name, deps)
+	}
+
+	for _, name := range tree.Graph.Nodes() {
+		if len(tree.Graph.Dependents(name)) == 0 {
+			tree.Roots = append(tree.Roots, name)
+		}
+	}
+	for _, site := range tree.Missing {
+		tree.Graph.SetNodeAttribute(site.Source.Address, "color", "red")
+		tree.Graph.SetNodeAttribute(site.Source.Address, "style", "dashed")
+	}
+	for name := range tree.Unreadable {
+		tree.Graph.SetNodeAttribute(name, "color", "red")
+	}
+	return tree, nil
+}
+
+// Shared returns the modules in the receiver that other modules call,
+// sorted.
+func (t *ModuleTree) Shared() []string {
+	shared := make([]string, 0)
+	for _, name := range t.Graph.Nodes() {
+		if len(t.Graph.Dependents(name)) > 0 {
+			shared = append(shared, name)
+		}
+	}
+	return shared
+}
+
+// DependentRoots returns the roots that call the given module, directly or
+// transitively, sorted. These are the roots to plan after a change to it.
+func (t *ModuleTree) DependentRoots(name string) []string {
+	roots := make([]string, 0)
+	for dependent := range t.Graph.walk(name, -1, t.Graph.Dependents) {
+		if contains(t.Roots, dependent) {
+			roots = append(roots, dependent)
+		}
+	}
+	sort.Strings(roots)
+	return roots
+}
+
+// DOT returns a DOT-format representation of the receiver's graph, with the
+// missing modules drawn dashed and red.
+func (t *ModuleTree) DOT() (string, error) {
+	dotGraph := newGraph()
+	for _, name := range t.Graph.Nodes() {
+		deps := append([]string{}, t.Graph.Dependencies(name)...)
+		for _, site := range t.Calls[name] {
+			if !contains(deps, site.Source.Address) {
+				deps = append(deps, site.Source.Address)
+			}
+		}
+		dotGraph.AddNode(name, deps)
+		for _, dep := range deps {
+			if !t.Graph.Has(dep) {
+				dotGraph.AddNode(dep, []string{})
+			}
+		}
+	}
+	for name, attrs := range t.Graph.attributes {
+		for k, v := range attrs {
+			dotGraph.SetNodeAttribute(name, k, v)
+		}
+	}
+	return dotGraph.DOT()
+}
+
+// String returns the receiver as an indented tree under each root, with each
+// call on a line of its own. Calls back into an ancestor are marked as
+// cycles, and calls of directories that don't exist as missing.
+func (t *ModuleTree) String() string {
+	var b strings.Builder
+	for _, root := range t.Roots {
+		b.WriteString(root + "\n")
+		t.writeCalls(&b, root, "  ", []string{root})
+	}
+	return b.String()
+}
+
+func (t *ModuleTree) writeCalls(b *strings.Builder, name, indent string, ancestors []string) {
+	for _, site := range t.Calls[name] {
+		callee := site.Source.Address
+		line := fmt.Sprintf("%s%s: %s", indent, Address{Kind: kindModule, Name: site.Name}, callee)
+		switch {
+		case !t.Graph.Has(callee):
+			b.WriteString(line + " (missing)\n")
+		case contains(ancestors, callee):
+			b.WriteString(line + " (cycle)\n")
+		case t.Unreadable[callee] != nil:
+			b.WriteString(line + " (unreadable)\n")
+		default:
+			b.WriteString(line + "\n")
+			t.writeCalls(b, callee, indent+"  ", append(ancestors, callee))
+		}
+	}
+}
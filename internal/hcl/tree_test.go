@@ -0,0 +1,110 @@
+package hcl
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestLoadModuleTree(t *testing.T) {
+	baseDir, err := filepath.Abs("../../fixtures/modules/tree")
+	if err != nil {
+		t.Fatal(err)
+	}
+	tree, err := LoadModuleTree([]string{filepath.Join(baseDir, "roots/main")}, baseDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := strings.Join([]string{
+		"./roots/main",
+		"  module.a: ./modules/a",
+		"    module.b: ./modules/b",
+		"      module.a: ./modules/a (cycle)",
+		"    module.d: ./modules/d",
+		"  module.c: ./modules/c",
+		"    module.d: ./modules/d",
+		"    module.nope: ./modules/nope (missing)",
+		"",
+	}, "\n")
+	if tree.String() != expected {
+		t.Errorf("Expected tree:\n%s\nActual tree:\n%s", expected, tree)
+	}
+
+	if len(tree.Missing) != 1 || tree.Missing[0].Source.Address != "./modules/nope" {
+		t.Errorf("Expected ./modules/nope to be missing, got %v", tree.Missing)
+	}
+	cycles := tree.Graph.Cycles()
+	if len(cycles) != 1 || strings.Join(cycles[0].Nodes, ", ") != "./modules/a, ./modules/b" {
+		t.Errorf("Expected a cycle between ./modules/a and ./modules/b, got %v", cycles)
+	}
+	if shared := strings.Join(tree.Shared(), ", "); shared != "./modules/a, ./modules/b, ./modules/c, ./modules/d" {
+		t.Errorf("Unexpected shared modules %s", shared)
+	}
+}
+
+func TestLoadModuleTreeUnreadable(t *testing.T) {
+	baseDir := t.TempDir()
+	files := map[string]string{
+		"roots/main/main.tf":     "module \"a\" {\n  source = \"../../modules/a\"\n}\n\nmodule \"broken\" {\n  source = \"../../modules/broken\"\n}\n",
+		"modules/a/main.tf":      "",
+		"modules/broken/main.tf": "module \"x\" {\n",
+	}
+	for name, contents := range files {
+		filename := filepath.Join(baseDir, name)
+		if err := os.MkdirAll(filepath.Dir(filename), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filename, []byte(contents), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tree, err := LoadModuleTree([]string{filepath.Join(baseDir, "roots/main")}, baseDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := strings.Join([]string{
+		"./roots/main",
+		"  module.a: ./modules/a",
+		"  module.broken: ./modules/broken (unreadable)",
+		"",
+	}, "\n")
+	if tree.String() != expected {
+		t.Errorf("Expected tree:\n%s\nActual tree:\n%s", expected, tree)
+	}
+	if len(tree.Unreadable) != 1 || tree.Unreadable["./modules/broken"] == nil {
+		t.Errorf("Expected only ./modules/broken to be unreadable, got %v", tree.Unreadable)
+	}
+}
+
+func TestModuleTreeDependentRoots(t *testing.T) {
+	baseDir, err := filepath.Abs("../../fixtures/monorepo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	dirs := []string{
+		filepath.Join(baseDir, "modules/app"),
+		filepath.Join(baseDir, "modules/vpc"),
+		filepath.Join(baseDir, "roots/prod"),
+		filepath.Join(baseDir, "roots/staging"),
+	}
+	tree, err := LoadModuleTree(dirs, baseDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if roots := strings.Join(tree.Roots, ", "); roots != "./roots/prod, ./roots/staging" {
+		t.Errorf("Unexpected roots %s", roots)
+	}
+	expected := map[string]string{
+		"./modules/app": "./roots/staging",
+		"./modules/vpc": "./roots/prod, ./roots/staging",
+	}
+	for name, expectedRoots := range expected {
+		if actual := strings.Join(tree.DependentRoots(name), ", "); actual != expectedRoots {
+			t.Errorf("Expected %s to be used by %s, got %s", name, expectedRoots, actual)
+		}
+	}
+}
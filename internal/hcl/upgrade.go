@@ -0,0 +1,195 @@
+package hcl
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/hclsyntax"
+	"github.com/hashicorp/hcl/v2/hclwrite"
+	"github.com/zclconf/go-cty/cty"
+)
+
+// ModuleUpgrade is the result of upgrading a single module call.
+type ModuleUpgrade struct {
+	// Name is the name of the module call.
+	Name string
+	// From is the version constraint (or git ref) the call had before.
+	From string
+	// To is the version constraint (or git ref) the call has after, which is
+	// the same as From if it was skipped.
+	To string
+	// Skipped is why the call wasn't upgraded, or empty if it was.
+	Skipped string
+}
+
+func (u *ModuleUpgrade) String() string {
+	if len(u.Skipped) > 0 {
+		return fmt.Sprintf("module.%s: %s", u.Name, u.Skipped)
+	}
+	return fmt.Sprintf("module.%s: %s -> %s", u.Name, u.From, u.To)
+}
+
+// UpgradeModuleCalls rewrites the module calls of the given module (at
+// target.Address) in the given configuration file source to use the given
+// version, and returns the result. Only the version constraint of a registry
+// source, or the ref of a git source, is changed; the rest of the file keeps
+// its formatting.
+//
+// If target has a version, only calls with exactly that version are
+// upgraded. Calls are skipped if their version isn't an exact version: a
+// range is left for whoever pinned it to widen, and a branch name or commit
+// isn't a version to upgrade from.
+//
+// callerDir is the directory of the file, and baseDir is the directory that
+// local sources are normalized against.
+func UpgradeModuleCalls(src []byte, filename string, target *ModuleSource, version, callerDir, baseDir string) ([]byte, []*ModuleUpgrade, error) {
+	if target.Kind != SourceRegistry && target.Kind != SourceGit {
+		return nil, nil, fmt.Errorf("%s is a %s module, which has no versions. Only registry and git modules can be upgraded", target.Address, target.Kind)
+	}
+	if _, err := parseSemver(version); err != nil {
+		return nil, nil, err
+	}
+
+	file, diags := hclwrite.ParseConfig(src, filename, hcl.InitialPos)
+	if diags.HasErrors() {
+		return nil, nil, diags
+	}
+
+	upgrades := make([]*ModuleUpgrade, 0)
+	for _, block := range file.Body().Blocks() {
+		if block.Type() != kindModule || len(block.Labels()) != 1 {
+			continue
+		}
+		body := block.Body()
+		sourceAttr := body.GetAttribute("source")
+		if sourceAttr == nil {
+			continue
+		}
+		source, ok := literalString(sourceAttr)
+		if !ok {
+			continue
+		}
+		var constraint string
+		versionAttr := body.GetAttribute("version")
+		if versionAttr != nil {
+			constraint, _ = literalString(versionAttr)
+		}
+
+		normalized := NormalizeModuleSource(source, constraint, callerDir, baseDir)
+		if normalized.Address != target.Address {
+			continue
+		}
+		if len(target.Version) > 0 && normalized.Version != target.Version {
+			continue
+		}
+
+		upgrade := &ModuleUpgrade{Name: block.Labels()[0], From: normalized.Version, To: normalized.Version}
+		upgrades = append(upgrades, upgrade)
+
+		switch normalized.Kind {
+		case SourceRegistry:
+			if versionAttr != nil && len(constraint) == 0 {
+				upgrade.Skipped = "version is not a literal string"
+				continue
+			}
+			if skipped := skipUpgrade(constraint, version); len(skipped) > 0 {
+				upgrade.Skipped = skipped
+				continue
+			}
+			body.SetAttributeValue("version", cty.StringVal(version))
+		case SourceGit:
+			if skipped := skipUpgrade(normalized.Version, version); len(skipped) > 0 {
+				upgrade.Skipped = skipped
+				continue
+			}
+			ref := gitRefForVersion(normalized.Version, version)
+			upgraded, err := setGitRef(source, ref)
+			if err != nil {
+				return nil, nil, fmt.Errorf("could not upgrade module.%s: %w", upgrade.Name, err)
+			}
+			body.SetAttributeValue("source", cty.StringVal(upgraded))
+			upgrade.To = ref
+			continue
+		}
+		upgrade.To = version
+	}
+	return file.Bytes(), upgrades, nil
+}
+
+// skipUpgrade returns why a call with the given version constraint (or git
+// ref) shouldn't be upgraded to the given version, or an empty string if it
+// should.
+func skipUpgrade(constraint, version string) string {
+	if len(constraint) == 0 {
+		return "not pinned to a version, so it already uses the latest"
+	}
+	if isExactVersion(constraint) {
+		if current, _ := parseSemver(strings.TrimPrefix(strings.TrimSpace(constraint), "=")); current != nil {
+			if next, _ := parseSemver(version); next != nil && current.compare(next) == 0 {
+				return fmt.Sprintf("already at %s", constraint)
+			}
+		}
+		return ""
+	}
+	allowed, err := constraintAllows(constraint, version)
+	if err != nil {
+		return fmt.Sprintf("pinned to %q, which is not a version", constraint)
+	}
+	if allowed {
+		return fmt.Sprintf("pinned by constraint %q, which already allows %s", constraint, version)
+	}
+	return fmt.Sprintf("pinned by constraint %q, which does not allow %s", constraint, version)
+}
+
+// gitRefForVersion returns the git ref to upgrade the given current ref to
+// for the given version. Tags are written like the current one: with a "v"
+// prefix (e.g. "v1.3.0") or without one, whichever way the version is given.
+// If the current ref isn't a version, the version is used as it is given.
+func gitRefForVersion(current, version string) string {
+	if _, err := parseSemver(current); err != nil {
+		return version
+	}
+	if _, err := parseSemver(version); err != nil {
+		return version
+	}
+	version = strings.TrimPrefix(version, "v")
+	if strings.HasPrefix(current, "v") {
+		return "v" + version
+	}
+	return version
+}
+
+// setGitRef returns the given git source with its ref changed to the given
+// one. Only the ref's value changes, so the rest of the source stays as it
+// was written.
+func setGitRef(source, ref string) (string, error) {
+	i := strings.Index(source, "?")
+	if i < 0 {
+		return "", fmt.Errorf("%s has no ref", source)
+	}
+	query := strings.Split(source[i+1:], "&")
+	for j, param := range query {
+		if strings.HasPrefix(param, "ref=") {
+			query[j] = "ref=" + url.QueryEscape(ref)
+			return source[:i+1] + strings.Join(query, "&"), nil
+		}
+	}
+	return "", fmt.Errorf("%s has no ref", source)
+}
+
+// literalString returns the value of the given attribute, if it is a string
+// with no references or function calls.
+func literalString(attr *hclwrite.Attribute) (string, bool) {
+	tokens := attr.Expr().BuildTokens(nil)
+	expr, diags := hclsyntax.ParseExpression(tokens.Bytes(), "", hcl.InitialPos)
+	if diags.HasErrors() {
+		return "", false
+	}
+	value, diags := expr.Value(nil)
+	if diags.HasErrors() || !value.IsKnown() || value.IsNull() || value.Type() != cty.String {
+		return "", false
+	}
+	return value.AsString(), true
+}
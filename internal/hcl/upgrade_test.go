@@ -0,0 +1,173 @@
+package hcl
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestConstraintAllows(t *testing.T) {
+	type test struct {
+		constraint string
+		version    string
+		expected   bool
+	}
+	tests := []test{
+		{constraint: "1.2.0", version: "1.2.0", expected: true},
+		{constraint: "= 1.2.0", version: "1.2.1", expected: false},
+		{constraint: "!= 1.2.0", version: "1.2.1", expected: true},
+		{constraint: ">= 1.2, < 2.0", version: "1.9.9", expected: true},
+		{constraint: ">= 1.2, < 2.0", version: "2.0.0", expected: false},
+		{constraint: "~> 1.2", version: "1.9.0", expected: true},
+		{constraint: "~> 1.2", version: "2.0.0", expected: false},
+		{constraint: "~> 1.2.0", version: "1.2.5", expected: true},
+		{constraint: "~> 1.2.0", version: "1.3.0", expected: false},
+		{constraint: "> 1.2.0", version: "1.2.1-beta", expected: true},
+		{constraint: ">= 1.2.0", version: "1.2.0-beta", expected: false},
+	}
+	for _, tc := range tests {
+		t.Run(tc.constraint+" allows "+tc.version, func(t *testing.T) {
+			actual, err := constraintAllows(tc.constraint, tc.version)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if actual != tc.expected {
+				t.Errorf("Expected %v, got %v", tc.expected, actual)
+			}
+		})
+	}
+}
+
+func TestSortVersions(t *testing.T) {
+	versions := []string{"main", "1.10.0", "~> 1.9", "v1.2.0", "1.10.0-beta.1", "develop"}
+	SortVersions(versions)
+	expected := []string{"v1.2.0", "~> 1.9", "1.10.0-beta.1", "1.10.0", "develop", "main"}
+	if strings.Join(versions, ", ") != strings.Join(expected, ", ") {
+		t.Errorf("Expected %v, got %v", expected, versions)
+	}
+}
+
+func TestUpgradeModuleCalls(t *testing.T) {
+	baseDir, err := filepath.Abs("../../fixtures/monorepo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	callerDir := filepath.Join(baseDir, "roots/prod")
+	filename := filepath.Join(callerDir, "main.tf")
+	src, err := os.ReadFile(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	type test struct {
+		target           string
+		version          string
+		expectedUpgrades []string
+		expectedLine     string
+	}
+	tests := []test{
+		{
+			target:           "registry.terraform.io/hashicorp/consul/aws",
+			version:          "0.3.0",
+			expectedUpgrades: []string{"module.consul: 0.1.0 -> 0.3.0"},
+			expectedLine:     `  version = "0.3.0"`,
+		},
+		{
+			target:           "hashicorp/consul/aws@0.2.0",
+			version:          "0.3.0",
+			expectedUpgrades: []string{},
+		},
+		{
+			target:           "git::https://github.com/acme/terraform-modules.git//labels",
+			version:          "v1.3.0",
+			expectedUpgrades: []string{"module.labels: v1.2.0 -> v1.3.0"},
+			expectedLine:     `  source = "git::https://github.com/acme/terraform-modules.git//labels?ref=v1.3.0"`,
+		},
+		{
+			// the ref keeps its "v", though the version is given without one
+			target:           "github.com/acme/terraform-modules//labels",
+			version:          "1.3.0",
+			expectedUpgrades: []string{"module.labels: v1.2.0 -> v1.3.0"},
+			expectedLine:     `  source = "git::https://github.com/acme/terraform-modules.git//labels?ref=v1.3.0"`,
+		},
+		{
+			target:           "github.com/acme/terraform-modules//labels",
+			version:          "v1.2.0",
+			expectedUpgrades: []string{"module.labels: already at v1.2.0"},
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.target+" to "+tc.version, func(t *testing.T) {
+			target, err := ParseModuleSourceArg(tc.target, baseDir)
+			if err != nil {
+				t.Fatal(err)
+			}
+			result, upgrades, err := UpgradeModuleCalls(src, filename, target, tc.version, callerDir, baseDir)
+			if err != nil {
+				t.Fatal(err)
+			}
+			actual := make([]string, len(upgrades))
+			for i, u := range upgrades {
+				actual[i] = u.String()
+			}
+			if strings.Join(actual, "\n") != strings.Join(tc.expectedUpgrades, "\n") {
+				t.Errorf("Expected upgrades:\n%s\nActual upgrades:\n%s", strings.Join(tc.expectedUpgrades, "\n"), strings.Join(actual, "\n"))
+			}
+
+			if len(tc.expectedLine) == 0 {
+				if string(result) != string(src) {
+					t.Errorf("Expected the file to be unchanged, got:\n%s", result)
+				}
+				return
+			}
+			// everything but the upgraded line stays the same
+			srcLines := strings.Split(string(src), "\n")
+			resultLines := strings.Split(string(result), "\n")
+			if len(srcLines) != len(resultLines) {
+				t.Fatalf("Expected %d lines, got %d:\n%s", len(srcLines), len(resultLines), result)
+			}
+			changed := make([]string, 0)
+			for i := range srcLines {
+				if srcLines[i] != resultLines[i] {
+					changed = append(changed, resultLines[i])
+				}
+			}
+			if len(changed) != 1 || changed[0] != tc.expectedLine {
+				t.Errorf("Expected only the line %q to change, got %q", tc.expectedLine, changed)
+			}
+		})
+	}
+}
+
+func TestUpgradeModuleCallsSkipsRanges(t *testing.T) {
+	src := []byte(`module "consul" {
+  source  = "hashicorp/consul/aws"
+  version = "~> 0.2.0" # pinned until the upgrade is tested
+}
+
+module "latest" {
+  source = "hashicorp/consul/aws"
+}
+`)
+	target := NormalizeModuleSource("hashicorp/consul/aws", "", "/repo", "/repo")
+	result, upgrades, err := UpgradeModuleCalls(src, "main.tf", target, "0.3.0", "/repo", "/repo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(result) != string(src) {
+		t.Errorf("Expected the file to be unchanged, got:\n%s", result)
+	}
+	expected := []string{
+		`module.consul: pinned by constraint "~> 0.2.0", which does not allow 0.3.0`,
+		"module.latest: not pinned to a version, so it already uses the latest",
+	}
+	if len(upgrades) != len(expected) {
+		t.Fatalf("Expected %d upgrades, got %d", len(expected), len(upgrades))
+	}
+	for i, u := range upgrades {
+		if u.String() != expected[i] {
+			t.Errorf("Expected %s, got %s", expected[i], u)
+		}
+	}
+}
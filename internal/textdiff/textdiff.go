@@ -0,0 +1,191 @@
+// Package textdiff provides functions for showing the differences between two
+// versions of a text file.
+package textdiff
+
+import (
+	"fmt"
+	"strings"
+)
+
+// contextLines is how many unchanged lines to show around each change
+const contextLines = 3
+
+type opKind int
+
+const (
+	opEqual opKind = iota
+	opDelete
+	opInsert
+)
+
+// op is a single line of an edit script
+type op struct {
+	kind opKind
+	line string
+	// aIndex and bIndex are the line's index in each version (or, for a line
+	// that's only in one version, where it would be in the other)
+	aIndex int
+	bIndex int
+}
+
+// Unified returns the differences between a and b in unified diff format,
+// with the given names for each version. It returns an empty string if they
+// are the same.
+func Unified(aName, bName, a, b string) string {
+	if a == b {
+		return ""
+	}
+	ops := editScript(splitLines(a), splitLines(b))
+
+	var out strings.Builder
+	fmt.Fprintf(&out, "--- %s\n+++ %s\n", aName, bName)
+	for _, hunk := range hunks(ops) {
+		writeHunk(&out, hunk)
+	}
+	return out.String()
+}
+
+// splitLines splits the given text into lines, keeping each line's newline.
+func splitLines(text string) []string {
+	if len(text) == 0 {
+		return []string{}
+	}
+	lines := strings.SplitAfter(text, "\n")
+	if len(lines[len(lines)-1]) == 0 {
+		lines = lines[:len(lines)-1]
+	}
+	return lines
+}
+
+// editScript returns the shortest list of operations that turns a into b.
+// The lines that a and b start and end with are equal; the lines between are
+// found with a longest common subsequence, so that a small change to a large
+// file only needs a small table.
+func editScript(a, b []string) []op {
+	prefix := 0
+	for prefix < len(a) && prefix < len(b) && a[prefix] == b[prefix] {
+		prefix++
+	}
+	suffix := 0
+	for suffix < len(a)-prefix && suffix < len(b)-prefix && a[len(a)-1-suffix] == b[len(b)-1-suffix] {
+		suffix++
+	}
+
+	ops := make([]op, 0, len(a)+len(b)-prefix-suffix)
+	for i := 0; i < prefix; i++ {
+		ops = append(ops, op{kind: opEqual, line: a[i], aIndex: i, bIndex: i})
+	}
+	ops = append(ops, lcsEditScript(a[prefix:len(a)-suffix], b[prefix:len(b)-suffix], prefix)...)
+	for k := suffix; k > 0; k-- {
+		i, j := len(a)-k, len(b)-k
+		ops = append(ops, op{kind: opEqual, line: a[i], aIndex: i, bIndex: j})
+	}
+	return ops
+}
+
+// lcsEditScript returns the shortest list of operations that turns a into b,
+// found with a longest common subsequence. Both are the lines from the given
+// offset on in their versions.
+func lcsEditScript(a, b []string, offset int) []op {
+	// lcs[i][j] is the length of the longest common subsequence of a[i:] and
+	// b[j:]
+	lcs := make([][]int, len(a)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else if lcs[i+1][j] >= lcs[i][j+1] {
+				lcs[i][j] = lcs[i+1][j]
+			} else {
+				lcs[i][j] = lcs[i][j+1]
+			}
+		}
+	}
+
+	ops := make([]op, 0, len(a)+len(b))
+	i, j := 0, 0
+	for i < len(a) || j < len(b) {
+		switch {
+		case i < len(a) && j < len(b) && a[i] == b[j]:
+			ops = append(ops, op{kind: opEqual, line: a[i], aIndex: offset + i, bIndex: offset + j})
+			i++
+			j++
+		case j == len(b) || (i < len(a) && lcs[i+1][j] >= lcs[i][j+1]):
+			ops = append(ops, op{kind: opDelete, line: a[i], aIndex: offset + i, bIndex: offset + j})
+			i++
+		default:
+			ops = append(ops, op{kind: opInsert, line: b[j], aIndex: offset + i, bIndex: offset + j})
+			j++
+		}
+	}
+	return ops
+}
+
+// hunks groups the given edit script into runs of changes, each with up to
+// contextLines unchanged lines around it. Changes that are close enough for
+// their context to overlap share a hunk.
+func hunks(ops []op) [][]op {
+	groups := make([][]op, 0)
+	start, end := -1, -1
+	for i, o := range ops {
+		if o.kind == opEqual {
+			continue
+		}
+		lo := max(i-contextLines, 0)
+		hi := min(i+contextLines+1, len(ops))
+		if start >= 0 && lo <= end {
+			end = hi
+			continue
+		}
+		if start >= 0 {
+			groups = append(groups, ops[start:end])
+		}
+		start, end = lo, hi
+	}
+	if start >= 0 {
+		groups = append(groups, ops[start:end])
+	}
+	return groups
+}
+
+func writeHunk(out *strings.Builder, hunk []op) {
+	aStart, bStart := hunk[0].aIndex, hunk[0].bIndex
+	aCount, bCount := 0, 0
+	for _, o := range hunk {
+		if o.kind != opInsert {
+			aCount++
+		}
+		if o.kind != opDelete {
+			bCount++
+		}
+	}
+	fmt.Fprintf(out, "@@ -%s +%s @@\n", hunkRange(aStart, aCount), hunkRange(bStart, bCount))
+	for _, o := range hunk {
+		prefix := " "
+		switch o.kind {
+		case opDelete:
+			prefix = "-"
+		case opInsert:
+			prefix = "+"
+		}
+		out.WriteString(prefix + o.line)
+		if !strings.HasSuffix(o.line, "\n") {
+			out.WriteString("\n\\ No newline at end of file\n")
+		}
+	}
+}
+
+// hunkRange formats the start (a 0-based index) and length of one side of a
+// hunk. An empty side is given by the line before it.
+func hunkRange(start, count int) string {
+	if count == 0 {
+		return fmt.Sprintf("%d,0", start)
+	}
+	if count == 1 {
+		return fmt.Sprintf("%d", start+1)
+	}
+	return fmt.Sprintf("%d,%d", start+1, count)
+}
@@ -0,0 +1,59 @@
+package textdiff
+
+import (
+	"testing"
+)
+
+func TestUnified(t *testing.T) {
+	type test struct {
+		name     string
+		a        string
+		b        string
+		expected string
+	}
+	tests := []test{
+		{
+			name: "same",
+			a:    "a\nb\n",
+			b:    "a\nb\n",
+		},
+		{
+			name:     "changed line",
+			a:        "1\n2\n3\n4\n5\n6\n7\n8\n9\n",
+			b:        "1\n2\n3\n4\nfive\n6\n7\n8\n9\n",
+			expected: "--- a\n+++ b\n@@ -2,7 +2,7 @@\n 2\n 3\n 4\n-5\n+five\n 6\n 7\n 8\n",
+		},
+		{
+			name:     "separate hunks",
+			a:        "1\n2\n3\n4\n5\n6\n7\n8\n9\n10\n",
+			b:        "one\n2\n3\n4\n5\n6\n7\n8\n9\nten\n",
+			expected: "--- a\n+++ b\n@@ -1,4 +1,4 @@\n-1\n+one\n 2\n 3\n 4\n@@ -7,4 +7,4 @@\n 7\n 8\n 9\n-10\n+ten\n",
+		},
+		{
+			name:     "added to repeated lines",
+			a:        "a\na\n",
+			b:        "a\na\na\n",
+			expected: "--- a\n+++ b\n@@ -1,2 +1,3 @@\n a\n a\n+a\n",
+		},
+		{
+			name:     "added to empty",
+			a:        "",
+			b:        "a\n",
+			expected: "--- a\n+++ b\n@@ -0,0 +1 @@\n+a\n",
+		},
+		{
+			name:     "no newline at end",
+			a:        "a\nb",
+			b:        "a\nc",
+			expected: "--- a\n+++ b\n@@ -1,2 +1,2 @@\n a\n-b\n\\ No newline at end of file\n+c\n\\ No newline at end of file\n",
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			actual := Unified("a", "b", tc.a, tc.b)
+			if actual != tc.expected {
+				t.Errorf("Expected:\n%s\nActual:\n%s", tc.expected, actual)
+			}
+		})
+	}
+}
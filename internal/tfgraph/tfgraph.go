@@ -0,0 +1,297 @@
+// Package tfgraph provides functions for working with the DOT-format graphs
+// printed by `terraform graph`.
+package tfgraph
+
+import (
+	"fmt"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/awalterschulze/gographviz"
+)
+
+const graphName = "G"
+const rootPrefix = "[root] "
+const modulePrefix = "module."
+const dataPrefix = "data."
+
+// providerColors are the fill colors given to each provider's resources, in
+// the order the providers are first seen (alphabetically).
+var providerColors = []string{
+	"lightblue",
+	"palegreen",
+	"khaki",
+	"plum",
+	"lightsalmon",
+	"lightcyan",
+	"pink",
+	"wheat",
+}
+
+// node is a single object in a beautified graph.
+type node struct {
+	// address is the object's full address, e.g.
+	// "module.network.aws_vpc.this".
+	address string
+	// module is the address of the module containing the object, e.g.
+	// "module.network". It is empty for objects in the root module.
+	module string
+	// local is the object's address relative to its module, e.g.
+	// "aws_vpc.this".
+	local string
+}
+
+// Beautify reads the raw DOT printed by `terraform graph`, and returns a more
+// readable DOT graph of the same configuration:
+//   - Terraform's internal nodes (the root, providers, meta-nodes and
+//     "close" nodes) are removed. The nodes that depended on one depend on
+//     its dependencies instead.
+//   - objects inside a module are grouped into a cluster for that module.
+//   - resources and data sources are colored by their provider.
+//
+// Edges point from a dependency to the node that depends on it.
+func Beautify(raw string) (string, error) {
+	rawAst, err := gographviz.ParseString(raw)
+	if err != nil {
+		return "", err
+	}
+	rawGraph := gographviz.NewGraph()
+	if err := gographviz.Analyse(rawAst, rawGraph); err != nil {
+		return "", err
+	}
+
+	// in terraform's graph, the source of an edge depends on its destination.
+	dependencies := make(map[string][]string)
+	for _, e := range rawGraph.Edges.Edges {
+		dependencies[e.Src] = append(dependencies[e.Src], e.Dst)
+	}
+
+	nodes := make(map[string]*node)
+	for _, n := range rawGraph.Nodes.Nodes {
+		address, ok := cleanAddress(n.Name)
+		if !ok {
+			bypass(dependencies, n.Name)
+			continue
+		}
+		nodes[n.Name] = newNode(address)
+	}
+
+	// dedupe nodes that appear under several raw names, e.g. "(expand)" and
+	// "(destroy)" variants of the same resource.
+	byAddress := make(map[string]*node)
+	for _, n := range nodes {
+		byAddress[n.address] = n
+	}
+
+	edges := make(map[string][]string)
+	for rawSrc, rawDsts := range dependencies {
+		for _, rawDst := range rawDsts {
+			src, srcOK := nodes[rawSrc]
+			dst, dstOK := nodes[rawDst]
+			if !srcOK || !dstOK || src.address == dst.address {
+				continue
+			}
+			edges[dst.address] = append(edges[dst.address], src.address)
+		}
+	}
+
+	return render(byAddress, edges)
+}
+
+// bypass removes the given node from the given dependencies (keyed by the
+// node that depends on them), and makes each node that depended on it
+// depend on each of its dependencies instead, so that the order between them
+// is kept.
+func bypass(dependencies map[string][]string, name string) {
+	through := dependencies[name]
+	delete(dependencies, name)
+	for src, dsts := range dependencies {
+		if !slices.Contains(dsts, name) {
+			continue
+		}
+		kept := make([]string, 0, len(dsts)+len(through))
+		for _, dst := range dsts {
+			if dst != name {
+				kept = append(kept, dst)
+			}
+		}
+		for _, dst := range through {
+			if dst != src && dst != name && !slices.Contains(kept, dst) {
+				kept = append(kept, dst)
+			}
+		}
+		dependencies[src] = kept
+	}
+}
+
+// cleanAddress strips the decorations terraform adds to a raw node name, and
+// returns the address of the object it represents. If the node is one of
+// terraform's internal nodes, cleanAddress returns false.
+func cleanAddress(rawName string) (string, bool) {
+	name, err := strconv.Unquote(rawName)
+	if err != nil {
+		name = rawName
+	}
+	name = strings.TrimPrefix(name, rootPrefix)
+
+	if i := strings.Index(name, " ("); i >= 0 {
+		suffix := name[i:]
+		name = name[:i]
+		if suffix == " (close)" {
+			return "", false
+		}
+	}
+
+	if name == "root" || strings.HasPrefix(name, "meta.") {
+		return "", false
+	}
+	if strings.HasPrefix(name, "provider[") || strings.Contains(name, ".provider[") {
+		return "", false
+	}
+
+	n := newNode(name)
+	// what's left of a module call after its contents have been clustered
+	if len(n.local) == 0 {
+		return "", false
+	}
+	return name, true
+}
+
+// newNode splits the given address into its module path and local address.
+func newNode(address string) *node {
+	n := &node{address: address, local: address}
+	for strings.HasPrefix(n.local, modulePrefix) {
+		parts := strings.SplitN(n.local, ".", 3)
+		if len(n.module) > 0 {
+			n.module += "."
+		}
+		n.module += parts[0] + "." + parts[1]
+		if len(parts) < 3 {
+			n.local = ""
+			break
+		}
+		n.local = parts[2]
+	}
+	return n
+}
+
+// provider returns the name of the provider of the receiver, if it is a
+// resource or data source. Otherwise it returns an empty string.
+func (n *node) provider() string {
+	local := strings.TrimPrefix(n.local, dataPrefix)
+	parts := strings.Split(local, ".")
+	switch parts[0] {
+	case "var", "local", "output":
+		return ""
+	}
+	return strings.SplitN(parts[0], "_", 2)[0]
+}
+
+func render(nodes map[string]*node, edges map[string][]string) (string, error) {
+	graphAst, _ := gographviz.ParseString(`digraph G {}`)
+	dotGraph := gographviz.NewGraph()
+	if err := gographviz.Analyse(graphAst, dotGraph); err != nil {
+		return "", err
+	}
+	if err := dotGraph.SetDir(true); err != nil {
+		return "", err
+	}
+	if err := dotGraph.AddAttr(graphName, "rankdir", "LR"); err != nil {
+		return "", err
+	}
+	if err := dotGraph.AddAttr(graphName, "compound", "true"); err != nil {
+		return "", err
+	}
+
+	addresses := make([]string, 0, len(nodes))
+	providers := make([]string, 0)
+	for address, n := range nodes {
+		addresses = append(addresses, address)
+		if p := n.provider(); len(p) > 0 && !slices.Contains(providers, p) {
+			providers = append(providers, p)
+		}
+	}
+	sort.Strings(addresses)
+	sort.Strings(providers)
+	colors := make(map[string]string, len(providers))
+	for i, p := range providers {
+		colors[p] = providerColors[i%len(providerColors)]
+	}
+
+	clusters := make(map[string]bool)
+	for _, address := range addresses {
+		n := nodes[address]
+		parent, err := addCluster(dotGraph, clusters, n.module)
+		if err != nil {
+			return "", err
+		}
+
+		attrs := map[string]string{
+			"label": quote(n.local),
+		}
+		if p := n.provider(); len(p) > 0 {
+			attrs["shape"] = "box"
+			attrs["style"] = quote("filled")
+			if strings.HasPrefix(n.local, dataPrefix) {
+				attrs["style"] = quote("filled,dashed")
+			}
+			attrs["fillcolor"] = quote(colors[p])
+			attrs["tooltip"] = quote(p)
+		} else {
+			attrs["shape"] = "note"
+		}
+		if err := dotGraph.AddNode(parent, quote(address), attrs); err != nil {
+			return "", err
+		}
+	}
+
+	for _, src := range addresses {
+		dsts := slices.Clone(edges[src])
+		slices.Sort(dsts)
+		dsts = slices.Compact(dsts)
+		for _, dst := range dsts {
+			if err := dotGraph.AddEdge(quote(src), quote(dst), true, nil); err != nil {
+				return "", err
+			}
+		}
+	}
+
+	return dotGraph.String(), nil
+}
+
+// addCluster adds a cluster subgraph for the given module (and each of its
+// ancestors) to the graph, and returns the name of the subgraph to add the
+// module's nodes to.
+func addCluster(g *gographviz.Graph, clusters map[string]bool, module string) (string, error) {
+	if len(module) == 0 {
+		return graphName, nil
+	}
+	name := quote("cluster_" + module)
+	if clusters[module] {
+		return name, nil
+	}
+
+	parent := graphName
+	if i := strings.LastIndex(module, "."+modulePrefix); i >= 0 {
+		var err error
+		parent, err = addCluster(g, clusters, module[:i])
+		if err != nil {
+			return "", err
+		}
+	}
+	attrs := map[string]string{
+		"label": quote(module),
+		"style": quote("rounded"),
+	}
+	if err := g.AddSubGraph(parent, name, attrs); err != nil {
+		return "", err
+	}
+	clusters[module] = true
+	return name, nil
+}
+
+func quote(s string) string {
+	return fmt.Sprintf("%q", s)
+}
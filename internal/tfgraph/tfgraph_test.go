@@ -0,0 +1,59 @@
+package tfgraph
+
+import (
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestCleanAddress(t *testing.T) {
+	type test struct {
+		rawName         string
+		expectedAddress string
+		expectedOK      bool
+	}
+
+	tests := []test{
+		{rawName: `"[root] aws_instance.web (expand)"`, expectedAddress: "aws_instance.web", expectedOK: true},
+		{rawName: `"[root] var.instance_type"`, expectedAddress: "var.instance_type", expectedOK: true},
+		{rawName: `"[root] module.network.aws_vpc.this (expand)"`, expectedAddress: "module.network.aws_vpc.this", expectedOK: true},
+		{rawName: `"aws_instance.web"`, expectedAddress: "aws_instance.web", expectedOK: true},
+		{rawName: `"[root] root"`},
+		{rawName: `"[root] meta.count-boundary (EachMode fixup)"`},
+		{rawName: `"[root] provider[\"registry.terraform.io/hashicorp/aws\"]"`},
+		{rawName: `"[root] provider[\"registry.terraform.io/hashicorp/aws\"] (close)"`},
+		{rawName: `"[root] module.network.provider[\"registry.terraform.io/hashicorp/aws\"]"`},
+		{rawName: `"[root] module.network (expand)"`},
+		{rawName: `"[root] module.network (close)"`},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.rawName, func(t *testing.T) {
+			address, ok := cleanAddress(tc.rawName)
+			if ok != tc.expectedOK || address != tc.expectedAddress {
+				t.Errorf("Expected (%q, %v), got (%q, %v)", tc.expectedAddress, tc.expectedOK, address, ok)
+			}
+		})
+	}
+}
+
+func TestBeautify(t *testing.T) {
+	raw, err := os.ReadFile("../../fixtures/graphs/terraform-graph.dot")
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected, err := os.ReadFile("../../fixtures/graphs/terraform-graph.beautified.dot")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	actual, err := Beautify(string(raw))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.TrimSpace(actual) != strings.TrimSpace(string(expected)) {
+		t.Logf("Expected: %s", expected)
+		t.Logf("Actual:   %s", actual)
+		t.Error("Actual graph did not match expected graph.")
+	}
+}
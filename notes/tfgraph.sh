@@ -36,3 +36,6 @@ cat 1.dot | dot -Tsvg > 1.svg
 #   spilliams/blast-radius
 
 # Still searching for that perfect grapher...
+# Until then, terrascope can do the beautifying itself:
+# cat 1.dot | terrascope module beautify > 4.dot
+# cat 4.dot | dot -Tsvg > 4.svg